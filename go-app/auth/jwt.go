@@ -1,6 +1,8 @@
 package auth
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"time"
 
@@ -10,18 +12,34 @@ import (
 
 // Claims represents the JWT claims
 type Claims struct {
-	Email   string `json:"email"`
-	IsAdmin bool   `json:"is_admin"`
+	Email     string `json:"email"`
+	IsAdmin   bool   `json:"is_admin"`
+	SessionID string `json:"sid"`
 	jwt.RegisteredClaims
 }
 
-// GenerateToken generates a new JWT token for a user
-func GenerateToken(email string, isAdmin bool) (string, error) {
+// TokenExpiry returns the lifetime of newly issued access tokens
+func TokenExpiry() time.Duration {
+	return time.Duration(config.JWTAccessTokenExpiry) * time.Minute
+}
+
+// NewSessionID generates a random identifier used to bind a token to a session
+func NewSessionID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// GenerateToken generates a new JWT token for a user bound to the given session
+func GenerateToken(email string, isAdmin bool, sessionID string) (string, error) {
 	claims := Claims{
-		Email:   email,
-		IsAdmin: isAdmin,
+		Email:     email,
+		IsAdmin:   isAdmin,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Duration(config.JWTAccessTokenExpiry) * time.Minute)),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(TokenExpiry())),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
 		},
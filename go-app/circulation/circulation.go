@@ -0,0 +1,313 @@
+package circulation
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"library-go/models"
+)
+
+var (
+	// ErrNoCopies is returned when every copy of a book is on loan
+	ErrNoCopies = errors.New("no available copies of this book")
+	// ErrReserved is returned when the free copies are held for readers ahead in the queue
+	ErrReserved = errors.New("available copies are reserved for readers with earlier holds")
+)
+
+// AvailableCopies returns, per book, the number of copies that are not on loan
+func AvailableCopies(db *gorm.DB, books []models.Book) (map[uint]int, error) {
+	available := make(map[uint]int, len(books))
+	if len(books) == 0 {
+		return available, nil
+	}
+
+	ids := make([]uint, len(books))
+	for i, book := range books {
+		ids[i] = book.ID
+		available[book.ID] = book.Copies
+	}
+
+	var loans []struct {
+		BookID uint
+		Count  int
+	}
+	if err := db.Model(&models.Borrow{}).Select("book_id, count(*) AS count").
+		Where("book_id IN ? AND is_returned = ?", ids, false).Group("book_id").Scan(&loans).Error; err != nil {
+		return nil, err
+	}
+	for _, loan := range loans {
+		available[loan.BookID] -= loan.Count
+		if available[loan.BookID] < 0 {
+			available[loan.BookID] = 0
+		}
+	}
+	return available, nil
+}
+
+// Editions returns the books to consider for a loan of book: every edition of
+// its work, or just the book when it belongs to no work
+func Editions(db *gorm.DB, book *models.Book) ([]models.Book, error) {
+	if book.WorkID == nil {
+		return []models.Book{*book}, nil
+	}
+	var editions []models.Book
+	if err := db.Where("work_id = ?", *book.WorkID).Order("id").Find(&editions).Error; err != nil {
+		return nil, err
+	}
+	return editions, nil
+}
+
+// Checkout lends a book to a reader. Copies claimed by earlier holds on the
+// book, or on its work, are not lent to anyone else; a waiting hold of the
+// reader that the loan satisfies is marked fulfilled.
+func Checkout(tx *gorm.DB, book *models.Book, readerID uint) (*models.Borrow, error) {
+	hold, err := readerHold(tx, book, readerID)
+	if err != nil {
+		return nil, err
+	}
+	return checkout(tx, book, readerID, hold)
+}
+
+// Fulfill lends a waiting hold's reader the held book or, for a hold on a
+// work, the first edition with a copy to spare
+func Fulfill(tx *gorm.DB, hold *models.Hold) (*models.Borrow, error) {
+	if hold.Status != models.HoldWaiting {
+		return nil, errors.New("hold is not waiting")
+	}
+
+	var books []models.Book
+	query := tx.Order("id")
+	if hold.BookID != nil {
+		query = query.Where("id = ?", *hold.BookID)
+	} else {
+		query = query.Where("work_id = ?", *hold.WorkID)
+	}
+	if err := query.Find(&books).Error; err != nil {
+		return nil, err
+	}
+
+	result := ErrNoCopies
+	for i := range books {
+		borrow, err := checkout(tx, &books[i], hold.ReaderID, hold)
+		if err == nil {
+			return borrow, nil
+		}
+		if errors.Is(err, ErrReserved) {
+			result = ErrReserved
+		} else if !errors.Is(err, ErrNoCopies) {
+			return nil, err
+		}
+	}
+	return nil, result
+}
+
+// QueuePosition returns the place of a waiting hold among the holds competing
+// for the same copies, starting at 1
+func QueuePosition(db *gorm.DB, hold *models.Hold) (int, error) {
+	var ahead int64
+	query := db.Model(&models.Hold{}).Where("status = ? AND id < ?", models.HoldWaiting, hold.ID)
+	if hold.WorkID != nil {
+		query = query.Where("work_id = ? OR book_id IN (?)", *hold.WorkID,
+			db.Model(&models.Book{}).Select("id").Where("work_id = ?", *hold.WorkID))
+	} else {
+		var book models.Book
+		if err := db.First(&book, *hold.BookID).Error; err != nil {
+			return 0, err
+		}
+		if book.WorkID != nil {
+			query = query.Where("book_id = ? OR work_id = ?", book.ID, *book.WorkID)
+		} else {
+			query = query.Where("book_id = ?", book.ID)
+		}
+	}
+	if err := query.Count(&ahead).Error; err != nil {
+		return 0, err
+	}
+	return int(ahead) + 1, nil
+}
+
+// readerHold returns the reader's oldest waiting hold that a loan of book satisfies, if any
+func readerHold(tx *gorm.DB, book *models.Book, readerID uint) (*models.Hold, error) {
+	query := tx.Where("reader_id = ? AND status = ?", readerID, models.HoldWaiting)
+	if book.WorkID != nil {
+		query = query.Where("book_id = ? OR work_id = ?", book.ID, *book.WorkID)
+	} else {
+		query = query.Where("book_id = ?", book.ID)
+	}
+
+	var holds []models.Hold
+	if err := query.Order("id").Limit(1).Find(&holds).Error; err != nil {
+		return nil, err
+	}
+	if len(holds) == 0 {
+		return nil, nil
+	}
+	return &holds[0], nil
+}
+
+// checkout lends book to the reader on behalf of hold, which may be nil
+func checkout(tx *gorm.DB, book *models.Book, readerID uint, hold *models.Hold) (*models.Borrow, error) {
+	editions, err := Editions(tx, book)
+	if err != nil {
+		return nil, err
+	}
+	available, err := AvailableCopies(tx, editions)
+	if err != nil {
+		return nil, err
+	}
+	if available[book.ID] <= 0 {
+		return nil, ErrNoCopies
+	}
+	if err := claimCopies(tx, book, editions, available, hold); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	borrow := models.Borrow{
+		BookID:     book.ID,
+		ReaderID:   readerID,
+		IsReturned: false,
+		BorrowedAt: now,
+	}
+	if err := tx.Create(&borrow).Error; err != nil {
+		return nil, err
+	}
+
+	if hold != nil {
+		hold.Status = models.HoldFulfilled
+		hold.BorrowID = &borrow.ID
+		hold.FulfilledAt = &now
+		if err := tx.Omit(clause.Associations).Save(hold).Error; err != nil {
+			return nil, err
+		}
+	}
+	return &borrow, nil
+}
+
+// claimCopies takes the copies claimed by waiting holds off available, the
+// copies of each edition on the shelf, and returns ErrReserved when none is
+// left for a loan of book on behalf of hold, which may be nil
+func claimCopies(tx *gorm.DB, book *models.Book, editions []models.Book, available map[uint]int, hold *models.Hold) error {
+	// Holds placed before the reader's own, or all of them when the reader has none
+	ahead := func() *gorm.DB {
+		query := tx.Model(&models.Hold{}).Where("status = ?", models.HoldWaiting)
+		if hold != nil {
+			query = query.Where("id < ?", hold.ID)
+		}
+		return query
+	}
+
+	// Edition holds claim copies of their edition only
+	ids := make([]uint, len(editions))
+	for i, edition := range editions {
+		ids[i] = edition.ID
+	}
+	var claims []struct {
+		BookID uint
+		Count  int
+	}
+	if err := ahead().Select("book_id, count(*) AS count").Where("book_id IN ?", ids).
+		Group("book_id").Scan(&claims).Error; err != nil {
+		return err
+	}
+	for _, claim := range claims {
+		available[claim.BookID] -= claim.Count
+	}
+	if available[book.ID] <= 0 {
+		return ErrReserved
+	}
+
+	// Work holds claim whatever copies the edition holds leave across the work
+	if book.WorkID != nil {
+		var workHolds int64
+		if err := ahead().Where("work_id = ?", *book.WorkID).Count(&workHolds).Error; err != nil {
+			return err
+		}
+		spare := 0
+		for _, count := range available {
+			if count > 0 {
+				spare += count
+			}
+		}
+		if int64(spare) <= workHolds {
+			return ErrReserved
+		}
+	}
+	return nil
+}
+
+// ErrNotOnLoan is returned when returning or renewing a book the reader does not have
+var ErrNotOnLoan = errors.New("book is not on loan")
+
+// Loan returns the open loan of book to the reader, or when readerID is zero
+// the book's oldest open loan
+func Loan(tx *gorm.DB, book *models.Book, readerID uint) (*models.Borrow, error) {
+	query := tx.Where("book_id = ? AND is_returned = ?", book.ID, false)
+	if readerID != 0 {
+		query = query.Where("reader_id = ?", readerID)
+	}
+	var loans []models.Borrow
+	if err := query.Order("borrowed_at, id").Limit(1).Find(&loans).Error; err != nil {
+		return nil, err
+	}
+	if len(loans) == 0 {
+		return nil, ErrNotOnLoan
+	}
+	return &loans[0], nil
+}
+
+// Return marks a loan returned at the given time
+func Return(tx *gorm.DB, borrow *models.Borrow, at time.Time) error {
+	if borrow.IsReturned {
+		return ErrNotOnLoan
+	}
+	borrow.IsReturned = true
+	borrow.ReturnedAt = &at
+	return tx.Omit(clause.Associations).Save(borrow).Error
+}
+
+// Renew extends a loan in place, restarting the loan period from now. Like a
+// checkout, it fails when the copy would be needed for a waiting hold were it
+// returned.
+func Renew(tx *gorm.DB, borrow *models.Borrow) (*models.Borrow, error) {
+	if borrow.IsReturned {
+		return nil, ErrNotOnLoan
+	}
+	var book models.Book
+	if err := tx.First(&book, borrow.BookID).Error; err != nil {
+		return nil, err
+	}
+	editions, err := Editions(tx, &book)
+	if err != nil {
+		return nil, err
+	}
+	available, err := AvailableCopies(tx, editions)
+	if err != nil {
+		return nil, err
+	}
+	// The renewed copy counts as if it had come back to the shelf
+	available[book.ID]++
+	if err := claimCopies(tx, &book, editions, available, nil); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	borrow.RenewedAt = &now
+	borrow.Renewals++
+	if err := tx.Omit(clause.Associations).Save(borrow).Error; err != nil {
+		return nil, err
+	}
+	return borrow, nil
+}
+
+// DueDate returns when a loan is due back after the given loan period, counted
+// from its last renewal
+func DueDate(borrow *models.Borrow, loanDays int) time.Time {
+	if borrow.RenewedAt != nil {
+		return borrow.RenewedAt.AddDate(0, 0, loanDays)
+	}
+	return borrow.BorrowedAt.AddDate(0, 0, loanDays)
+}
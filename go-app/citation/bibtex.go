@@ -0,0 +1,115 @@
+package citation
+
+import (
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// bibtexSpecials escapes the characters LaTeX gives a meaning to
+var bibtexSpecials = strings.NewReplacer(
+	`\`, `\textbackslash{}`,
+	"{", `\{`,
+	"}", `\}`,
+	"&", `\&`,
+	"%", `\%`,
+	"$", `\$`,
+	"#", `\#`,
+	"_", `\_`,
+	"~", `\textasciitilde{}`,
+	"^", `\textasciicircum{}`,
+)
+
+// writeBibTeX writes a @book entry per item
+func writeBibTeX(w io.Writer, items []Item) error {
+	keys := Keys(items)
+	var b strings.Builder
+	for i, item := range items {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString("@book{" + keys[i] + ",\n")
+		field := func(name, value string) {
+			if value != "" {
+				b.WriteString("  " + name + " = {" + value + "},\n")
+			}
+		}
+		field("author", bibtexNames(item.Authors))
+		field("editor", bibtexNames(item.Editors))
+		field("translator", bibtexNames(item.Translators))
+		field("title", bibtexTitle(item.Title))
+		field("edition", bibtexEdition(item.Edition))
+		field("publisher", bibtexEscape(item.Publisher))
+		if item.Year != 0 {
+			field("year", strconv.Itoa(item.Year))
+		}
+		if item.Pages != 0 {
+			field("pagetotal", strconv.Itoa(item.Pages))
+		}
+		field("isbn", bibtexEscape(item.ISBN))
+		field("language", bibtexEscape(item.Language))
+		field("abstract", bibtexEscape(item.Abstract))
+		field("keywords", bibtexEscape(strings.Join(item.Keywords, ", ")))
+		field("url", item.URL)
+		b.WriteString("}\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func bibtexEscape(s string) string {
+	return bibtexSpecials.Replace(s)
+}
+
+// bibtexNames joins names with "and", each written "von Last, Jr, First".
+// Parts that contain the word "and" are braced so they are not split.
+func bibtexNames(names []Name) string {
+	written := make([]string, len(names))
+	for i, n := range names {
+		parts := []string{bibtexNamePart(n.FamilyName())}
+		if n.Suffix != "" {
+			parts = append(parts, bibtexNamePart(n.Suffix))
+		}
+		if n.Given != "" {
+			parts = append(parts, bibtexNamePart(n.Given))
+		}
+		written[i] = strings.Join(parts, ", ")
+	}
+	return strings.Join(written, " and ")
+}
+
+func bibtexNamePart(s string) string {
+	s = bibtexEscape(s)
+	for _, word := range strings.Fields(s) {
+		if strings.EqualFold(word, "and") {
+			return "{" + s + "}"
+		}
+	}
+	return s
+}
+
+// bibtexTitle escapes a title, bracing words such as "NASA" or "iPhone" whose
+// capitals a style must not lower-case
+func bibtexTitle(title string) string {
+	words := strings.Split(title, " ")
+	for i, word := range words {
+		escaped := bibtexEscape(word)
+		for j, r := range []rune(word) {
+			if j > 0 && unicode.IsUpper(r) {
+				escaped = "{" + escaped + "}"
+				break
+			}
+		}
+		words[i] = escaped
+	}
+	return strings.Join(words, " ")
+}
+
+// bibtexEdition gives numbered editions as the bare number biblatex expects
+func bibtexEdition(edition string) string {
+	if m := editionNumber.FindStringSubmatch(strings.ToLower(strings.TrimSuffix(edition, "."))); m != nil {
+		return m[1]
+	}
+	return bibtexEscape(edition)
+}
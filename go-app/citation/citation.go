@@ -0,0 +1,395 @@
+// Package citation formats books as references: BibTeX, RIS and CSL-JSON for
+// reference managers, and APA and MLA reference list entries for reading.
+package citation
+
+import (
+	"errors"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"library-go/fuzzy"
+	"library-go/models"
+)
+
+// Citation formats
+const (
+	FormatBibTeX  = "bibtex"
+	FormatRIS     = "ris"
+	FormatCSLJSON = "csl-json"
+	FormatAPA     = "apa"
+	FormatMLA     = "mla"
+)
+
+// Formats lists the citation formats
+var Formats = []string{FormatBibTeX, FormatRIS, FormatCSLJSON, FormatAPA, FormatMLA}
+
+// ErrUnknownFormat is returned for a format not in Formats
+var ErrUnknownFormat = errors.New("unknown citation format")
+
+// Item is a book as cited: its contributors split into names and the
+// publication details references give
+type Item struct {
+	ID          uint
+	Title       string
+	Authors     []Name
+	Editors     []Name
+	Translators []Name
+	Year        int // Zero when unknown
+	Publisher   string
+	Edition     string
+	Medium      string // Such as "Audiobook", empty for print
+	ISBN        string
+	Language    string // ISO 639 code
+	Pages       int
+	Abstract    string
+	Keywords    []string
+	URL         string
+}
+
+// Name is a personal name split into its parts. A name that cannot be split,
+// such as "Homer", only has a family part.
+type Name struct {
+	Given    string
+	Particle string // Lower-case prefix of the family name, such as "van" or "de"
+	Family   string
+	Suffix   string // Such as "Jr."
+}
+
+// mediums names the formats that are cited with their medium
+var mediums = map[string]string{
+	models.FormatLargePrint: "Large print",
+	models.FormatAudiobook:  "Audiobook",
+	models.FormatEbook:      "E-book",
+	models.FormatDVD:        "DVD",
+}
+
+// FromBook builds the item citing a book found at url. Contributors, when
+// loaded, give the names and roles; otherwise the credit line is split into
+// authors. Categories and tags, when loaded, become keywords.
+func FromBook(book *models.Book, url string) Item {
+	item := Item{
+		ID:        book.ID,
+		Title:     clean(book.Title),
+		Medium:    mediums[book.Format],
+		Publisher: cleanPtr(book.Publisher),
+		Edition:   cleanPtr(book.Edition),
+		ISBN:      cleanPtr(book.ISBN),
+		Language:  cleanPtr(book.Language),
+		Abstract:  cleanPtr(book.Description),
+		URL:       url,
+	}
+	if book.Year != nil {
+		item.Year = *book.Year
+	}
+	if book.PageCount != nil {
+		item.Pages = *book.PageCount
+	}
+
+	for _, c := range book.Contributors {
+		if c.Author == nil {
+			continue
+		}
+		name := ParseName(c.Author.Name)
+		switch c.Role {
+		case models.RoleAuthor:
+			item.Authors = append(item.Authors, name)
+		case models.RoleEditor:
+			item.Editors = append(item.Editors, name)
+		case models.RoleTranslator:
+			item.Translators = append(item.Translators, name)
+		}
+	}
+	if len(book.Contributors) == 0 {
+		for _, name := range models.SplitAuthorNames(book.Author) {
+			item.Authors = append(item.Authors, ParseName(name))
+		}
+	}
+
+	for _, c := range book.Categories {
+		item.Keywords = append(item.Keywords, clean(c.Name))
+	}
+	for _, t := range book.Tags {
+		item.Keywords = append(item.Keywords, clean(t.Name))
+	}
+	return item
+}
+
+var (
+	// particles are the lower-case prefixes of family names
+	particles = map[string]bool{
+		"van": true, "von": true, "de": true, "der": true, "den": true, "da": true, "di": true, "du": true,
+		"del": true, "della": true, "des": true, "la": true, "le": true, "ten": true, "ter": true, "zu": true,
+		"dos": true, "das": true, "do": true, "af": true, "av": true,
+	}
+	suffixPattern = regexp.MustCompile(`^(?i:jr\.?|sr\.?|[IVX]+)$`)
+)
+
+// ParseName splits a name written "Given Family" or inverted as
+// "Family, Given". Lower-case particles before the family name are kept
+// apart, capitalised ones such as the "Le" of "Le Guin" belong to it.
+func ParseName(text string) Name {
+	parts := strings.Split(clean(text), ",")
+	for i := range parts {
+		parts[i] = strings.TrimSpace(parts[i])
+	}
+
+	var name Name
+	switch {
+	case len(parts) >= 2 && suffixPattern.MatchString(parts[1]):
+		// "Martin Luther King, Jr."
+		name = parseWords(strings.Fields(parts[0]))
+		name.Suffix = parts[1]
+	case len(parts) >= 2:
+		family := strings.Fields(parts[0])
+		i := 0
+		for i < len(family)-1 && particles[family[i]] {
+			i++
+		}
+		name = Name{
+			Given:    parts[1],
+			Particle: strings.Join(family[:i], " "),
+			Family:   strings.Join(family[i:], " "),
+		}
+		if len(parts) > 2 {
+			name.Suffix = strings.Join(parts[2:], ", ")
+		}
+	default:
+		name = parseWords(strings.Fields(parts[0]))
+	}
+	return name
+}
+
+// parseWords splits the words of an uninverted name
+func parseWords(words []string) Name {
+	var name Name
+	if n := len(words); n > 1 && suffixPattern.MatchString(words[n-1]) {
+		name.Suffix = words[n-1]
+		words = words[:n-1]
+	}
+	if len(words) == 0 {
+		return name
+	}
+
+	family := len(words) - 1
+	if family >= 2 && particles[strings.ToLower(words[family-1])] && !particles[words[family-1]] {
+		family--
+	}
+	particle := family
+	for particle > 1 && particles[words[particle-1]] {
+		particle--
+	}
+	name.Given = strings.Join(words[:particle], " ")
+	name.Particle = strings.Join(words[particle:family], " ")
+	name.Family = strings.Join(words[family:], " ")
+	return name
+}
+
+// FamilyName is the family name with its particle
+func (n Name) FamilyName() string {
+	if n.Particle == "" {
+		return n.Family
+	}
+	return n.Particle + " " + n.Family
+}
+
+// Initials abbreviates the given names, keeping hyphens: "Jean-Paul" is "J.-P."
+func (n Name) Initials() string {
+	words := strings.FieldsFunc(n.Given, func(r rune) bool { return r == '.' || unicode.IsSpace(r) })
+	initials := make([]string, 0, len(words))
+	for _, word := range words {
+		var parts []string
+		for _, part := range strings.Split(word, "-") {
+			if r := []rune(part); len(r) > 0 {
+				parts = append(parts, string(unicode.ToUpper(r[0]))+".")
+			}
+		}
+		initials = append(initials, strings.Join(parts, "-"))
+	}
+	return strings.Join(initials, " ")
+}
+
+// inverted writes the name family first: "Tolstoy, Leo", "King, Martin Luther, Jr."
+func (n Name) inverted(given string) string {
+	s := n.FamilyName()
+	if given != "" {
+		s += ", " + given
+	}
+	if n.Suffix != "" {
+		s += ", " + n.Suffix
+	}
+	return s
+}
+
+// String writes the name in reading order
+func (n Name) String() string {
+	s := strings.TrimSpace(n.Given + " " + n.FamilyName())
+	if n.Suffix != "" {
+		s += ", " + n.Suffix
+	}
+	return s
+}
+
+// ContentType returns the media type of a format
+func ContentType(format string) string {
+	switch format {
+	case FormatBibTeX:
+		return "application/x-bibtex; charset=utf-8"
+	case FormatRIS:
+		return "application/x-research-info-systems; charset=utf-8"
+	case FormatCSLJSON:
+		return "application/vnd.citationstyles.csl+json; charset=utf-8"
+	}
+	return "text/plain; charset=utf-8"
+}
+
+// Extension returns the file extension of a format, empty for the styles read as text
+func Extension(format string) string {
+	switch format {
+	case FormatBibTeX:
+		return ".bib"
+	case FormatRIS:
+		return ".ris"
+	case FormatCSLJSON:
+		return ".json"
+	}
+	return ""
+}
+
+// Write writes the items in a format. Reference manager formats keep the
+// order of the items; APA and MLA entries are sorted as a reference list.
+func Write(w io.Writer, format string, items []Item) error {
+	switch format {
+	case FormatBibTeX:
+		return writeBibTeX(w, items)
+	case FormatRIS:
+		return writeRIS(w, items)
+	case FormatCSLJSON:
+		return writeCSLJSON(w, items)
+	case FormatAPA:
+		return writeReferences(w, items, APA)
+	case FormatMLA:
+		return writeReferences(w, items, MLA)
+	}
+	return ErrUnknownFormat
+}
+
+// writeReferences writes one entry per paragraph in alphabetical order
+func writeReferences(w io.Writer, items []Item, style func(Item) string) error {
+	entries := make([]string, len(items))
+	for i, item := range items {
+		entries[i] = style(item)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return fuzzy.Transliterate(entries[i]) < fuzzy.Transliterate(entries[j])
+	})
+	_, err := io.WriteString(w, strings.Join(entries, "\n\n")+"\n")
+	return err
+}
+
+// Keys returns a citation key for each item, built from the first
+// contributor's family name, the year and the first significant word of the
+// title, as in "tolstoy1869war". Items sharing a key get a letter suffix.
+func Keys(items []Item) []string {
+	keys := make([]string, len(items))
+	seen := make(map[string]int)
+	for i, item := range items {
+		key := baseKey(item)
+		if n := seen[key]; n > 0 {
+			keys[i] = key + string(rune('a'+(n-1)%26)) + strings.Repeat("a", (n-1)/26)
+		} else {
+			keys[i] = key
+		}
+		seen[key]++
+	}
+	return keys
+}
+
+// titleStopWords are skipped when taking the first word of a title for a key
+var titleStopWords = map[string]bool{
+	"a": true, "an": true, "the": true, "le": true, "la": true, "les": true, "l": true,
+	"der": true, "die": true, "das": true, "el": true, "los": true, "las": true, "il": true, "on": true, "of": true,
+}
+
+func baseKey(item Item) string {
+	var key string
+	for _, names := range [][]Name{item.Authors, item.Editors} {
+		if len(names) > 0 {
+			key = keyWord(names[0].Family)
+			break
+		}
+	}
+	if key == "" {
+		key = "book"
+	}
+	if item.Year != 0 {
+		key += strconv.Itoa(item.Year)
+	}
+	for _, word := range fuzzy.Words(item.Title) {
+		if word = keyWord(word); word != "" && !titleStopWords[word] {
+			key += word
+			break
+		}
+	}
+	if key == "book" {
+		key += strconv.FormatUint(uint64(item.ID), 10)
+	}
+	return key
+}
+
+// keyWord reduces text to the ASCII letters and digits safe in every key syntax
+func keyWord(text string) string {
+	var b strings.Builder
+	for _, r := range fuzzy.Transliterate(text) {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// editionNumber matches editions given as an ordinal number, such as "2nd ed."
+var editionNumber = regexp.MustCompile(`^(\d+)(?:st|nd|rd|th)?(?:\s+(?:ed|edn|edition)\.?)?$`)
+
+// editionLabel writes an edition statement for a reference, abbreviated as
+// "2nd ed." when it is a number
+func editionLabel(edition string) string {
+	edition = strings.TrimSuffix(edition, ".")
+	if m := editionNumber.FindStringSubmatch(strings.ToLower(edition)); m != nil {
+		return ordinal(m[1]) + " ed."
+	}
+	if words := strings.Fields(edition); len(words) > 1 && strings.EqualFold(words[len(words)-1], "ed") {
+		return edition + "."
+	}
+	return edition
+}
+
+func ordinal(n string) string {
+	suffix := "th"
+	if !strings.HasSuffix(n, "11") && !strings.HasSuffix(n, "12") && !strings.HasSuffix(n, "13") {
+		switch n[len(n)-1] {
+		case '1':
+			suffix = "st"
+		case '2':
+			suffix = "nd"
+		case '3':
+			suffix = "rd"
+		}
+	}
+	return n + suffix
+}
+
+// clean collapses the whitespace of a value, line breaks included
+func clean(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func cleanPtr(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return clean(*s)
+}
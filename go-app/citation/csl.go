@@ -0,0 +1,77 @@
+package citation
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+)
+
+// cslItem is an item in CSL-JSON, the input of citeproc processors
+type cslItem struct {
+	ID            string    `json:"id"`
+	Type          string    `json:"type"`
+	Title         string    `json:"title"`
+	Author        []cslName `json:"author,omitempty"`
+	Editor        []cslName `json:"editor,omitempty"`
+	Translator    []cslName `json:"translator,omitempty"`
+	Issued        *cslDate  `json:"issued,omitempty"`
+	Publisher     string    `json:"publisher,omitempty"`
+	Edition       string    `json:"edition,omitempty"`
+	Medium        string    `json:"medium,omitempty"`
+	ISBN          string    `json:"ISBN,omitempty"`
+	Language      string    `json:"language,omitempty"`
+	NumberOfPages int       `json:"number-of-pages,omitempty"`
+	Abstract      string    `json:"abstract,omitempty"`
+	Keyword       string    `json:"keyword,omitempty"`
+	URL           string    `json:"URL,omitempty"`
+}
+
+type cslName struct {
+	Family   string `json:"family,omitempty"`
+	Given    string `json:"given,omitempty"`
+	Particle string `json:"non-dropping-particle,omitempty"`
+	Suffix   string `json:"suffix,omitempty"`
+}
+
+type cslDate struct {
+	DateParts [][]int `json:"date-parts"`
+}
+
+// writeCSLJSON writes the items as a CSL-JSON array
+func writeCSLJSON(w io.Writer, items []Item) error {
+	keys := Keys(items)
+	out := make([]cslItem, len(items))
+	for i, item := range items {
+		out[i] = cslItem{
+			ID:            keys[i],
+			Type:          "book",
+			Title:         item.Title,
+			Author:        cslNames(item.Authors),
+			Editor:        cslNames(item.Editors),
+			Translator:    cslNames(item.Translators),
+			Publisher:     item.Publisher,
+			Edition:       item.Edition,
+			Medium:        item.Medium,
+			ISBN:          item.ISBN,
+			Language:      item.Language,
+			NumberOfPages: item.Pages,
+			Abstract:      item.Abstract,
+			Keyword:       strings.Join(item.Keywords, ", "),
+			URL:           item.URL,
+		}
+		if item.Year != 0 {
+			out[i].Issued = &cslDate{DateParts: [][]int{{item.Year}}}
+		}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(out)
+}
+
+func cslNames(names []Name) []cslName {
+	out := make([]cslName, len(names))
+	for i, n := range names {
+		out[i] = cslName{Family: n.Family, Given: n.Given, Particle: n.Particle, Suffix: n.Suffix}
+	}
+	return out
+}
@@ -0,0 +1,51 @@
+package citation
+
+import (
+	"io"
+	"strconv"
+	"strings"
+)
+
+// writeRIS writes a BOOK record per item, with the CRLF line ends of the format
+func writeRIS(w io.Writer, items []Item) error {
+	keys := Keys(items)
+	var b strings.Builder
+	for i, item := range items {
+		tag := func(name, value string) {
+			if value != "" {
+				b.WriteString(name + "  - " + value + "\r\n")
+			}
+		}
+		tag("TY", "BOOK")
+		tag("ID", keys[i])
+		for _, n := range item.Authors {
+			tag("AU", n.inverted(n.Given))
+		}
+		for _, n := range item.Editors {
+			tag("ED", n.inverted(n.Given))
+		}
+		for _, n := range item.Translators {
+			tag("A4", n.inverted(n.Given))
+		}
+		tag("TI", item.Title)
+		tag("ET", item.Edition)
+		tag("M3", item.Medium)
+		tag("PB", item.Publisher)
+		if item.Year != 0 {
+			tag("PY", strconv.Itoa(item.Year))
+		}
+		if item.Pages != 0 {
+			tag("SP", strconv.Itoa(item.Pages))
+		}
+		tag("SN", item.ISBN)
+		tag("LA", item.Language)
+		tag("AB", item.Abstract)
+		for _, keyword := range item.Keywords {
+			tag("KW", keyword)
+		}
+		tag("UR", item.URL)
+		b.WriteString("ER  - \r\n\r\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,159 @@
+package citation
+
+import (
+	"strconv"
+	"strings"
+)
+
+// APA writes the item as an APA (7th edition) reference list entry:
+// Author, A. A., & Author, B. B. (Year). Title (T. Translator, Trans.; 2nd ed.). Publisher.
+func APA(item Item) string {
+	var elements []string
+
+	var details []string
+	if len(item.Translators) > 0 {
+		details = append(details, apaNamesGivenFirst(item.Translators)+", Trans.")
+	}
+	if item.Edition != "" {
+		details = append(details, editionLabel(item.Edition))
+	}
+	title := item.Title
+	if len(details) > 0 {
+		title += " (" + strings.Join(details, "; ") + ")"
+	}
+	if item.Medium != "" {
+		title += " [" + item.Medium + "]"
+	}
+
+	date := "(n.d.)"
+	if item.Year != 0 {
+		date = "(" + strconv.Itoa(item.Year) + ")"
+	}
+
+	switch {
+	case len(item.Authors) > 0:
+		elements = append(elements, apaNames(item.Authors), date, title)
+	case len(item.Editors) > 0:
+		role := " (Ed.)"
+		if len(item.Editors) > 1 {
+			role = " (Eds.)"
+		}
+		elements = append(elements, apaNames(item.Editors)+role, date, title)
+	default:
+		// Without an author the title takes its place
+		elements = append(elements, title, date)
+	}
+	if item.Publisher != "" {
+		elements = append(elements, item.Publisher)
+	}
+	return sentences(elements)
+}
+
+// apaNames lists names as "Family, I. I.", all of them up to twenty and
+// otherwise the first nineteen, an ellipsis and the last
+func apaNames(names []Name) string {
+	written := make([]string, len(names))
+	for i, n := range names {
+		written[i] = n.inverted(n.Initials())
+	}
+	if len(written) > 20 {
+		return strings.Join(written[:19], ", ") + ", . . . " + written[len(written)-1]
+	}
+	// Inverted names are separated by commas, so even two take one before the ampersand
+	return joinNames(written, ", & ", ", & ")
+}
+
+// apaNamesGivenFirst lists names as "I. I. Family", as for translators; two
+// are joined by a bare ampersand and more take a serial comma before it
+func apaNamesGivenFirst(names []Name) string {
+	written := make([]string, len(names))
+	for i, n := range names {
+		written[i] = Name{Given: n.Initials(), Particle: n.Particle, Family: n.Family, Suffix: n.Suffix}.String()
+	}
+	return joinNames(written, ", & ", " & ")
+}
+
+// MLA writes the item as an MLA (9th edition) works cited entry:
+// Family, Given, and Given Family. Title. Translated by Given Family, 2nd ed., Publisher, Year.
+func MLA(item Item) string {
+	var elements []string
+	switch {
+	case len(item.Authors) > 0:
+		elements = append(elements, mlaNames(item.Authors))
+	case len(item.Editors) > 0:
+		role := ", editor"
+		if len(item.Editors) > 1 {
+			role = ", editors"
+		}
+		elements = append(elements, mlaNames(item.Editors)+role)
+	}
+	elements = append(elements, item.Title)
+
+	var publication []string
+	if len(item.Translators) > 0 {
+		publication = append(publication, "Translated by "+mlaNamesGivenFirst(item.Translators))
+	}
+	if item.Edition != "" {
+		publication = append(publication, editionLabel(item.Edition))
+	}
+	if item.Medium != "" {
+		publication = append(publication, item.Medium)
+	}
+	if item.Publisher != "" {
+		publication = append(publication, item.Publisher)
+	}
+	if item.Year != 0 {
+		publication = append(publication, strconv.Itoa(item.Year))
+	}
+	if len(publication) > 0 {
+		elements = append(elements, strings.Join(publication, ", "))
+	}
+	return sentences(elements)
+}
+
+// mlaNames lists one name inverted, two as "Family, Given, and Given Family"
+// and more by the first followed by "et al."
+func mlaNames(names []Name) string {
+	first := names[0].inverted(names[0].Given)
+	switch len(names) {
+	case 1:
+		return first
+	case 2:
+		return first + ", and " + names[1].String()
+	}
+	return first + ", et al."
+}
+
+func mlaNamesGivenFirst(names []Name) string {
+	switch len(names) {
+	case 1:
+		return names[0].String()
+	case 2:
+		return names[0].String() + " and " + names[1].String()
+	}
+	return names[0].String() + " et al."
+}
+
+// joinNames joins names with commas, the last two with final, or with pair when there are only two
+func joinNames(names []string, final, pair string) string {
+	switch len(names) {
+	case 0:
+		return ""
+	case 1:
+		return names[0]
+	case 2:
+		return names[0] + pair + names[1]
+	}
+	return strings.Join(names[:len(names)-1], ", ") + final + names[len(names)-1]
+}
+
+// sentences ends each element with a period, unless it already ends with
+// punctuation, and joins them with spaces
+func sentences(elements []string) string {
+	for i, e := range elements {
+		if !strings.HasSuffix(e, ".") && !strings.HasSuffix(e, "?") && !strings.HasSuffix(e, "!") {
+			elements[i] = e + "."
+		}
+	}
+	return strings.Join(elements, " ")
+}
@@ -13,6 +13,42 @@ var (
 	JWTSecretKey          string
 	JWTAlgorithm          string
 	JWTAccessTokenExpiry int64
+
+	// Search configuration
+	SearchBackend   string
+	SearchIndexPath string
+
+	// Cover image configuration
+	CoverStorage  string
+	CoverDir      string
+	CoverBaseURL  string
+	CoverMaxBytes int64
+
+	// S3-compatible object storage configuration, used when CoverStorage is "s3"
+	S3Endpoint  string
+	S3Region    string
+	S3Bucket    string
+	S3AccessKey string
+	S3SecretKey string
+
+	// Book metadata lookup configuration
+	MetadataProviders  string
+	OpenLibraryURL     string
+	GoogleBooksURL     string
+	GoogleBooksAPIKey  string
+	MetadataCacheHours int
+	MetadataCacheSize  int
+
+	// OAI-PMH repository configuration
+	OAIRepositoryName       string
+	OAIRepositoryIdentifier string
+	OAIAdminEmail           string
+
+	// SIP2 self-check server configuration
+	SIPAddress              string
+	SIPInstitutionID        string
+	SIPLoanDays             int
+	SIPTrustPatronPasswords bool
 )
 
 func LoadConfig() {
@@ -27,6 +63,56 @@ func LoadConfig() {
 		accessTokenExpiry = 30 // default to 30 minutes
 	}
 	JWTAccessTokenExpiry = int64(accessTokenExpiry)
+
+	// Search configuration, "database" uses the database full-text index, "bleve" an embedded index
+	SearchBackend = getEnv("SEARCH_BACKEND", "database")
+	SearchIndexPath = getEnv("SEARCH_INDEX_PATH", "library.bleve") // Stored next to library.db
+
+	// Cover configuration, "local" keeps images under CoverDir, "s3" in an S3-compatible bucket
+	CoverStorage = getEnv("COVER_STORAGE", "local")
+	CoverDir = getEnv("COVER_DIR", "covers")
+	CoverBaseURL = getEnv("COVER_BASE_URL", "/covers") // Public prefix of cover URLs, served by the app by default
+	coverMaxBytes, err := strconv.ParseInt(getEnv("COVER_MAX_BYTES", "5242880"), 10, 64)
+	if err != nil {
+		coverMaxBytes = 5 << 20 // default to 5 MiB
+	}
+	CoverMaxBytes = coverMaxBytes
+
+	S3Endpoint = getEnv("S3_ENDPOINT", "https://s3.amazonaws.com") // Path-style requests, so MinIO works as well
+	S3Region = getEnv("S3_REGION", "us-east-1")
+	S3Bucket = getEnv("S3_BUCKET", "")
+	S3AccessKey = getEnv("S3_ACCESS_KEY", "")
+	S3SecretKey = getEnv("S3_SECRET_KEY", "")
+
+	// Metadata configuration, providers are asked in order and "none" disables lookups
+	MetadataProviders = getEnv("METADATA_PROVIDERS", "openlibrary,googlebooks")
+	OpenLibraryURL = getEnv("OPEN_LIBRARY_URL", "https://openlibrary.org")
+	GoogleBooksURL = getEnv("GOOGLE_BOOKS_URL", "https://www.googleapis.com/books/v1")
+	GoogleBooksAPIKey = getEnv("GOOGLE_BOOKS_API_KEY", "")
+	MetadataCacheHours, err = strconv.Atoi(getEnv("METADATA_CACHE_HOURS", "24"))
+	if err != nil {
+		MetadataCacheHours = 24 // default to a day
+	}
+	MetadataCacheSize, err = strconv.Atoi(getEnv("METADATA_CACHE_SIZE", "10000"))
+	if err != nil {
+		MetadataCacheSize = 10000
+	}
+
+	// OAI-PMH configuration, record identifiers are oai:<OAI_REPOSITORY_IDENTIFIER>:<book ID>
+	OAIRepositoryName = getEnv("OAI_REPOSITORY_NAME", "Library")
+	OAIRepositoryIdentifier = getEnv("OAI_REPOSITORY_IDENTIFIER", "library.local") // A domain name the library controls
+	OAIAdminEmail = getEnv("OAI_ADMIN_EMAIL", "admin@library.local")
+
+	// SIP2 configuration, the server only listens when SIP_ADDRESS is set, such as ":6001"
+	SIPAddress = getEnv("SIP_ADDRESS", "")
+	SIPInstitutionID = getEnv("SIP_INSTITUTION_ID", "library")
+	SIPLoanDays, err = strconv.Atoi(getEnv("SIP_LOAN_DAYS", "21")) // Loans store no due date, so machines are told this period
+	if err != nil {
+		SIPLoanDays = 21
+	}
+	// Readers have no PIN, so patron passwords are reported invalid unless the
+	// machines are trusted to have identified patrons themselves, by a library card say
+	SIPTrustPatronPasswords, _ = strconv.ParseBool(getEnv("SIP_TRUST_PATRON_PASSWORDS", "false"))
 }
 
 func getEnv(key, defaultValue string) string {
@@ -0,0 +1,180 @@
+package covers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+	"net/http"
+	"strings"
+
+	// Decoders for the accepted upload formats
+	_ "image/gif"
+	_ "image/png"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+
+	"library-go/config"
+	"library-go/storage"
+)
+
+// Size is a thumbnail size, scaled to a width and keeping the aspect ratio
+type Size struct {
+	Name  string
+	Width int
+}
+
+// Sizes lists the thumbnails generated for every cover
+var Sizes = []Size{
+	{Name: "small", Width: 80},   // Search results and lists
+	{Name: "medium", Width: 200}, // Book cards
+	{Name: "large", Width: 400},  // Book details
+}
+
+// maxPixels caps the decoded size of an upload, so a small file cannot expand into a huge bitmap
+const maxPixels = 40000000
+
+// thumbnailQuality is the JPEG quality of thumbnails
+const thumbnailQuality = 85
+
+// extensions maps the accepted content types, as sniffed from the data, to file extensions
+var extensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+var (
+	// ErrUnsupportedType is returned for uploads that are not JPEG, PNG, GIF or WebP images
+	ErrUnsupportedType = errors.New("cover must be a JPEG, PNG, GIF or WebP image")
+	// ErrInvalidImage is returned for uploads that cannot be decoded
+	ErrInvalidImage = errors.New("cover image is corrupt or truncated")
+	// ErrTooManyPixels is returned for images whose dimensions exceed maxPixels
+	ErrTooManyPixels = errors.New("cover image dimensions are too large")
+)
+
+var store storage.Store
+
+// Setup opens the configured cover storage
+func Setup() error {
+	s, err := storage.Open()
+	if err != nil {
+		return err
+	}
+	store = s
+	return nil
+}
+
+// Save stores an uploaded cover of a book with its thumbnails and returns the
+// key of the original. Keys carry a hash of the image, so they can be cached forever.
+func Save(ctx context.Context, bookID uint, data []byte) (string, error) {
+	ext, ok := extensions[http.DetectContentType(data)]
+	if !ok {
+		return "", ErrUnsupportedType
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return "", ErrInvalidImage
+	}
+	if cfg.Width*cfg.Height > maxPixels {
+		return "", ErrTooManyPixels
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return "", ErrInvalidImage
+	}
+
+	sum := sha256.Sum256(data)
+	key := fmt.Sprintf("books/%d/%x%s", bookID, sum[:8], ext)
+
+	// Thumbnails first, so the original never exists without them
+	for _, size := range Sizes {
+		thumbnail, err := thumbnail(img, size.Width)
+		if err != nil {
+			return "", err
+		}
+		if err := store.Put(ctx, ThumbnailKey(key, size.Name), thumbnail, "image/jpeg"); err != nil {
+			return "", err
+		}
+	}
+	if err := store.Put(ctx, key, data, http.DetectContentType(data)); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// Remove deletes a cover and its thumbnails
+func Remove(ctx context.Context, key string) error {
+	keys := []string{key}
+	for _, size := range Sizes {
+		keys = append(keys, ThumbnailKey(key, size.Name))
+	}
+	var errs []string
+	for _, k := range keys {
+		if err := store.Delete(ctx, k); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// Open opens a stored cover or thumbnail by key
+func Open(ctx context.Context, key string) (io.ReadCloser, string, error) {
+	return store.Get(ctx, key)
+}
+
+// ThumbnailKey returns the key of a thumbnail of the cover stored under key
+func ThumbnailKey(key, size string) string {
+	if dot := strings.LastIndex(key, "."); dot > strings.LastIndex(key, "/") {
+		key = key[:dot]
+	}
+	return key + "-" + size + ".jpg"
+}
+
+// URL returns the public URL of a stored cover or thumbnail
+func URL(key string) string {
+	return strings.TrimRight(config.CoverBaseURL, "/") + "/" + key
+}
+
+// ThumbnailURLs returns the public URLs of the thumbnails of a cover by size name
+func ThumbnailURLs(key string) map[string]string {
+	urls := make(map[string]string, len(Sizes))
+	for _, size := range Sizes {
+		urls[size.Name] = URL(ThumbnailKey(key, size.Name))
+	}
+	return urls
+}
+
+// thumbnail scales img to width, never enlarging it, and encodes it as a JPEG
+// on a white background in place of any transparency
+func thumbnail(img image.Image, width int) ([]byte, error) {
+	bounds := img.Bounds()
+	if bounds.Dx() < width {
+		width = bounds.Dx()
+	}
+	height := bounds.Dy() * width / bounds.Dx()
+	if height < 1 {
+		height = 1
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, draw.Over, nil)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: thumbnailQuality}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
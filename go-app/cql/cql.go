@@ -0,0 +1,312 @@
+// Package cql parses queries in the Contextual Query Language used by SRU,
+// such as `dc.title = "dune" and author any "herbert frank"`, into a tree.
+// Deciding which indexes and relations are supported is left to the caller.
+package cql
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ServerChoice is the index of a search term given without one
+const ServerChoice = "cql.serverchoice"
+
+// Node is a Clause or a Boolean
+type Node interface {
+	node()
+}
+
+// Clause is a search term with its index and relation. Index and Relation are
+// lower-cased; Term keeps its masking characters and backslash escapes.
+type Clause struct {
+	Index     string
+	Relation  string
+	Modifiers []Modifier
+	Term      string
+}
+
+// Boolean combines two subqueries; Op is and, or, not or prox
+type Boolean struct {
+	Op        string
+	Modifiers []Modifier
+	Left      Node
+	Right     Node
+}
+
+// Modifier refines a relation or boolean, such as /relevant or /distance<3
+type Modifier struct {
+	Name       string
+	Comparitor string
+	Value      string
+}
+
+func (*Clause) node()  {}
+func (*Boolean) node() {}
+
+// SyntaxError reports a query that is not valid CQL
+type SyntaxError struct {
+	Pos int // Byte offset in the query
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%s at position %d", e.Msg, e.Pos+1)
+}
+
+// Named comparitors; any other word with a dot is a relation of a context set
+var namedRelations = map[string]bool{
+	"any": true, "all": true, "adj": true, "within": true, "encloses": true, "exact": true,
+}
+
+// Parse parses a CQL query. Prefix assignments are not supported.
+func Parse(query string) (Node, error) {
+	tokens, err := lex(query)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	if p.peek().kind == tokenEOF {
+		return nil, &SyntaxError{Pos: 0, Msg: "empty query"}
+	}
+	node, err := p.query()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, &SyntaxError{Pos: t.pos, Msg: "unexpected " + t.String()}
+	}
+	return node, nil
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenWord
+	tokenQuoted
+	tokenSymbol // Comparitor symbols: = == <> < > <= >=
+	tokenOpen
+	tokenClose
+	tokenSlash
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+	pos   int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokenEOF:
+		return "end of query"
+	case tokenQuoted:
+		return `"` + t.value + `"`
+	}
+	return "'" + t.value + "'"
+}
+
+// boolean returns the lower-cased operator when the token is one
+func (t token) boolean() (string, bool) {
+	if t.kind != tokenWord {
+		return "", false
+	}
+	switch op := strings.ToLower(t.value); op {
+	case "and", "or", "not", "prox":
+		return op, true
+	}
+	return "", false
+}
+
+// term reports whether the token can be a search term
+func (t token) term() bool {
+	return t.kind == tokenWord || t.kind == tokenQuoted
+}
+
+func lex(query string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(query); {
+		switch c := query[i]; {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			tokens = append(tokens, token{tokenOpen, "(", i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{tokenClose, ")", i})
+			i++
+		case c == '/':
+			tokens = append(tokens, token{tokenSlash, "/", i})
+			i++
+		case c == '=' || c == '<' || c == '>':
+			symbol := string(c)
+			if i+1 < len(query) {
+				if two := query[i : i+2]; two == "==" || two == "<>" || two == "<=" || two == ">=" {
+					symbol = two
+				}
+			}
+			tokens = append(tokens, token{tokenSymbol, symbol, i})
+			i += len(symbol)
+		case c == '"':
+			// Escapes are kept, so masking characters can be told from literal ones
+			start := i
+			var b strings.Builder
+			for i++; ; i++ {
+				if i >= len(query) {
+					return nil, &SyntaxError{Pos: start, Msg: "unterminated quoted string"}
+				}
+				if query[i] == '\\' && i+1 < len(query) {
+					b.WriteString(query[i : i+2])
+					i++
+					continue
+				}
+				if query[i] == '"' {
+					break
+				}
+				b.WriteByte(query[i])
+			}
+			tokens = append(tokens, token{tokenQuoted, b.String(), start})
+			i++
+		default:
+			start := i
+			for i < len(query) && !strings.ContainsRune(" \t\n\r()/=<>\"", rune(query[i])) {
+				if query[i] == '\\' && i+1 < len(query) {
+					i++
+				}
+				i++
+			}
+			tokens = append(tokens, token{tokenWord, query[start:i], start})
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(query)}), nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) peekAt(offset int) token {
+	if p.pos+offset >= len(p.tokens) {
+		return p.tokens[len(p.tokens)-1]
+	}
+	return p.tokens[p.pos+offset]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+// query parses search clauses joined by booleans, which all have the same
+// precedence and associate to the left
+func (p *parser) query() (Node, error) {
+	left, err := p.searchClause()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.peek().boolean()
+		if !ok {
+			return left, nil
+		}
+		p.next()
+		modifiers, err := p.modifiers()
+		if err != nil {
+			return nil, err
+		}
+		right, err := p.searchClause()
+		if err != nil {
+			return nil, err
+		}
+		left = &Boolean{Op: op, Modifiers: modifiers, Left: left, Right: right}
+	}
+}
+
+func (p *parser) searchClause() (Node, error) {
+	t := p.peek()
+	if t.kind == tokenOpen {
+		p.next()
+		node, err := p.query()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokenClose {
+			return nil, &SyntaxError{Pos: closing.pos, Msg: "expected ')' but found " + closing.String()}
+		}
+		return node, nil
+	}
+	if !t.term() {
+		return nil, &SyntaxError{Pos: t.pos, Msg: "expected a search term but found " + t.String()}
+	}
+	if _, ok := t.boolean(); ok && !p.relationFollows() {
+		return nil, &SyntaxError{Pos: t.pos, Msg: "expected a search term but found " + t.String()}
+	}
+
+	if !p.relationFollows() {
+		p.next()
+		return &Clause{Index: ServerChoice, Relation: "=", Term: t.value}, nil
+	}
+
+	index := p.next()
+	if index.kind != tokenWord {
+		return nil, &SyntaxError{Pos: index.pos, Msg: "an index cannot be quoted"}
+	}
+	relation := p.next()
+	modifiers, err := p.modifiers()
+	if err != nil {
+		return nil, err
+	}
+	term := p.next()
+	if !term.term() {
+		return nil, &SyntaxError{Pos: term.pos, Msg: "expected a search term but found " + term.String()}
+	}
+	return &Clause{Index: strings.ToLower(index.value), Relation: strings.ToLower(relation.value), Modifiers: modifiers, Term: term.value}, nil
+}
+
+// relationFollows reports whether the token after the current one is a
+// relation, making the current one an index rather than a bare term
+func (p *parser) relationFollows() bool {
+	next := p.peekAt(1)
+	if next.kind == tokenSymbol {
+		return true
+	}
+	if next.kind != tokenWord {
+		return false
+	}
+	if _, ok := next.boolean(); ok {
+		return false
+	}
+	name := strings.ToLower(next.value)
+	return namedRelations[name] || strings.Contains(name, ".")
+}
+
+// modifiers parses an optional list of /name, /name=value modifiers
+func (p *parser) modifiers() ([]Modifier, error) {
+	var modifiers []Modifier
+	for p.peek().kind == tokenSlash {
+		p.next()
+		name := p.next()
+		if name.kind != tokenWord {
+			return nil, &SyntaxError{Pos: name.pos, Msg: "expected a modifier name but found " + name.String()}
+		}
+		modifier := Modifier{Name: strings.ToLower(name.value)}
+		if p.peek().kind == tokenSymbol {
+			modifier.Comparitor = p.next().value
+			value := p.next()
+			if !value.term() {
+				return nil, &SyntaxError{Pos: value.pos, Msg: "expected a modifier value but found " + value.String()}
+			}
+			modifier.Value = value.value
+		}
+		modifiers = append(modifiers, modifier)
+	}
+	return modifiers, nil
+}
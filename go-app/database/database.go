@@ -3,6 +3,7 @@ package database
 import (
 	"log"
 	"strings"
+	"time"
 
 	"gorm.io/driver/sqlite"
 	"gorm.io/driver/postgres"
@@ -10,6 +11,7 @@ import (
 
 	"library-go/config"
 	"library-go/models"
+	"library-go/search"
 )
 
 var DB *gorm.DB
@@ -25,7 +27,7 @@ func InitDB() {
 
 	// For SQLite
 	if strings.Contains(strings.ToLower(dbURL), "sqlite") || strings.HasSuffix(strings.ToLower(dbURL), ".db") {
-		DB, err = gorm.Open(sqlite.Open("library.db"), &gorm.Config{})
+		DB, err = gorm.Open(&sqlite.Dialector{DriverName: sqliteDriver, DSN: "library.db"}, &gorm.Config{})
 		if err != nil {
 			log.Fatal("Failed to connect to SQLite database:", err)
 		}
@@ -38,14 +40,89 @@ func InitDB() {
 	}
 
 	// Auto-migrate the schema
-	err = DB.AutoMigrate(&models.User{}, &models.Book{}, &models.Reader{}, &models.Borrow{})
+	err = DB.AutoMigrate(&models.User{}, &models.Book{}, &models.Reader{}, &models.Borrow{}, &models.Session{}, &models.Author{}, &models.Contribution{}, &models.Category{}, &models.Tag{}, &models.ReadingList{}, &models.ReadingListEntry{}, &models.Work{}, &models.Series{}, &models.SeriesEntry{}, &models.Hold{}, &models.DeletedBook{})
 	if err != nil {
 		log.Fatal("Failed to migrate database schema:", err)
 	}
 
+	// Set up the full-text search index
+	if err := search.Setup(DB); err != nil {
+		log.Fatal("Failed to set up full-text search:", err)
+	}
+
+	if err := normalizeISBNs(); err != nil {
+		log.Fatal("Failed to normalize ISBNs:", err)
+	}
+
+	if err := splitAuthors(); err != nil {
+		log.Fatal("Failed to migrate authors:", err)
+	}
+
 	log.Println("Database connected and migrated successfully")
 }
 
+// normalizeISBNs rewrites ISBNs stored before validation was strict as bare ISBN-13s.
+// Invalid or conflicting ISBNs are logged and left for a librarian to fix.
+func normalizeISBNs() error {
+	var books []models.Book
+	if err := DB.Select("id", "isbn").Where("isbn IS NOT NULL").Find(&books).Error; err != nil {
+		return err
+	}
+
+	// Skip the Book hooks, which validate the whole record
+	tx := DB.Session(&gorm.Session{SkipHooks: true})
+	for _, book := range books {
+		isbn, err := models.NormalizeISBN(*book.ISBN)
+		if err != nil {
+			log.Printf("Book %d has an invalid ISBN %q: %v\n", book.ID, *book.ISBN, err)
+			continue
+		}
+		if isbn == *book.ISBN {
+			continue
+		}
+		if err := tx.Model(&models.Book{ID: book.ID}).Updates(map[string]interface{}{"isbn": isbn, "updated_at": time.Now()}).Error; err != nil {
+			log.Printf("Failed to normalize ISBN of book %d: %v\n", book.ID, err)
+		}
+	}
+	return nil
+}
+
+// splitAuthors credits books created before the Author model, which only have
+// a free-text author, to author records split from that text
+func splitAuthors() error {
+	var books []models.Book
+	if err := DB.Select("id", "author").
+		Where("id NOT IN (?)", DB.Model(&models.Contribution{}).Select("book_id")).
+		Find(&books).Error; err != nil {
+		return err
+	}
+	if len(books) == 0 {
+		return nil
+	}
+
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		for _, book := range books {
+			for position, name := range models.SplitAuthorNames(book.Author) {
+				author, err := models.FindOrCreateAuthor(tx, name)
+				if err != nil {
+					return err
+				}
+				contribution := models.Contribution{BookID: book.ID, AuthorID: author.ID, Role: models.RoleAuthor, Position: position}
+				if err := tx.Create(&contribution).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Split the authors of %d books into author records\n", len(books))
+	return nil
+}
+
 func CloseDB() {
 	sqlDB, err := DB.DB()
 	if err != nil {
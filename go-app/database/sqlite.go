@@ -0,0 +1,40 @@
+package database
+
+import (
+	"database/sql"
+	"strings"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// sqliteDriver is go-sqlite3 with lower() and upper() folding every letter,
+// as Postgres does, instead of only ASCII. Searches lower-case queries in Go
+// and compare them to lower(column), which must agree for Cyrillic or Greek.
+const sqliteDriver = "sqlite3_unicode"
+
+func init() {
+	sql.Register(sqliteDriver, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			if err := conn.RegisterFunc("lower", foldCase(strings.ToLower), true); err != nil {
+				return err
+			}
+			return conn.RegisterFunc("upper", foldCase(strings.ToUpper), true)
+		},
+	})
+}
+
+// foldCase applies fold to text, leaving NULL and numbers as they are
+func foldCase(fold func(string) string) func(interface{}) interface{} {
+	return func(v interface{}) interface{} {
+		switch v := v.(type) {
+		case string:
+			return fold(v)
+		case []byte:
+			if v == nil {
+				return nil
+			}
+			return fold(string(v))
+		}
+		return v
+	}
+}
@@ -0,0 +1,41 @@
+// Package dublincore encodes records in simple Dublin Core, the element set
+// the harvesting and search protocols exchange alongside MARC.
+package dublincore
+
+import (
+	"bytes"
+	"encoding/xml"
+)
+
+// Namespace is the namespace of the Dublin Core elements, bound to the dc prefix
+const Namespace = "http://purl.org/dc/elements/1.1/"
+
+// Record is a description in simple Dublin Core, each element repeatable
+type Record struct {
+	Title       []string `xml:"dc:title"`
+	Creator     []string `xml:"dc:creator"`
+	Subject     []string `xml:"dc:subject"`
+	Description []string `xml:"dc:description"`
+	Publisher   []string `xml:"dc:publisher"`
+	Contributor []string `xml:"dc:contributor"`
+	Date        []string `xml:"dc:date"`
+	Type        []string `xml:"dc:type"`
+	Format      []string `xml:"dc:format"`
+	Identifier  []string `xml:"dc:identifier"`
+	Language    []string `xml:"dc:language"`
+}
+
+// Marshal encodes the record as the children of a root element, which
+// declares the dc prefix along with attrs, such as the namespace of a
+// prefixed root name
+func Marshal(record Record, root string, attrs ...xml.Attr) ([]byte, error) {
+	start := xml.StartElement{
+		Name: xml.Name{Local: root},
+		Attr: append([]xml.Attr{{Name: xml.Name{Local: "xmlns:dc"}, Value: Namespace}}, attrs...),
+	}
+	var buf bytes.Buffer
+	if err := xml.NewEncoder(&buf).EncodeElement(record, start); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
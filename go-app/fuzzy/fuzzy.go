@@ -0,0 +1,183 @@
+package fuzzy
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// cyrillic maps Russian and Ukrainian letters to a Latin transliteration
+var cyrillic = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e", 'ж': "zh",
+	'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o",
+	'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts",
+	'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu",
+	'я': "ya", 'і': "i", 'ї': "yi", 'є': "ye", 'ґ': "g",
+}
+
+// variants collapses common differences between romanization schemes, applied in order
+var variants = strings.NewReplacer(
+	"tch", "ch",
+	"kh", "h",
+	"ck", "k",
+	"ph", "f",
+	"w", "v",
+	"x", "ks",
+	"tz", "ts",
+	"j", "i",
+	"y", "i",
+)
+
+// Transliterate lower-cases text, converts Cyrillic to Latin and strips diacritics
+func Transliterate(text string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(text) {
+		if latin, ok := cyrillic[r]; ok {
+			b.WriteString(latin)
+			continue
+		}
+		b.WriteRune(r)
+	}
+
+	// Decompose accented letters and drop the combining marks
+	var stripped strings.Builder
+	for _, r := range norm.NFD.String(b.String()) {
+		if !unicode.Is(unicode.Mn, r) {
+			stripped.WriteRune(r)
+		}
+	}
+	return stripped.String()
+}
+
+// Words splits text into transliterated words, keeping their spelling
+func Words(text string) []string {
+	return strings.FieldsFunc(Transliterate(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// Fold returns the words of text reduced to a canonical spelling, so that
+// "Dostoevsky", "Dostoyevsky" and "Достоевский" all fold to "dostoevski"
+func Fold(text string) []string {
+	words := Words(text)
+	for i, word := range words {
+		words[i] = foldWord(word)
+	}
+	return words
+}
+
+func foldWord(word string) string {
+	word = variants.Replace(word)
+
+	// Collapse doubled letters ("ii" -> "i", "ss" -> "s")
+	runes := []rune(word)
+	collapsed := runes[:0]
+	for i, r := range runes {
+		if i > 0 && r == runes[i-1] {
+			continue
+		}
+		collapsed = append(collapsed, r)
+	}
+
+	// Drop an iotating "i" between vowels ("dostoievski" -> "dostoevski") and before a leading "e"
+	folded := make([]rune, 0, len(collapsed))
+	for i, r := range collapsed {
+		if r == 'i' && i+1 < len(collapsed) && isVowel(collapsed[i+1]) &&
+			((i > 0 && isVowel(collapsed[i-1])) || (i == 0 && collapsed[i+1] == 'e')) {
+			continue
+		}
+		folded = append(folded, r)
+	}
+	return string(folded)
+}
+
+func isVowel(r rune) bool {
+	return strings.ContainsRune("aeiou", r)
+}
+
+// Trigrams returns the distinct trigrams of a word padded with spaces
+func Trigrams(word string) []string {
+	runes := []rune("  " + word + " ")
+	seen := make(map[string]bool, len(runes))
+	var grams []string
+	for i := 0; i+3 <= len(runes); i++ {
+		gram := string(runes[i : i+3])
+		if !seen[gram] {
+			seen[gram] = true
+			grams = append(grams, gram)
+		}
+	}
+	return grams
+}
+
+// Similarity scores two folded words between 0 and 1, taking the better of
+// trigram overlap and normalized edit distance
+func Similarity(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+
+	gramsA, gramsB := Trigrams(a), Trigrams(b)
+	inA := make(map[string]bool, len(gramsA))
+	for _, gram := range gramsA {
+		inA[gram] = true
+	}
+	shared := 0
+	for _, gram := range gramsB {
+		if inA[gram] {
+			shared++
+		}
+	}
+	trigram := float64(shared) / float64(len(gramsA)+len(gramsB)-shared)
+
+	longest := len([]rune(a))
+	if n := len([]rune(b)); n > longest {
+		longest = n
+	}
+	edit := 1 - float64(Distance(a, b))/float64(longest)
+
+	if edit > trigram {
+		return edit
+	}
+	return trigram
+}
+
+// Distance returns the Damerau-Levenshtein (optimal string alignment) distance between two words
+func Distance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	rows := make([][]int, len(ra)+1)
+	for i := range rows {
+		rows[i] = make([]int, len(rb)+1)
+		rows[i][0] = i
+	}
+	for j := 0; j <= len(rb); j++ {
+		rows[0][j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			d := minInt(rows[i-1][j]+1, rows[i][j-1]+1, rows[i-1][j-1]+cost)
+			// Adjacent transposition ("dostoevksy")
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				d = minInt(d, rows[i-2][j-2]+1)
+			}
+			rows[i][j] = d
+		}
+	}
+	return rows[len(ra)][len(rb)]
+}
+
+func minInt(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
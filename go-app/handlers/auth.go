@@ -3,9 +3,9 @@ package handlers
 import (
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	"golang.org/x/crypto/bcrypt"
 	"library-go/auth"
 	"library-go/database"
 	"library-go/models"
@@ -61,6 +61,7 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	var input struct {
 		Email    string `json:"username"` // Using username field as email
 		Password string `json:"password"`
+		Device   string `json:"device"` // Optional client supplied device name
 	}
 
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -87,8 +88,36 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
+	// Record the session the token is issued for
+	tokenID, err := auth.NewSessionID()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create session"})
+		return
+	}
+
+	device := strings.TrimSpace(input.Device)
+	if device == "" {
+		device = models.DeviceFromUserAgent(c.Request.UserAgent())
+	}
+
+	now := time.Now()
+	session := models.Session{
+		UserID:     user.ID,
+		TokenID:    tokenID,
+		Device:     device,
+		IPAddress:  c.ClientIP(),
+		UserAgent:  c.Request.UserAgent(),
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(auth.TokenExpiry()),
+	}
+
+	if err := database.DB.Create(&session).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create session"})
+		return
+	}
+
 	// Generate JWT token
-	token, err := auth.GenerateToken(user.Email, user.IsAdmin)
+	token, err := auth.GenerateToken(user.Email, user.IsAdmin, tokenID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
@@ -118,8 +147,20 @@ func (h *AuthHandler) GetMe(c *gin.Context) {
 	})
 }
 
-// Logout handles user logout (just a confirmation since JWT is stateless)
+// Logout handles user logout by revoking the session of the presented token
 func (h *AuthHandler) Logout(c *gin.Context) {
+	session, exists := c.Get("session")
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Session not found in context"})
+		return
+	}
+
+	sessionModel := session.(models.Session)
+	if _, err := revokeSessions(database.DB.Where("id = ?", sessionModel.ID)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Successfully logged out",
 	})
@@ -168,8 +209,41 @@ func (h *AuthHandler) AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		// Set user in context
+		// Check that the session the token was issued for is still active
+		var session models.Session
+		if err := database.DB.Where("token_id = ? AND user_id = ?", claims.SessionID, user.ID).First(&session).Error; err != nil || !session.IsActive() {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Session has been terminated"})
+			c.Abort()
+			return
+		}
+
+		// Refresh last seen information, at most once per minute to limit writes
+		now := time.Now()
+		if now.Sub(session.LastSeenAt) > time.Minute || session.IPAddress != c.ClientIP() {
+			session.LastSeenAt = now
+			session.IPAddress = c.ClientIP()
+			database.DB.Model(&session).Updates(map[string]interface{}{
+				"last_seen_at": session.LastSeenAt,
+				"ip_address":   session.IPAddress,
+			})
+		}
+
+		// Set user and session in context
 		c.Set("user", user)
+		c.Set("session", session)
+		c.Next()
+	}
+}
+
+// AdminMiddleware restricts a route to admin users, it must run after AuthMiddleware
+func (h *AuthHandler) AdminMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, exists := c.Get("user")
+		if !exists || !user.(models.User).IsAdmin {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Admin privileges required"})
+			c.Abort()
+			return
+		}
 		c.Next()
 	}
-}
\ No newline at end of file
+}
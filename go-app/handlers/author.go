@@ -0,0 +1,489 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"library-go/database"
+	"library-go/models"
+	"library-go/query"
+	"library-go/suggest"
+)
+
+type AuthorHandler struct{}
+
+// authorFields lists the author fields usable in filters, sort and field selection
+var authorFields = query.Resource{
+	"id":         {Type: query.Int, Filter: true, Sort: true},
+	"name":       {Type: query.String, Filter: true, Sort: true},
+	"bio":        {Type: query.String, Filter: true},
+	"created_at": {Type: query.Time, Filter: true, Sort: true},
+	"updated_at": {Type: query.Time, Filter: true, Sort: true},
+}
+
+// contributorInput names a contributor of a book, by author ID or by name
+type contributorInput struct {
+	AuthorID *uint  `json:"author_id"`
+	Name     string `json:"name"`
+	Role     string `json:"role"`
+}
+
+func NewAuthorHandler() *AuthorHandler {
+	return &AuthorHandler{}
+}
+
+// GetAuthors retrieves all authors
+func (h *AuthorHandler) GetAuthors(c *gin.Context) {
+	var authors []models.Author
+
+	page, ok := parsePage(c, query.DefaultLimit)
+	if !ok {
+		return
+	}
+	params, ok := parseListParams(c, authorFields)
+	if !ok {
+		return
+	}
+
+	var total int64
+	if err := database.DB.Model(&models.Author{}).Scopes(params.FilterScope()).Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch authors"})
+		return
+	}
+
+	if err := database.DB.Scopes(params.Scope("name")).Offset(page.Offset()).Limit(page.Limit).Find(&authors).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch authors"})
+		return
+	}
+
+	respondPage(c, params, page, total, authors)
+}
+
+// GetAuthor retrieves a specific author by ID
+func (h *AuthorHandler) GetAuthor(c *gin.Context) {
+	author, ok := h.findAuthor(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, author)
+}
+
+// GetAuthorBooks retrieves the books an author contributed to, optionally in a single role
+func (h *AuthorHandler) GetAuthorBooks(c *gin.Context) {
+	author, ok := h.findAuthor(c)
+	if !ok {
+		return
+	}
+
+	page, ok := parsePage(c, query.DefaultLimit)
+	if !ok {
+		return
+	}
+	params, ok := parseListParams(c, bookFields, "role")
+	if !ok {
+		return
+	}
+
+	contributions := database.DB.Model(&models.Contribution{}).Select("book_id").Where("author_id = ?", author.ID)
+	if role := c.Query("role"); role != "" {
+		if !models.IsContributorRole(role) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "role must be one of: " + strings.Join(models.ContributorRoles, ", ")})
+			return
+		}
+		contributions = contributions.Where("role = ?", role)
+	}
+
+	var total int64
+	if err := database.DB.Model(&models.Book{}).Where("id IN (?)", contributions).Scopes(params.FilterScope()).Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch books"})
+		return
+	}
+
+	var books []models.Book
+	if err := database.DB.Scopes(withContributors, params.Scope("id")).Where("id IN (?)", contributions).
+		Offset(page.Offset()).Limit(page.Limit).Find(&books).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch books"})
+		return
+	}
+
+	respondPage(c, params, page, total, books)
+}
+
+// CreateAuthor creates a new author
+func (h *AuthorHandler) CreateAuthor(c *gin.Context) {
+	var input models.Author
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := input.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := database.DB.Omit(clause.Associations).Create(&input).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create author"})
+		return
+	}
+	suggest.AddAuthor(input)
+
+	c.JSON(http.StatusCreated, input)
+}
+
+// UpdateAuthor updates an existing author, refreshing the credit line of their books on a rename
+func (h *AuthorHandler) UpdateAuthor(c *gin.Context) {
+	author, ok := h.findAuthor(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Name *string `json:"name"`
+		Bio  *string `json:"bio"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	renamed := input.Name != nil && *input.Name != author.Name
+	if input.Name != nil {
+		author.Name = *input.Name
+	}
+	if input.Bio != nil {
+		author.Bio = input.Bio
+	}
+
+	if err := author.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var changes creditChanges
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(author).Error; err != nil {
+			return err
+		}
+		if !renamed {
+			return nil
+		}
+		var err error
+		changes, err = refreshCreditLines(tx, author.ID)
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update author"})
+		return
+	}
+	suggest.UpdateAuthor(*author)
+	changes.apply()
+
+	c.JSON(http.StatusOK, author)
+}
+
+// DeleteAuthor deletes an author who is not credited on any book
+func (h *AuthorHandler) DeleteAuthor(c *gin.Context) {
+	author, ok := h.findAuthor(c)
+	if !ok {
+		return
+	}
+
+	var contributions int64
+	database.DB.Model(&models.Contribution{}).Where("author_id = ?", author.ID).Count(&contributions)
+
+	if contributions > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot delete author credited on books, merge them into another author instead"})
+		return
+	}
+
+	if err := database.DB.Delete(author).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete author"})
+		return
+	}
+	suggest.RemoveAuthor(*author)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Author deleted successfully"})
+}
+
+// MergeAuthors moves the credits of duplicate authors to this one and deletes the duplicates
+func (h *AuthorHandler) MergeAuthors(c *gin.Context) {
+	author, ok := h.findAuthor(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		AuthorIDs []uint `json:"author_ids" binding:"required,min=1"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var duplicates []models.Author
+	if err := database.DB.Where("id IN ?", input.AuthorIDs).Find(&duplicates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to merge authors"})
+		return
+	}
+	if len(duplicates) != len(uniqueIDs(input.AuthorIDs)) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Author not found"})
+		return
+	}
+	for _, duplicate := range duplicates {
+		if duplicate.ID == author.ID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot merge an author into itself"})
+			return
+		}
+	}
+
+	var changes creditChanges
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		for _, duplicate := range duplicates {
+			var contributions []models.Contribution
+			if err := tx.Where("author_id = ?", duplicate.ID).Find(&contributions).Error; err != nil {
+				return err
+			}
+			for _, contribution := range contributions {
+				// A book crediting both authors in the same role keeps a single credit
+				var existing int64
+				if err := tx.Model(&models.Contribution{}).
+					Where("book_id = ? AND author_id = ? AND role = ?", contribution.BookID, author.ID, contribution.Role).
+					Count(&existing).Error; err != nil {
+					return err
+				}
+				if existing > 0 {
+					if err := tx.Delete(&contribution).Error; err != nil {
+						return err
+					}
+					continue
+				}
+				if err := tx.Model(&contribution).Update("author_id", author.ID).Error; err != nil {
+					return err
+				}
+			}
+			if err := tx.Delete(&duplicate).Error; err != nil {
+				return err
+			}
+		}
+
+		var err error
+		changes, err = refreshCreditLines(tx, author.ID)
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to merge authors"})
+		return
+	}
+	suggest.MergeAuthors(*author, duplicates)
+	changes.apply()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Authors merged successfully", "author": author, "books_updated": len(changes)})
+}
+
+// findAuthor loads the author named by the id parameter, writing an error response on failure
+func (h *AuthorHandler) findAuthor(c *gin.Context) (*models.Author, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid author ID"})
+		return nil, false
+	}
+
+	var author models.Author
+	if err := database.DB.First(&author, uint(id)).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Author not found"})
+		return nil, false
+	}
+	return &author, true
+}
+
+// withContributors preloads the contributors of books in credit order
+func withContributors(db *gorm.DB) *gorm.DB {
+	return db.Preload("Contributors", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position, id")
+	}).Preload("Contributors.Author")
+}
+
+// validateContributors checks roles and author references before any write
+func validateContributors(inputs []contributorInput) error {
+	for i, input := range inputs {
+		if input.Role != "" && !models.IsContributorRole(input.Role) {
+			return fmt.Errorf("contributor %d: role must be one of: %s", i+1, strings.Join(models.ContributorRoles, ", "))
+		}
+		if input.AuthorID == nil && strings.TrimSpace(input.Name) == "" {
+			return fmt.Errorf("contributor %d: author_id or name is required", i+1)
+		}
+		if input.AuthorID != nil {
+			var author models.Author
+			if err := database.DB.First(&author, *input.AuthorID).Error; err != nil {
+				return fmt.Errorf("contributor %d: author %d not found", i+1, *input.AuthorID)
+			}
+		}
+	}
+	return nil
+}
+
+// authorInputs turns a free-text author string into author contributors
+func authorInputs(text string) []contributorInput {
+	var inputs []contributorInput
+	for _, name := range models.SplitAuthorNames(text) {
+		inputs = append(inputs, contributorInput{Name: name, Role: models.RoleAuthor})
+	}
+	return inputs
+}
+
+// resolveContributors finds or creates the authors of inputs, returning
+// contributions in credit order with their authors loaded. Repeated
+// author and role pairs are dropped.
+func resolveContributors(tx *gorm.DB, inputs []contributorInput) ([]models.Contribution, error) {
+	contributions := make([]models.Contribution, 0, len(inputs))
+	seen := make(map[string]bool, len(inputs))
+	for _, input := range inputs {
+		var author *models.Author
+		if input.AuthorID != nil {
+			author = &models.Author{}
+			if err := tx.First(author, *input.AuthorID).Error; err != nil {
+				return nil, err
+			}
+		} else {
+			var err error
+			if author, err = models.FindOrCreateAuthor(tx, input.Name); err != nil {
+				return nil, err
+			}
+		}
+		role := input.Role
+		if role == "" {
+			role = models.RoleAuthor
+		}
+		key := fmt.Sprintf("%d:%s", author.ID, role)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		contributions = append(contributions, models.Contribution{AuthorID: author.ID, Role: role, Position: len(contributions), Author: author})
+	}
+	return contributions, nil
+}
+
+// createContributions credits the resolved contributions on a saved book
+func createContributions(tx *gorm.DB, book *models.Book, contributions []models.Contribution) error {
+	for i := range contributions {
+		contributions[i].BookID = book.ID
+	}
+	if len(contributions) > 0 {
+		if err := tx.Omit(clause.Associations).Create(&contributions).Error; err != nil {
+			return err
+		}
+	}
+	book.Contributors = contributions
+	return nil
+}
+
+// replaceContributors replaces the credits of a book, or only its author
+// credits when authorsOnly is set, and recomputes book.Author. The book itself
+// is not saved.
+func replaceContributors(tx *gorm.DB, book *models.Book, inputs []contributorInput, authorsOnly bool) error {
+	contributions, err := resolveContributors(tx, inputs)
+	if err != nil {
+		return err
+	}
+
+	existing := tx.Where("book_id = ?", book.ID)
+	if authorsOnly {
+		existing = existing.Where("role = ?", models.RoleAuthor)
+	}
+	if err := existing.Delete(&models.Contribution{}).Error; err != nil {
+		return err
+	}
+	if err := createContributions(tx, book, contributions); err != nil {
+		return err
+	}
+
+	// Credits kept from other roles follow the new ones
+	if authorsOnly {
+		var kept []models.Contribution
+		if err := tx.Where("book_id = ? AND role <> ?", book.ID, models.RoleAuthor).Order("position, id").Find(&kept).Error; err != nil {
+			return err
+		}
+		for i, contribution := range kept {
+			if err := tx.Model(&contribution).Update("position", len(contributions)+i).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	return loadCreditLine(tx, book)
+}
+
+// loadCreditLine reloads the contributors of a book and derives book.Author from them
+func loadCreditLine(tx *gorm.DB, book *models.Book) error {
+	book.Contributors = nil
+	if err := tx.Where("book_id = ?", book.ID).Order("position, id").Preload("Author").Find(&book.Contributors).Error; err != nil {
+		return err
+	}
+	if line := models.CreditLine(book.Contributors); line != "" {
+		book.Author = line
+	}
+	return nil
+}
+
+// creditChange records a book whose credit line changed, for the suggestion index
+type creditChange struct {
+	old, book models.Book
+}
+
+type creditChanges []creditChange
+
+func (changes creditChanges) apply() {
+	for _, change := range changes {
+		suggest.UpdateBook(change.old, change.book)
+	}
+}
+
+// refreshCreditLines recomputes Book.Author for every book crediting the author
+func refreshCreditLines(tx *gorm.DB, authorID uint) (creditChanges, error) {
+	var books []models.Book
+	if err := tx.Where("id IN (?)", tx.Model(&models.Contribution{}).Select("book_id").Where("author_id = ?", authorID)).
+		Find(&books).Error; err != nil {
+		return nil, err
+	}
+
+	var changes creditChanges
+	for i := range books {
+		book := &books[i]
+		old := *book
+		if err := loadCreditLine(tx, book); err != nil {
+			return nil, err
+		}
+		if book.Author == old.Author {
+			continue
+		}
+		if err := tx.Omit(clause.Associations).Save(book).Error; err != nil {
+			return nil, err
+		}
+		changes = append(changes, creditChange{old: old, book: *book})
+	}
+	return changes, nil
+}
+
+// uniqueIDs returns ids without duplicates
+func uniqueIDs(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	unique := ids[:0:0]
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
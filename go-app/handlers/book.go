@@ -3,14 +3,58 @@ package handlers
 import (
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"library-go/database"
 	"library-go/models"
+	"library-go/query"
+	"library-go/search"
+	"library-go/suggest"
 )
 
 type BookHandler struct{}
 
+// bookFields lists the book fields usable in filters, sort and field selection
+var bookFields = query.Resource{
+	"id":               {Type: query.Int, Filter: true, Sort: true},
+	"title":            {Type: query.String, Filter: true, Sort: true},
+	"author":           {Type: query.String, Filter: true, Sort: true},
+	"year":             {Type: query.Int, Filter: true, Sort: true},
+	"isbn":             {Type: query.String, Filter: true, Sort: true, Normalize: models.NormalizeISBN},
+	"copies":           {Type: query.Int, Filter: true, Sort: true},
+	"description":      {Type: query.String, Filter: true},
+	"publisher":        {Type: query.String, Filter: true, Sort: true},
+	"language":         {Type: query.String, Filter: true, Sort: true, Normalize: models.NormalizeLanguage},
+	"edition":          {Type: query.String, Filter: true},
+	"page_count":       {Type: query.Int, Filter: true, Sort: true},
+	"format":           {Type: query.String, Filter: true, Sort: true, Normalize: models.NormalizeFormat},
+	"work_id":          {Type: query.Int, Filter: true, Sort: true},
+	"created_at":       {Type: query.Time, Filter: true, Sort: true},
+	"updated_at":       {Type: query.Time, Filter: true, Sort: true},
+	"isbn_13":          {},
+	"isbn_10":          {},
+	"cover_url":        {},
+	"cover_thumbnails": {},
+	"contributors":     {},
+	"categories":       {},
+	"tags":             {},
+}
+
+// maxFuzzyCandidates caps the number of fuzzy matches a search considers
+const maxFuzzyCandidates = 500
+
+// searchFields extends bookFields with the relevance score of search hits
+var searchFields = func() query.Resource {
+	fields := query.Resource{"rank": {}}
+	for name, field := range bookFields {
+		fields[name] = field
+	}
+	return fields
+}()
+
 func NewBookHandler() *BookHandler {
 	return &BookHandler{}
 }
@@ -20,16 +64,146 @@ func (h *BookHandler) GetBooks(c *gin.Context) {
 	var books []models.Book
 	
 	// Get pagination parameters
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
-	offset := (page - 1) * limit
+	page, ok := parsePage(c, query.DefaultLimit)
+	if !ok {
+		return
+	}
+
+	// Get filter, sort and field selection parameters
+	params, ok := parseListParams(c, bookFields, "category", "tag")
+	if !ok {
+		return
+	}
+
+	related, ok := relatedBookScopes(c)
+	if !ok {
+		return
+	}
+
+	var total int64
+	if err := database.DB.Model(&models.Book{}).Scopes(params.FilterScope()).Scopes(related...).Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch books"})
+		return
+	}
 
-	if err := database.DB.Offset(offset).Limit(limit).Find(&books).Error; err != nil {
+	if err := database.DB.Scopes(withContributors, withCategories, withTags, params.Scope("id")).Scopes(related...).Offset(page.Offset()).Limit(page.Limit).Find(&books).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch books"})
 		return
 	}
 
-	c.JSON(http.StatusOK, books)
+	respondPage(c, params, page, total, newBookResponses(books))
+}
+
+// relatedBookScopes returns the category and tag filters of a book list, writing an error response on failure
+func relatedBookScopes(c *gin.Context) ([]func(*gorm.DB) *gorm.DB, bool) {
+	// A category filter includes the books of its descendants
+	var related []func(*gorm.DB) *gorm.DB
+	if ref := c.Query("category"); ref != "" {
+		inCategory, err := categoryBookIDs(ref)
+		if err != nil {
+			respondCategoryError(c, err)
+			return nil, false
+		}
+		related = append(related, func(db *gorm.DB) *gorm.DB {
+			return db.Where("id IN (?)", inCategory)
+		})
+	}
+	if ref := c.Query("tag"); ref != "" {
+		tag, ok := findTag(c, ref)
+		if !ok {
+			return nil, false
+		}
+		related = append(related, func(db *gorm.DB) *gorm.DB {
+			return db.Where("id IN (?)", tagBookIDs(tag.ID))
+		})
+	}
+
+	return related, true
+}
+
+// searchHit is a book found by a search with its rank
+type searchHit struct {
+	bookResponse
+	Rank float64 `json:"rank"`
+}
+
+// SearchBooks performs a ranked full-text search over the catalog, returning
+// facet counts for drill-down alongside the results
+func (h *BookHandler) SearchBooks(c *gin.Context) {
+	// Get pagination parameters
+	page, ok := parsePage(c, 20)
+	if !ok {
+		return
+	}
+
+	// Get filter parameters, including the facet drill-down filters
+	params, ok := parseListParams(c, searchFields, "q", "decade", "available", "category", "facets", "fuzzy")
+	if !ok {
+		return
+	}
+
+	req := search.Request{
+		Query:  strings.TrimSpace(c.Query("q")),
+		Scopes: []func(*gorm.DB) *gorm.DB{params.FilterScope()},
+		Limit:  page.Limit,
+		Offset: page.Offset(),
+		Facets: c.DefaultQuery("facets", "true") != "false",
+	}
+
+	// Fuzzy search tolerates typos and transliteration, matching in memory first
+	if c.Query("fuzzy") == "true" && req.Query != "" {
+		req.Candidates = []search.Candidate{}
+		for _, match := range suggest.Match(suggest.Books, req.Query, maxFuzzyCandidates) {
+			req.Candidates = append(req.Candidates, search.Candidate{ID: match.ID, Score: match.Score})
+		}
+	}
+
+	if raw := c.Query("decade"); raw != "" {
+		decade, err := strconv.Atoi(raw)
+		if err != nil || decade%10 != 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "decade must be a year divisible by 10"})
+			return
+		}
+		req.Scopes = append(req.Scopes, search.DecadeScope(decade))
+	}
+	if raw := c.Query("available"); raw != "" {
+		available, err := strconv.ParseBool(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "available must be true or false"})
+			return
+		}
+		req.Scopes = append(req.Scopes, search.AvailabilityScope(available))
+	}
+
+	if ref := c.Query("category"); ref != "" {
+		inCategory, err := categoryBookIDs(ref)
+		if err != nil {
+			respondCategoryError(c, err)
+			return
+		}
+		req.Scopes = append(req.Scopes, search.CategoryScope(inCategory))
+	}
+
+	result, err := search.Books(database.DB, req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search books"})
+		return
+	}
+
+	hits := make([]searchHit, len(result.Hits))
+	for i, hit := range result.Hits {
+		hits[i] = searchHit{bookResponse: newBookResponse(hit.Book), Rank: hit.Rank}
+	}
+	data, ok := selectFields(c, params, hits)
+	if !ok {
+		return
+	}
+
+	response := pageResponse(c, page, result.Total, data)
+	if result.Facets != nil {
+		response.Facets = result.Facets
+	}
+	c.JSON(http.StatusOK, response)
 }
 
 // GetBook retrieves a specific book by ID
@@ -41,25 +215,77 @@ func (h *BookHandler) GetBook(c *gin.Context) {
 	}
 
 	var book models.Book
-	if err := database.DB.First(&book, uint(id)).Error; err != nil {
+	if err := database.DB.Scopes(withContributors, withCategories, withTags).First(&book, uint(id)).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Book not found"})
 		return
 	}
 
-	c.JSON(http.StatusOK, book)
+	respondBook(c, &book, bookMediaTypes)
 }
 
-// CreateBook creates a new book
+// GetBookByISBN retrieves a book by ISBN, given in either ISBN-10 or ISBN-13 form
+func (h *BookHandler) GetBookByISBN(c *gin.Context) {
+	isbn, err := models.NormalizeISBN(c.Param("isbn"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var book models.Book
+	if err := database.DB.Scopes(withContributors, withCategories, withTags).Where("isbn = ?", isbn).First(&book).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Book not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, newBookResponse(book))
+}
+
+// CreateBook creates a new book, crediting the given contributors or, without
+// them, the authors named in the author field. With enrich set, fields left
+// empty are filled from external catalogues by ISBN.
 func (h *BookHandler) CreateBook(c *gin.Context) {
-	var input models.Book
+	var input struct {
+		models.Book
+		Contributors []contributorInput `json:"contributors"`
+		CategoryIDs  []uint             `json:"category_ids"`
+		Enrich       bool               `json:"enrich"` // Fill empty fields from the catalogue record of the ISBN
+	}
 	
 	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Check if ISBN already exists (if provided)
+	if input.Enrich && !enrichBook(c, &input.Book, &input.Contributors) {
+		return
+	}
+
+	contributors := input.Contributors
+	if len(contributors) == 0 {
+		contributors = authorInputs(input.Author)
+	}
+	if err := validateContributors(contributors); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateCategoryIDs(input.CategoryIDs); err != nil {
+		respondCategoryError(c, err)
+		return
+	}
+	if err := validateWorkID(input.WorkID); err != nil {
+		respondWorkError(c, err)
+		return
+	}
+
+	// Check if ISBN already exists (if provided), in either ISBN-10 or ISBN-13 form
 	if input.ISBN != nil {
+		isbn, err := models.NormalizeISBN(*input.ISBN)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		input.ISBN = &isbn
+
 		var existingBook models.Book
 		if err := database.DB.Where("isbn = ?", *input.ISBN).First(&existingBook).Error; err == nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Book with this ISBN already exists"})
@@ -67,12 +293,40 @@ func (h *BookHandler) CreateBook(c *gin.Context) {
 		}
 	}
 
-	if err := database.DB.Create(&input).Error; err != nil {
+	book := input.Book
+	book.Categories = nil
+	var invalid error
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		contributions, err := resolveContributors(tx, contributors)
+		if err != nil {
+			return err
+		}
+		if line := models.CreditLine(contributions); line != "" {
+			book.Author = line
+		}
+		// Validate once the credit line is known, so the error can be reported as such
+		if invalid = book.Validate(); invalid != nil {
+			return invalid
+		}
+		if err := tx.Omit(clause.Associations).Create(&book).Error; err != nil {
+			return err
+		}
+		if err := createContributions(tx, &book, contributions); err != nil {
+			return err
+		}
+		return setBookCategories(tx, &book, input.CategoryIDs)
+	})
+	if invalid != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": invalid.Error()})
+		return
+	}
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create book"})
 		return
 	}
+	suggest.AddBook(book)
 
-	c.JSON(http.StatusCreated, input)
+	c.JSON(http.StatusCreated, book)
 }
 
 // UpdateBook updates an existing book
@@ -88,14 +342,23 @@ func (h *BookHandler) UpdateBook(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Book not found"})
 		return
 	}
+	original := book
 
 	var input struct {
-		Title       *string  `json:"title"`
-		Author      *string  `json:"author"`
-		Year        *int     `json:"year"`
-		ISBN        *string  `json:"isbn"`
-		Copies      *int     `json:"copies"`
-		Description *string  `json:"description"`
+		Title        *string             `json:"title"`
+		Author       *string             `json:"author"`
+		Year         *int                `json:"year"`
+		ISBN         *string             `json:"isbn"`
+		Copies       *int                `json:"copies"`
+		Description  *string             `json:"description"`
+		Publisher    *string             `json:"publisher"`
+		Language     *string             `json:"language"`
+		Edition      *string             `json:"edition"`
+		PageCount    *int                `json:"page_count"`
+		Format       *string             `json:"format"`
+		WorkID       *uint               `json:"work_id"` // 0 detaches the book from its work
+		Contributors *[]contributorInput `json:"contributors"`
+		CategoryIDs  *[]uint             `json:"category_ids"`
 	}
 
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -114,8 +377,15 @@ func (h *BookHandler) UpdateBook(c *gin.Context) {
 		book.Year = input.Year
 	}
 	if input.ISBN != nil {
+		isbn, err := models.NormalizeISBN(*input.ISBN)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		input.ISBN = &isbn
+
 		// Check if ISBN is being updated and if it already exists
-		if *input.ISBN != *book.ISBN {
+		if book.ISBN == nil || *input.ISBN != *book.ISBN {
 			var existingBook models.Book
 			if err := database.DB.Where("isbn = ?", *input.ISBN).First(&existingBook).Error; err == nil {
 				c.JSON(http.StatusBadRequest, gin.H{"error": "Book with this ISBN already exists"})
@@ -130,6 +400,31 @@ func (h *BookHandler) UpdateBook(c *gin.Context) {
 	if input.Description != nil {
 		book.Description = input.Description
 	}
+	if input.Publisher != nil {
+		book.Publisher = input.Publisher
+	}
+	if input.Language != nil {
+		book.Language = input.Language
+	}
+	if input.Edition != nil {
+		book.Edition = input.Edition
+	}
+	if input.PageCount != nil {
+		book.PageCount = input.PageCount
+	}
+	if input.Format != nil {
+		book.Format = *input.Format
+	}
+	if input.WorkID != nil {
+		if *input.WorkID == 0 {
+			book.WorkID = nil
+		} else if err := validateWorkID(input.WorkID); err != nil {
+			respondWorkError(c, err)
+			return
+		} else {
+			book.WorkID = input.WorkID
+		}
+	}
 
 	// Validate the updated book
 	if err := book.Validate(); err != nil {
@@ -137,12 +432,50 @@ func (h *BookHandler) UpdateBook(c *gin.Context) {
 		return
 	}
 
-	if err := database.DB.Save(&book).Error; err != nil {
+	// Contributors replace every credit, a changed author string only the author credits
+	var contributors []contributorInput
+	authorsOnly := false
+	if input.Contributors != nil {
+		contributors = *input.Contributors
+	} else if input.Author != nil && book.Author != original.Author {
+		contributors = authorInputs(book.Author)
+		authorsOnly = true
+	}
+	if err := validateContributors(contributors); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if input.CategoryIDs != nil {
+		if err := validateCategoryIDs(*input.CategoryIDs); err != nil {
+			respondCategoryError(c, err)
+			return
+		}
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if input.CategoryIDs != nil {
+			if err := setBookCategories(tx, &book, *input.CategoryIDs); err != nil {
+				return err
+			}
+		} else if err := tx.Model(&book).Association("Categories").Find(&book.Categories); err != nil {
+			return err
+		}
+		if input.Contributors != nil || authorsOnly {
+			if err := replaceContributors(tx, &book, contributors, authorsOnly); err != nil {
+				return err
+			}
+		} else if err := loadCreditLine(tx, &book); err != nil {
+			return err
+		}
+		return tx.Omit(clause.Associations).Save(&book).Error
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update book"})
 		return
 	}
+	suggest.UpdateBook(original, book)
 
-	c.JSON(http.StatusOK, book)
+	c.JSON(http.StatusOK, newBookResponse(book))
 }
 
 // DeleteBook deletes a book
@@ -168,10 +501,40 @@ func (h *BookHandler) DeleteBook(c *gin.Context) {
 		return
 	}
 
-	if err := database.DB.Delete(&book).Error; err != nil {
+	var waitingHolds int64
+	database.DB.Model(&models.Hold{}).Where("book_id = ? AND status = ?", book.ID, models.HoldWaiting).Count(&waitingHolds)
+
+	if waitingHolds > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot delete book with waiting holds"})
+		return
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := recordDeletedBook(tx, &book); err != nil {
+			return err
+		}
+		if err := tx.Where("book_id = ?", book.ID).Delete(&models.Contribution{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&book).Association("Categories").Clear(); err != nil {
+			return err
+		}
+		if err := tx.Model(&book).Association("Tags").Clear(); err != nil {
+			return err
+		}
+		if err := removeBookFromLists(tx, book.ID); err != nil {
+			return err
+		}
+		return tx.Delete(&book).Error
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete book"})
 		return
 	}
+	suggest.RemoveBook(book)
+	if book.Cover != nil {
+		removeCover(c, *book.Cover)
+	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Book deleted successfully"})
 }
\ No newline at end of file
@@ -1,36 +1,120 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"library-go/circulation"
 	"library-go/database"
 	"library-go/models"
+	"library-go/query"
 )
 
 type BorrowHandler struct{}
 
+// borrowFields lists the borrow fields usable in filters, sort and field selection
+var borrowFields = query.Resource{
+	"id":          {Type: query.Int, Filter: true, Sort: true},
+	"book_id":     {Type: query.Int, Filter: true, Sort: true},
+	"reader_id":   {Type: query.Int, Filter: true, Sort: true},
+	"borrowed_at": {Type: query.Time, Filter: true, Sort: true},
+	"returned_at": {Type: query.Time, Filter: true, Sort: true},
+	"is_returned": {Type: query.Bool, Filter: true, Sort: true},
+	"renewed_at":  {Type: query.Time, Filter: true, Sort: true},
+	"renewals":    {Type: query.Int, Filter: true, Sort: true},
+	"created_at":  {Type: query.Time, Filter: true, Sort: true},
+	"updated_at":  {Type: query.Time, Filter: true, Sort: true},
+	"book":        {},
+	"reader":      {},
+}
+
 func NewBorrowHandler() *BorrowHandler {
 	return &BorrowHandler{}
 }
 
-// GetBorrows retrieves all borrows
+// GetBorrows retrieves all borrows, paginated by page number or by cursor when a cursor parameter is present
 func (h *BorrowHandler) GetBorrows(c *gin.Context) {
 	var borrows []models.Borrow
-	
+
+	// Get filter, sort and field selection parameters
+	params, ok := parseListParams(c, borrowFields, "cursor")
+	if !ok {
+		return
+	}
+
+	if cursor, useCursor := c.GetQuery("cursor"); useCursor {
+		h.getBorrowsByCursor(c, params, cursor)
+		return
+	}
+
 	// Get pagination parameters
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
-	offset := (page - 1) * limit
+	page, ok := parsePage(c, query.DefaultLimit)
+	if !ok {
+		return
+	}
 
-	if err := database.DB.Offset(offset).Limit(limit).Preload("Book").Preload("Reader").Find(&borrows).Error; err != nil {
+	var total int64
+	if err := database.DB.Model(&models.Borrow{}).Scopes(params.FilterScope()).Count(&total).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch borrows"})
 		return
 	}
 
-	c.JSON(http.StatusOK, borrows)
+	if err := database.DB.Scopes(params.Scope("id")).Offset(page.Offset()).Limit(page.Limit).Preload("Book").Preload("Reader").Find(&borrows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch borrows"})
+		return
+	}
+
+	respondPage(c, params, page, total, borrows)
+}
+
+// getBorrowsByCursor serves keyset pagination over borrow IDs, which stays fast on large tables
+func (h *BorrowHandler) getBorrowsByCursor(c *gin.Context, params *query.Params, cursor string) {
+	var borrows []models.Borrow
+
+	desc, ok := params.KeysetOrder()
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cursor pagination only supports sorting by id"})
+		return
+	}
+
+	afterID, err := query.DecodeCursor(cursor)
+	if err != nil {
+		respondQueryError(c, err)
+		return
+	}
+
+	limit, err := query.ParseLimit(c.Request.URL.Query(), query.DefaultLimit)
+	if err != nil {
+		respondQueryError(c, err)
+		return
+	}
+
+	db := database.DB.Scopes(params.Scope("id"))
+	if afterID > 0 {
+		if desc {
+			db = db.Where("id < ?", afterID)
+		} else {
+			db = db.Where("id > ?", afterID)
+		}
+	}
+
+	// Fetch one extra row to find out whether there is a next page
+	if err := db.Limit(limit + 1).Preload("Book").Preload("Reader").Find(&borrows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch borrows"})
+		return
+	}
+
+	nextCursor := ""
+	if len(borrows) > limit {
+		borrows = borrows[:limit]
+		nextCursor = query.EncodeCursor(borrows[limit-1].ID)
+	}
+
+	respondCursor(c, params, limit, nextCursor, borrows)
 }
 
 // GetBorrow retrieves a specific borrow by ID
@@ -76,30 +160,28 @@ func (h *BorrowHandler) CreateBorrow(c *gin.Context) {
 		return
 	}
 
-	// Check if book is available (has copies)
-	var activeBorrows int64
-	database.DB.Model(&models.Borrow{}).Where("book_id = ? AND is_returned = ?", input.BookID, false).Count(&activeBorrows)
-
-	if int(activeBorrows) >= book.Copies {
+	// Check if book is available, keeping copies held for readers ahead in the queue
+	var borrow *models.Borrow
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		borrow, err = circulation.Checkout(tx, &book, input.ReaderID)
+		return err
+	})
+	if errors.Is(err, circulation.ErrNoCopies) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "No available copies of this book"})
 		return
 	}
-
-	// Create borrow record
-	borrow := models.Borrow{
-		BookID:   input.BookID,
-		ReaderID: input.ReaderID,
-		IsReturned: false,
-		BorrowedAt: time.Now(),
+	if errors.Is(err, circulation.ErrReserved) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Available copies are reserved for readers with holds"})
+		return
 	}
-
-	if err := database.DB.Create(&borrow).Error; err != nil {
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create borrow record"})
 		return
 	}
 
 	// Preload relationships for response
-	database.DB.Preload("Book").Preload("Reader").First(&borrow, borrow.ID)
+	database.DB.Preload("Book").Preload("Reader").First(borrow, borrow.ID)
 
 	c.JSON(http.StatusCreated, borrow)
 }
@@ -0,0 +1,458 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"library-go/database"
+	"library-go/models"
+	"library-go/query"
+)
+
+type CategoryHandler struct{}
+
+// categoryNode is a category with its subtree, as returned by the browse endpoints
+type categoryNode struct {
+	models.Category
+	BookCount  int             `json:"book_count"`  // Books assigned to the category itself
+	TotalCount int             `json:"total_count"` // Distinct books in the category and its descendants
+	Children   []*categoryNode `json:"children"`
+
+	books map[uint]bool
+}
+
+// categoryTree holds every category, small enough to be walked in memory
+type categoryTree struct {
+	nodes map[uint]*categoryNode
+	roots []*categoryNode
+}
+
+func NewCategoryHandler() *CategoryHandler {
+	return &CategoryHandler{}
+}
+
+// loadCategoryTree loads all categories, with book counts when withCounts is set
+func loadCategoryTree(db *gorm.DB, withCounts bool) (*categoryTree, error) {
+	var categories []models.Category
+	if err := db.Order("name, id").Find(&categories).Error; err != nil {
+		return nil, err
+	}
+
+	tree := &categoryTree{nodes: make(map[uint]*categoryNode, len(categories)), roots: []*categoryNode{}}
+	for _, category := range categories {
+		tree.nodes[category.ID] = &categoryNode{Category: category, Children: []*categoryNode{}}
+	}
+	for _, category := range categories {
+		node := tree.nodes[category.ID]
+		if parent, ok := tree.nodes[derefUint(category.ParentID)]; ok {
+			parent.Children = append(parent.Children, node)
+		} else {
+			tree.roots = append(tree.roots, node)
+		}
+	}
+
+	if !withCounts {
+		return tree, nil
+	}
+
+	var links []struct {
+		BookID     uint
+		CategoryID uint
+	}
+	if err := db.Table("book_categories").Select("book_id, category_id").Scan(&links).Error; err != nil {
+		return nil, err
+	}
+
+	// A book counts once towards every ancestor, however many of its descendants it is in
+	for _, link := range links {
+		node, ok := tree.nodes[link.CategoryID]
+		if !ok {
+			continue
+		}
+		node.BookCount++
+		for _, ancestor := range tree.path(node.ID) {
+			if ancestor.books == nil {
+				ancestor.books = make(map[uint]bool)
+			}
+			ancestor.books[link.BookID] = true
+		}
+	}
+	for _, node := range tree.nodes {
+		node.TotalCount = len(node.books)
+	}
+	return tree, nil
+}
+
+// path returns the nodes from the root down to the category itself
+func (t *categoryTree) path(id uint) []*categoryNode {
+	var path []*categoryNode
+	seen := make(map[uint]bool)
+	for node, ok := t.nodes[id]; ok && !seen[node.ID]; node, ok = t.nodes[derefUint(node.ParentID)] {
+		seen[node.ID] = true
+		path = append([]*categoryNode{node}, path...)
+		if node.ParentID == nil {
+			break
+		}
+	}
+	return path
+}
+
+// descendants returns the IDs of a category and everything below it
+func (t *categoryTree) descendants(id uint) []uint {
+	node, ok := t.nodes[id]
+	if !ok {
+		return nil
+	}
+	ids := []uint{node.ID}
+	for _, child := range node.Children {
+		ids = append(ids, t.descendants(child.ID)...)
+	}
+	return ids
+}
+
+// find looks a category up by ID or slug
+func (t *categoryTree) find(ref string) (*categoryNode, bool) {
+	if id, err := strconv.ParseUint(ref, 10, 32); err == nil {
+		node, ok := t.nodes[uint(id)]
+		return node, ok
+	}
+	slug := models.Slugify(ref)
+	for _, node := range t.nodes {
+		if node.Slug == slug {
+			return node, true
+		}
+	}
+	return nil, false
+}
+
+// categoryBookIDs returns a subquery of the books in the category named by ref
+// (an ID or slug) or any of its descendants
+func categoryBookIDs(ref string) (*gorm.DB, error) {
+	tree, err := loadCategoryTree(database.DB, false)
+	if err != nil {
+		return nil, err
+	}
+	node, ok := tree.find(ref)
+	if !ok {
+		return nil, errCategoryNotFound
+	}
+	return database.DB.Table("book_categories").Select("book_id").Where("category_id IN ?", tree.descendants(node.ID)), nil
+}
+
+var errCategoryNotFound = errors.New("category not found")
+
+// respondCategoryError writes the response for a failed category lookup
+func respondCategoryError(c *gin.Context, err error) {
+	if errors.Is(err, errCategoryNotFound) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown category"})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch categories"})
+}
+
+// GetCategories returns the category tree with book counts, optionally limited to one kind
+func (h *CategoryHandler) GetCategories(c *gin.Context) {
+	tree, err := loadCategoryTree(database.DB, true)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch categories"})
+		return
+	}
+
+	roots := tree.roots
+	if kind := c.Query("kind"); kind != "" {
+		roots = []*categoryNode{}
+		for _, root := range tree.roots {
+			if root.Kind == kind {
+				roots = append(roots, root)
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": roots})
+}
+
+// GetCategory returns a category with its subtree, book counts and the path from the root
+func (h *CategoryHandler) GetCategory(c *gin.Context) {
+	tree, err := loadCategoryTree(database.DB, true)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch categories"})
+		return
+	}
+
+	node, ok := tree.find(c.Param("id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+		return
+	}
+
+	path := []models.Category{}
+	for _, ancestor := range tree.path(node.ID) {
+		if ancestor.ID != node.ID {
+			path = append(path, ancestor.Category)
+		}
+	}
+
+	c.JSON(http.StatusOK, struct {
+		*categoryNode
+		Path []models.Category `json:"path"`
+	}{node, path})
+}
+
+// GetCategoryBooks retrieves the books in a category, including its descendants unless descendants=false
+func (h *CategoryHandler) GetCategoryBooks(c *gin.Context) {
+	tree, err := loadCategoryTree(database.DB, false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch categories"})
+		return
+	}
+	node, ok := tree.find(c.Param("id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+		return
+	}
+
+	page, ok := parsePage(c, query.DefaultLimit)
+	if !ok {
+		return
+	}
+	params, ok := parseListParams(c, bookFields, "descendants")
+	if !ok {
+		return
+	}
+
+	ids := []uint{node.ID}
+	if c.Query("descendants") != "false" {
+		ids = tree.descendants(node.ID)
+	}
+	inCategory := database.DB.Table("book_categories").Select("book_id").Where("category_id IN ?", ids)
+
+	var total int64
+	if err := database.DB.Model(&models.Book{}).Where("id IN (?)", inCategory).Scopes(params.FilterScope()).Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch books"})
+		return
+	}
+
+	var books []models.Book
+	if err := database.DB.Scopes(withContributors, withCategories, params.Scope("id")).Where("id IN (?)", inCategory).
+		Offset(page.Offset()).Limit(page.Limit).Find(&books).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch books"})
+		return
+	}
+
+	respondPage(c, params, page, total, books)
+}
+
+// CreateCategory creates a new category
+func (h *CategoryHandler) CreateCategory(c *gin.Context) {
+	var input models.Category
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !h.checkCategory(c, &input) {
+		return
+	}
+
+	if err := database.DB.Create(&input).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create category"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, input)
+}
+
+// UpdateCategory updates a category, which may be moved under another parent
+func (h *CategoryHandler) UpdateCategory(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category ID"})
+		return
+	}
+
+	var category models.Category
+	if err := database.DB.First(&category, uint(id)).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+		return
+	}
+
+	var input struct {
+		Name        *string `json:"name"`
+		Slug        *string `json:"slug"`
+		Kind        *string `json:"kind"`
+		ParentID    *uint   `json:"parent_id"`
+		Root        bool    `json:"root"` // Moves the category to the top level
+		Description *string `json:"description"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if input.Name != nil {
+		category.Name = *input.Name
+	}
+	if input.Slug != nil {
+		category.Slug = *input.Slug
+	}
+	if input.Kind != nil && *input.Kind != category.Kind {
+		// Subcategories share the kind of their parent, so the subtree would be split
+		var children int64
+		database.DB.Model(&models.Category{}).Where("parent_id = ?", category.ID).Count(&children)
+
+		if children > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot change the kind of a category that has subcategories"})
+			return
+		}
+		category.Kind = *input.Kind
+	}
+	if input.ParentID != nil {
+		category.ParentID = input.ParentID
+	}
+	if input.Root {
+		category.ParentID = nil
+	}
+	if input.Description != nil {
+		category.Description = input.Description
+	}
+
+	if !h.checkCategory(c, &category) {
+		return
+	}
+
+	if err := database.DB.Omit("Parent", "Books").Save(&category).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update category"})
+		return
+	}
+
+	c.JSON(http.StatusOK, category)
+}
+
+// DeleteCategory deletes a category without subcategories, unassigning its books
+func (h *CategoryHandler) DeleteCategory(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category ID"})
+		return
+	}
+
+	var category models.Category
+	if err := database.DB.First(&category, uint(id)).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+		return
+	}
+
+	var children int64
+	database.DB.Model(&models.Category{}).Where("parent_id = ?", category.ID).Count(&children)
+
+	if children > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot delete category that has subcategories"})
+		return
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&category).Association("Books").Clear(); err != nil {
+			return err
+		}
+		return tx.Delete(&category).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete category"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Category deleted successfully"})
+}
+
+// checkCategory validates a category and its place in the tree, writing a 400 response on failure
+func (h *CategoryHandler) checkCategory(c *gin.Context, category *models.Category) bool {
+	if err := category.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+
+	var existing int64
+	database.DB.Model(&models.Category{}).Where("slug = ? AND id <> ?", category.Slug, category.ID).Count(&existing)
+	if existing > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Category with this slug already exists"})
+		return false
+	}
+
+	if category.ParentID == nil {
+		return true
+	}
+
+	tree, err := loadCategoryTree(database.DB, false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch categories"})
+		return false
+	}
+	parent, ok := tree.nodes[*category.ParentID]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Parent category not found"})
+		return false
+	}
+	if parent.Kind != category.Kind {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A category must have the same kind as its parent"})
+		return false
+	}
+	// Moving a category under one of its descendants would detach the subtree
+	for _, ancestor := range tree.path(parent.ID) {
+		if ancestor.ID == category.ID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "A category cannot be moved under its own subcategory"})
+			return false
+		}
+	}
+	return true
+}
+
+// withCategories preloads the categories of books by name
+func withCategories(db *gorm.DB) *gorm.DB {
+	return db.Preload("Categories", func(db *gorm.DB) *gorm.DB {
+		return db.Order("name")
+	})
+}
+
+// setBookCategories replaces the categories a book is assigned to
+func setBookCategories(tx *gorm.DB, book *models.Book, ids []uint) error {
+	var categories []models.Category
+	if len(ids) > 0 {
+		if err := tx.Where("id IN ?", ids).Order("name").Find(&categories).Error; err != nil {
+			return err
+		}
+	}
+	if err := tx.Model(book).Omit("Categories.*").Association("Categories").Replace(categories); err != nil {
+		return err
+	}
+	book.Categories = categories
+	return nil
+}
+
+// validateCategoryIDs checks that every category exists
+func validateCategoryIDs(ids []uint) error {
+	unique := uniqueIDs(ids)
+	if len(unique) == 0 {
+		return nil
+	}
+	var count int64
+	if err := database.DB.Model(&models.Category{}).Where("id IN ?", unique).Count(&count).Error; err != nil {
+		return err
+	}
+	if int(count) != len(unique) {
+		return errCategoryNotFound
+	}
+	return nil
+}
+
+func derefUint(value *uint) uint {
+	if value == nil {
+		return 0
+	}
+	return *value
+}
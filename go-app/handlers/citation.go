@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"library-go/citation"
+	"library-go/database"
+	"library-go/models"
+)
+
+type CitationHandler struct{}
+
+func NewCitationHandler() *CitationHandler {
+	return &CitationHandler{}
+}
+
+// CiteBook formats a citation of a book in the format of the format parameter
+func (h *CitationHandler) CiteBook(c *gin.Context) {
+	format, ok := citationFormat(c)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid book ID"})
+		return
+	}
+
+	var book models.Book
+	if err := database.DB.Scopes(withContributors, withCategories, withTags).First(&book, uint(id)).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Book not found"})
+		return
+	}
+
+	h.respond(c, format, "book-"+strconv.FormatUint(uint64(book.ID), 10), []models.Book{book})
+}
+
+// CiteReadingList formats citations of every book of a reading list
+func (h *CitationHandler) CiteReadingList(c *gin.Context) {
+	format, ok := citationFormat(c)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid reading list ID"})
+		return
+	}
+	var list models.ReadingList
+	if err := database.DB.First(&list, uint(id)).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Reading list not found"})
+		return
+	}
+
+	var entries []models.ReadingListEntry
+	if err := database.DB.Where("reading_list_id = ?", list.ID).Order("position, id").
+		Preload("Book", withContributors, withCategories, withTags).Find(&entries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reading list"})
+		return
+	}
+	books := make([]models.Book, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Book != nil {
+			books = append(books, *entry.Book)
+		}
+	}
+
+	h.respond(c, format, "reading-list-"+strconv.FormatUint(uint64(list.ID), 10), books)
+}
+
+// respond writes the citations, as a download named after name for the reference manager formats
+func (h *CitationHandler) respond(c *gin.Context, format, name string, books []models.Book) {
+	base := requestBaseURL(c)
+	items := make([]citation.Item, len(books))
+	for i := range books {
+		items[i] = citation.FromBook(&books[i], base+"/api/books/"+strconv.FormatUint(uint64(books[i].ID), 10))
+	}
+
+	var buf bytes.Buffer
+	if err := citation.Write(&buf, format, items); err != nil {
+		log.Println("Citation failed:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to format citations"})
+		return
+	}
+	if extension := citation.Extension(format); extension != "" {
+		c.Header("Content-Disposition", `attachment; filename="`+name+extension+`"`)
+	}
+	c.Data(http.StatusOK, citation.ContentType(format), buf.Bytes())
+}
+
+// citationFormat reads the format parameter, BibTeX by default
+func citationFormat(c *gin.Context) (string, bool) {
+	format := strings.ToLower(c.DefaultQuery("format", citation.FormatBibTeX))
+	for _, f := range citation.Formats {
+		if f == format {
+			return format, true
+		}
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": "format must be one of: " + strings.Join(citation.Formats, ", ")})
+	return "", false
+}
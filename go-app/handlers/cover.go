@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"library-go/config"
+	"library-go/covers"
+	"library-go/database"
+	"library-go/models"
+	"library-go/storage"
+)
+
+type CoverHandler struct{}
+
+// multipartOverhead is the allowance for form boundaries and headers on top of the cover size limit
+const multipartOverhead = 64 << 10
+
+func NewCoverHandler() *CoverHandler {
+	return &CoverHandler{}
+}
+
+// UploadCover replaces the cover of a book with the image in the "cover" form field
+func (h *CoverHandler) UploadCover(c *gin.Context) {
+	book, ok := findBook(c)
+	if !ok {
+		return
+	}
+
+	tooLarge := fmt.Sprintf("Cover must be at most %d bytes", config.CoverMaxBytes)
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, config.CoverMaxBytes+multipartOverhead)
+	file, header, err := c.Request.FormFile("cover")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": tooLarge})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A cover image is required in the cover form field"})
+		return
+	}
+	defer file.Close()
+	if header.Size > config.CoverMaxBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": tooLarge})
+		return
+	}
+
+	data, err := io.ReadAll(io.LimitReader(file, config.CoverMaxBytes+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read cover image"})
+		return
+	}
+	if int64(len(data)) > config.CoverMaxBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": tooLarge})
+		return
+	}
+
+	key, err := covers.Save(c.Request.Context(), book.ID, data)
+	if errors.Is(err, covers.ErrUnsupportedType) {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, covers.ErrInvalidImage) || errors.Is(err, covers.ErrTooManyPixels) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Println("Failed to store cover:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store cover"})
+		return
+	}
+
+	previous := book.Cover
+	book.Cover = &key
+	if err := database.DB.Model(book).Update("cover", key).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update book"})
+		return
+	}
+	if previous != nil && *previous != key {
+		removeCover(c, *previous)
+	}
+
+	c.JSON(http.StatusOK, newBookResponse(*book))
+}
+
+// DeleteCover removes the cover of a book
+func (h *CoverHandler) DeleteCover(c *gin.Context) {
+	book, ok := findBook(c)
+	if !ok {
+		return
+	}
+
+	if book.Cover == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Book has no cover"})
+		return
+	}
+
+	previous := *book.Cover
+	book.Cover = nil
+	if err := database.DB.Model(book).Update("cover", nil).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update book"})
+		return
+	}
+	removeCover(c, previous)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Cover deleted successfully"})
+}
+
+// ServeCover streams a stored cover or thumbnail. Keys change with the
+// image, so responses can be cached indefinitely.
+func (h *CoverHandler) ServeCover(c *gin.Context) {
+	key := strings.TrimPrefix(c.Param("key"), "/")
+
+	body, contentType, err := covers.Open(c.Request.Context(), key)
+	if errors.Is(err, storage.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Cover not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch cover"})
+		return
+	}
+	defer body.Close()
+
+	c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	c.DataFromReader(http.StatusOK, -1, contentType, body, nil)
+}
+
+// bookResponse is a book as the API returns it, with the public URLs of the
+// cover whose storage key the model keeps
+type bookResponse struct {
+	models.Book
+	CoverURL   *string           `json:"cover_url,omitempty"`
+	Thumbnails map[string]string `json:"cover_thumbnails,omitempty"` // Cover thumbnail URLs by size
+}
+
+func newBookResponse(book models.Book) bookResponse {
+	response := bookResponse{Book: book}
+	if book.Cover != nil {
+		url := covers.URL(*book.Cover)
+		response.CoverURL = &url
+		response.Thumbnails = covers.ThumbnailURLs(*book.Cover)
+	}
+	return response
+}
+
+func newBookResponses(books []models.Book) []bookResponse {
+	responses := make([]bookResponse, len(books))
+	for i, book := range books {
+		responses[i] = newBookResponse(book)
+	}
+	return responses
+}
+
+// removeCover deletes a replaced cover; failures only leave unreferenced files, so they are logged
+func removeCover(c *gin.Context, key string) {
+	if err := covers.Remove(c.Request.Context(), key); err != nil {
+		log.Println("Failed to remove cover", key+":", err)
+	}
+}
@@ -0,0 +1,338 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"library-go/database"
+	"library-go/models"
+	"library-go/query"
+	"library-go/spreadsheet"
+)
+
+type ExportHandler struct{}
+
+// exportFlushRows is how often rows are pushed to the client during an export
+const exportFlushRows = 200
+
+// Export columns in their default order; the fields parameter picks and orders them
+var (
+	bookExportColumns = []string{"id", "title", "author", "year", "isbn", "copies", "description", "publisher",
+		"language", "edition", "page_count", "format", "work_id", "created_at", "updated_at"}
+	readerExportColumns = []string{"id", "first_name", "last_name", "email", "phone", "address", "created_at", "updated_at"}
+	borrowExportColumns = []string{"id", "book_id", "book_title", "book_isbn", "reader_id", "reader_name", "reader_email",
+		"borrowed_at", "returned_at", "is_returned", "renewed_at", "renewals", "created_at", "updated_at"}
+)
+
+// borrowExportFields extends borrowFields with the book and reader columns of the loan history
+var borrowExportFields = func() query.Resource {
+	fields := query.Resource{"book_title": {}, "book_isbn": {}, "reader_name": {}, "reader_email": {}}
+	for name, field := range borrowFields {
+		fields[name] = field
+	}
+	return fields
+}()
+
+// borrowExportRow is a borrow joined with the book and reader it is a loan of
+type borrowExportRow struct {
+	ID              uint
+	BookID          uint
+	BookTitle       *string
+	BookISBN        *string
+	ReaderID        uint
+	ReaderFirstName *string
+	ReaderLastName  *string
+	ReaderEmail     *string
+	BorrowedAt      time.Time
+	ReturnedAt      *time.Time
+	IsReturned      bool
+	RenewedAt       *time.Time
+	Renewals        int
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
+
+// rowWriter encodes the rows of an export in one file format
+type rowWriter interface {
+	Write(cells []interface{}) error
+	Flush() error
+	Close() error
+}
+
+func NewExportHandler() *ExportHandler {
+	return &ExportHandler{}
+}
+
+// ExportBooks streams the books matching the list filters as CSV, JSON Lines
+// or XLSX, by the extension of the request path
+func (h *ExportHandler) ExportBooks(c *gin.Context) {
+	params, ok := parseListParams(c, bookFields, "category", "tag")
+	if !ok {
+		return
+	}
+	related, ok := relatedBookScopes(c)
+	if !ok {
+		return
+	}
+
+	db := database.DB.Model(&models.Book{}).Scopes(params.Scope("id")).Scopes(related...)
+	h.export(c, "books", bookExportColumns, params, db, func(rows *sql.Rows) ([]interface{}, error) {
+		var b models.Book
+		if err := database.DB.ScanRows(rows, &b); err != nil {
+			return nil, err
+		}
+		return []interface{}{b.ID, b.Title, b.Author, b.Year, b.ISBN, b.Copies, b.Description, b.Publisher,
+			b.Language, b.Edition, b.PageCount, b.Format, b.WorkID, b.CreatedAt, b.UpdatedAt}, nil
+	})
+}
+
+// ExportReaders streams the readers matching the list filters
+func (h *ExportHandler) ExportReaders(c *gin.Context) {
+	params, ok := parseListParams(c, readerFields)
+	if !ok {
+		return
+	}
+
+	db := database.DB.Model(&models.Reader{}).Scopes(params.Scope("id"))
+	h.export(c, "readers", readerExportColumns, params, db, func(rows *sql.Rows) ([]interface{}, error) {
+		var r models.Reader
+		if err := database.DB.ScanRows(rows, &r); err != nil {
+			return nil, err
+		}
+		return []interface{}{r.ID, r.FirstName, r.LastName, r.Email, r.Phone, r.Address, r.CreatedAt, r.UpdatedAt}, nil
+	})
+}
+
+// ExportBorrows streams the loan history matching the list filters, with the
+// title of each book and the name of each reader
+func (h *ExportHandler) ExportBorrows(c *gin.Context) {
+	params, ok := parseListParams(c, borrowExportFields)
+	if !ok {
+		return
+	}
+
+	db := database.DB.Model(&models.Borrow{}).Scopes(params.Scope("id")).
+		Select("borrows.id, borrows.book_id, books.title AS book_title, books.isbn AS book_isbn, borrows.reader_id, " +
+			"readers.first_name AS reader_first_name, readers.last_name AS reader_last_name, readers.email AS reader_email, " +
+			"borrows.borrowed_at, borrows.returned_at, borrows.is_returned, borrows.renewed_at, borrows.renewals, borrows.created_at, borrows.updated_at").
+		Joins("LEFT JOIN books ON books.id = borrows.book_id").
+		Joins("LEFT JOIN readers ON readers.id = borrows.reader_id")
+	h.export(c, "borrows", borrowExportColumns, params, db, func(rows *sql.Rows) ([]interface{}, error) {
+		var b borrowExportRow
+		if err := database.DB.ScanRows(rows, &b); err != nil {
+			return nil, err
+		}
+		var readerName interface{}
+		if b.ReaderFirstName != nil && b.ReaderLastName != nil {
+			readerName = *b.ReaderFirstName + " " + *b.ReaderLastName
+		}
+		return []interface{}{b.ID, b.BookID, b.BookTitle, b.BookISBN, b.ReaderID, readerName, b.ReaderEmail,
+			b.BorrowedAt, b.ReturnedAt, b.IsReturned, b.RenewedAt, b.Renewals, b.CreatedAt, b.UpdatedAt}, nil
+	})
+}
+
+// export writes the rows of db one at a time as they are read from the
+// database, keeping the columns chosen by the fields parameter
+func (h *ExportHandler) export(c *gin.Context, name string, columns []string, params *query.Params, db *gorm.DB, scan func(*sql.Rows) ([]interface{}, error)) {
+	// Pick the columns before anything is sent, so a bad selection is still a 400
+	picked := make([]int, 0, len(columns))
+	header := columns
+	if fields := params.Fields(); len(fields) > 0 {
+		index := make(map[string]int, len(columns))
+		for i, column := range columns {
+			index[column] = i
+		}
+		header = fields
+		for _, field := range fields {
+			i, ok := index[field]
+			if !ok {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("cannot export field '%s'", field)})
+				return
+			}
+			picked = append(picked, i)
+		}
+	} else {
+		for i := range columns {
+			picked = append(picked, i)
+		}
+	}
+
+	extension := path.Ext(c.Request.URL.Path)
+	contentType, ok := map[string]string{
+		".csv":   "text/csv; charset=utf-8",
+		".jsonl": "application/x-ndjson",
+		".xlsx":  "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	}[extension]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Export format must be csv, jsonl or xlsx"})
+		return
+	}
+
+	rows, err := db.Rows()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export " + name})
+		return
+	}
+	defer rows.Close()
+
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", `attachment; filename="`+name+extension+`"`)
+	c.Status(http.StatusOK)
+
+	var writer rowWriter
+	switch extension {
+	case ".csv":
+		writer = &csvRowWriter{csv.NewWriter(c.Writer)}
+	case ".jsonl":
+		writer = &jsonlRowWriter{w: c.Writer, header: header}
+	case ".xlsx":
+		xlsx, err := spreadsheet.NewXLSXWriter(c.Writer, name)
+		if err != nil {
+			log.Println("Export of", name, "failed:", err)
+			return
+		}
+		writer = xlsx
+	}
+
+	// The status is already sent from here on, so failures can only cut the download short
+	if extension != ".jsonl" {
+		if err := writer.Write(stringCells(header)); err != nil {
+			log.Println("Export of", name, "failed:", err)
+			return
+		}
+	}
+	for n := 1; rows.Next(); n++ {
+		values, err := scan(rows)
+		if err != nil {
+			log.Println("Export of", name, "failed:", err)
+			return
+		}
+		cells := make([]interface{}, len(picked))
+		for i, column := range picked {
+			cells[i] = exportValue(values[column])
+		}
+		if err := writer.Write(cells); err != nil {
+			log.Println("Export of", name, "failed:", err)
+			return
+		}
+		if n%exportFlushRows == 0 {
+			if err := writer.Flush(); err != nil {
+				log.Println("Export of", name, "failed:", err)
+				return
+			}
+			c.Writer.Flush()
+		}
+	}
+	if err := rows.Err(); err != nil {
+		log.Println("Export of", name, "failed:", err)
+		return
+	}
+	if err := writer.Close(); err != nil {
+		log.Println("Export of", name, "failed:", err)
+	}
+}
+
+// exportValue dereferences optional values, so a missing one is an empty cell
+func exportValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case *string:
+		if v != nil {
+			return *v
+		}
+	case *int:
+		if v != nil {
+			return *v
+		}
+	case *uint:
+		if v != nil {
+			return *v
+		}
+	case *time.Time:
+		if v != nil {
+			return *v
+		}
+	default:
+		return value
+	}
+	return nil
+}
+
+func stringCells(values []string) []interface{} {
+	cells := make([]interface{}, len(values))
+	for i, value := range values {
+		cells[i] = value
+	}
+	return cells
+}
+
+// csvRowWriter writes rows as CSV, with times in RFC 3339
+type csvRowWriter struct {
+	w *csv.Writer
+}
+
+func (w *csvRowWriter) Write(cells []interface{}) error {
+	record := make([]string, len(cells))
+	for i, cell := range cells {
+		switch value := cell.(type) {
+		case nil:
+		case time.Time:
+			record[i] = value.Format(time.RFC3339)
+		case bool:
+			record[i] = strconv.FormatBool(value)
+		default:
+			record[i] = fmt.Sprint(value)
+		}
+	}
+	return w.w.Write(record)
+}
+
+func (w *csvRowWriter) Flush() error {
+	w.w.Flush()
+	return w.w.Error()
+}
+
+func (w *csvRowWriter) Close() error {
+	return w.Flush()
+}
+
+// jsonlRowWriter writes each row as a JSON object on its own line
+type jsonlRowWriter struct {
+	w      io.Writer
+	header []string
+}
+
+func (w *jsonlRowWriter) Write(cells []interface{}) error {
+	// Keys are written in column order, which a map would lose
+	line := []byte{'{'}
+	for i, cell := range cells {
+		key, err := json.Marshal(w.header[i])
+		if err != nil {
+			return err
+		}
+		value, err := json.Marshal(cell)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			line = append(line, ',')
+		}
+		line = append(append(append(line, key...), ':'), value...)
+	}
+	_, err := w.w.Write(append(line, '}', '\n'))
+	return err
+}
+
+func (w *jsonlRowWriter) Flush() error { return nil }
+
+func (w *jsonlRowWriter) Close() error { return nil }
@@ -0,0 +1,249 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"library-go/circulation"
+	"library-go/database"
+	"library-go/models"
+	"library-go/query"
+)
+
+type HoldHandler struct{}
+
+// holdFields lists the hold fields usable in filters, sort and field selection
+var holdFields = query.Resource{
+	"id":             {Type: query.Int, Filter: true, Sort: true},
+	"reader_id":      {Type: query.Int, Filter: true, Sort: true},
+	"book_id":        {Type: query.Int, Filter: true, Sort: true},
+	"work_id":        {Type: query.Int, Filter: true, Sort: true},
+	"status":         {Type: query.String, Filter: true, Sort: true},
+	"borrow_id":      {Type: query.Int, Filter: true},
+	"fulfilled_at":   {Type: query.Time, Filter: true, Sort: true},
+	"cancelled_at":   {Type: query.Time, Filter: true, Sort: true},
+	"created_at":     {Type: query.Time, Filter: true, Sort: true},
+	"updated_at":     {Type: query.Time, Filter: true, Sort: true},
+	"reader":         {},
+	"book":           {},
+	"work":           {},
+	"queue_position": {},
+}
+
+// holdView is a hold with its place in the queue while it is waiting
+type holdView struct {
+	models.Hold
+	QueuePosition *int `json:"queue_position,omitempty"`
+}
+
+func NewHoldHandler() *HoldHandler {
+	return &HoldHandler{}
+}
+
+// GetHolds retrieves all holds, oldest first
+func (h *HoldHandler) GetHolds(c *gin.Context) {
+	var holds []models.Hold
+
+	page, ok := parsePage(c, query.DefaultLimit)
+	if !ok {
+		return
+	}
+	params, ok := parseListParams(c, holdFields)
+	if !ok {
+		return
+	}
+
+	var total int64
+	if err := database.DB.Model(&models.Hold{}).Scopes(params.FilterScope()).Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch holds"})
+		return
+	}
+
+	if err := database.DB.Preload("Reader").Preload("Book").Preload("Work").Scopes(params.Scope("id")).
+		Offset(page.Offset()).Limit(page.Limit).Find(&holds).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch holds"})
+		return
+	}
+
+	respondPage(c, params, page, total, holds)
+}
+
+// GetHold retrieves a hold with its place in the queue
+func (h *HoldHandler) GetHold(c *gin.Context) {
+	hold, ok := h.findHold(c)
+	if !ok {
+		return
+	}
+
+	h.respondHold(c, http.StatusOK, hold)
+}
+
+// CreateHold queues a reader for a book, or for any edition of a work. With
+// any_edition set, a hold placed on a book covers every edition of its work.
+func (h *HoldHandler) CreateHold(c *gin.Context) {
+	var input struct {
+		ReaderID   uint  `json:"reader_id" binding:"required"`
+		BookID     *uint `json:"book_id"`
+		WorkID     *uint `json:"work_id"`
+		AnyEdition bool  `json:"any_edition"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if (input.BookID == nil) == (input.WorkID == nil) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Either book_id or work_id is required"})
+		return
+	}
+
+	var reader models.Reader
+	if err := database.DB.First(&reader, input.ReaderID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Reader not found"})
+		return
+	}
+
+	hold := models.Hold{ReaderID: input.ReaderID, BookID: input.BookID, WorkID: input.WorkID}
+	if input.BookID != nil {
+		var book models.Book
+		if err := database.DB.First(&book, *input.BookID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Book not found"})
+			return
+		}
+		if input.AnyEdition {
+			if book.WorkID == nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Book is not an edition of any work"})
+				return
+			}
+			hold.BookID, hold.WorkID = nil, book.WorkID
+		}
+	} else if err := validateWorkID(input.WorkID); err != nil {
+		if errors.Is(err, errWorkNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Work not found"})
+			return
+		}
+		respondWorkError(c, err)
+		return
+	}
+	if err := hold.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// A reader holds each book or work once
+	var existing int64
+	database.DB.Model(&models.Hold{}).Where(&models.Hold{ReaderID: hold.ReaderID, BookID: hold.BookID, WorkID: hold.WorkID, Status: models.HoldWaiting}).Count(&existing)
+	if existing > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Reader already has a waiting hold on this title"})
+		return
+	}
+
+	if err := database.DB.Omit(clause.Associations).Create(&hold).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create hold"})
+		return
+	}
+
+	h.respondHold(c, http.StatusCreated, &hold)
+}
+
+// CancelHold cancels a waiting hold
+func (h *HoldHandler) CancelHold(c *gin.Context) {
+	hold, ok := h.findHold(c)
+	if !ok {
+		return
+	}
+
+	if hold.Status != models.HoldWaiting {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only waiting holds can be cancelled"})
+		return
+	}
+
+	now := time.Now()
+	hold.Status = models.HoldCancelled
+	hold.CancelledAt = &now
+	if err := database.DB.Omit(clause.Associations).Save(hold).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel hold"})
+		return
+	}
+
+	h.respondHold(c, http.StatusOK, hold)
+}
+
+// FulfillHold lends the held title to the reader, choosing any edition with a
+// copy to spare when the hold is on a work
+func (h *HoldHandler) FulfillHold(c *gin.Context) {
+	hold, ok := h.findHold(c)
+	if !ok {
+		return
+	}
+
+	if hold.Status != models.HoldWaiting {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only waiting holds can be fulfilled"})
+		return
+	}
+
+	var borrow *models.Borrow
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		borrow, err = circulation.Fulfill(tx, hold)
+		return err
+	})
+	if errors.Is(err, circulation.ErrNoCopies) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No available copies of this title"})
+		return
+	}
+	if errors.Is(err, circulation.ErrReserved) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Available copies are reserved for readers with earlier holds"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fulfill hold"})
+		return
+	}
+
+	database.DB.Preload("Book").Preload("Reader").First(borrow, borrow.ID)
+
+	c.JSON(http.StatusCreated, gin.H{"hold": hold, "borrow": borrow})
+}
+
+// findHold loads the hold named by the id parameter, writing an error response on failure
+func (h *HoldHandler) findHold(c *gin.Context) (*models.Hold, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid hold ID"})
+		return nil, false
+	}
+
+	var hold models.Hold
+	if err := database.DB.First(&hold, uint(id)).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Hold not found"})
+		return nil, false
+	}
+	return &hold, true
+}
+
+// respondHold writes a hold with its relationships and, while waiting, its queue position
+func (h *HoldHandler) respondHold(c *gin.Context, status int, hold *models.Hold) {
+	if err := database.DB.Preload("Reader").Preload("Book").Preload("Work").First(hold, hold.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch hold"})
+		return
+	}
+
+	view := holdView{Hold: *hold}
+	if hold.Status == models.HoldWaiting {
+		position, err := circulation.QueuePosition(database.DB, hold)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch hold"})
+			return
+		}
+		view.QueuePosition = &position
+	}
+
+	c.JSON(status, view)
+}
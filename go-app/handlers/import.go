@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"library-go/database"
+	"library-go/models"
+	"library-go/suggest"
+)
+
+// maxImportBytes caps the size of an uploaded import file
+const maxImportBytes = 50 << 20
+
+// Import record statuses
+const (
+	importCreated     = "created"
+	importWouldCreate = "would_create"
+	importUpdated     = "updated"
+	importWouldUpdate = "would_update"
+	importDuplicate   = "duplicate"
+	importInvalid     = "invalid"
+)
+
+// importRecord reports what happened to one record of an import
+type importRecord struct {
+	Index    int      `json:"index"` // Position of the record in the file, from 1; the row number for tables
+	Title    string   `json:"title,omitempty"`
+	ISBN     *string  `json:"isbn,omitempty"`
+	Name     string   `json:"name,omitempty"`
+	Email    *string  `json:"email,omitempty"`
+	Status   string   `json:"status"`
+	BookID   *uint    `json:"book_id,omitempty"`   // Created or updated book, or the existing one for duplicates
+	ReaderID *uint    `json:"reader_id,omitempty"` // Likewise for readers
+	Errors   []string `json:"errors,omitempty"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// importReport summarizes an import; in a dry run nothing is written
+type importReport struct {
+	DryRun  bool              `json:"dry_run"`
+	Mapping map[string]string `json:"mapping,omitempty"` // Field each column of a table was imported into
+	Total   int               `json:"total"`
+	Counts  map[string]int    `json:"counts"`
+	Error   string            `json:"error,omitempty"` // Set when the file could not be read to the end
+	Records []importRecord    `json:"records"`
+}
+
+func newImportReport(dryRun bool) *importReport {
+	return &importReport{DryRun: dryRun, Counts: map[string]int{}, Records: []importRecord{}}
+}
+
+// add records the outcome of one record
+func (r *importReport) add(record importRecord) {
+	r.Total++
+	r.Counts[record.Status]++
+	r.Records = append(r.Records, record)
+}
+
+// isbnBookIDs returns the books that already have one of the given ISBNs, by ISBN
+func isbnBookIDs(isbns []string) (map[string]uint, error) {
+	existing := make(map[string]uint, len(isbns))
+	if len(isbns) == 0 {
+		return existing, nil
+	}
+	var books []models.Book
+	if err := database.DB.Select("id", "isbn").Where("isbn IN ?", isbns).Find(&books).Error; err != nil {
+		return nil, err
+	}
+	for _, book := range books {
+		existing[*book.ISBN] = book.ID
+	}
+	return existing, nil
+}
+
+// insertBook creates an imported book with its contributors the way CreateBook does
+func insertBook(book *models.Book, contributors []contributorInput) error {
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		contributions, err := resolveContributors(tx, contributors)
+		if err != nil {
+			return err
+		}
+		if line := models.CreditLine(contributions); line != "" {
+			book.Author = line
+		}
+		if err := tx.Omit(clause.Associations).Create(book).Error; err != nil {
+			return err
+		}
+		return createContributions(tx, book, contributions)
+	})
+	if err != nil {
+		return err
+	}
+	suggest.AddBook(*book)
+	return nil
+}
+
+// importBody returns the uploaded file of an import: the "file" form field of
+// a multipart request, or the raw request body
+func importBody(c *gin.Context) (io.ReadCloser, bool) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportBytes)
+	if !strings.HasPrefix(c.ContentType(), "multipart/") {
+		return c.Request.Body, true
+	}
+
+	file, _, err := c.Request.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("Import file must be at most %d bytes", maxImportBytes)})
+			return nil, false
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "An import file is required in the file form field"})
+		return nil, false
+	}
+	return file, true
+}
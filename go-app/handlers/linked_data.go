@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"library-go/circulation"
+	"library-go/config"
+	"library-go/covers"
+	"library-go/database"
+	"library-go/models"
+	"library-go/oai"
+	"library-go/schemaorg"
+)
+
+// bookMediaTypes are the representations of a book in the API, the first
+// being the default; linkedDataMediaTypes are those served without a token
+var (
+	bookMediaTypes       = []string{gin.MIMEJSON, schemaorg.ContentType, gin.MIMEXML, gin.MIMEXML2}
+	linkedDataMediaTypes = []string{schemaorg.ContentType, gin.MIMEXML, gin.MIMEXML2}
+)
+
+type LinkedDataHandler struct{}
+
+func NewLinkedDataHandler() *LinkedDataHandler {
+	return &LinkedDataHandler{}
+}
+
+// GetBook serves the public description of a book to search engines and
+// linked data clients, as a schema.org Book or a Dublin Core record
+func (h *LinkedDataHandler) GetBook(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid book ID"})
+		return
+	}
+
+	var book models.Book
+	if err := database.DB.Scopes(withContributors, withCategories, withTags).First(&book, uint(id)).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Book not found"})
+		return
+	}
+
+	respondBook(c, &book, linkedDataMediaTypes)
+}
+
+// respondBook writes a book in the offered representation the Accept header
+// prefers: our JSON, a schema.org Book in JSON-LD or a Dublin Core record in XML
+func respondBook(c *gin.Context, book *models.Book, offers []string) {
+	c.Header("Vary", "Accept")
+
+	switch mediaType := negotiateMediaType(c.GetHeader("Accept"), offers); mediaType {
+	case schemaorg.ContentType:
+		available, err := circulation.AvailableCopies(database.DB, []models.Book{*book})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch book availability"})
+			return
+		}
+		doc, err := json.Marshal(bookSchemaOrg(requestBaseURL(c), *book, available[book.ID]))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encode book"})
+			return
+		}
+		c.Data(http.StatusOK, schemaorg.ContentType+"; charset=utf-8", doc)
+	case gin.MIMEXML, gin.MIMEXML2:
+		record, err := oai.MarshalDC(bookDublinCore(requestBaseURL(c), *book))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encode book"})
+			return
+		}
+		c.Data(http.StatusOK, mediaType+"; charset=utf-8", append([]byte(xml.Header), record...))
+	default:
+		c.JSON(http.StatusOK, newBookResponse(*book))
+	}
+}
+
+// negotiateMediaType picks the offered media type the Accept header gives
+// the highest quality, matching each offer by its most specific range. Ties
+// and headers accepting none of the offers fall back to the order of offers,
+// so clients that ask for something else still get the default.
+func negotiateMediaType(accept string, offers []string) string {
+	type mediaRange struct {
+		mediaType string
+		q         float64
+	}
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+		r := mediaRange{mediaType: strings.ToLower(strings.TrimSpace(params[0])), q: 1}
+		if r.mediaType == "" {
+			continue
+		}
+		for _, param := range params[1:] {
+			name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.EqualFold(name, "q") {
+				if q, err := strconv.ParseFloat(value, 64); err == nil {
+					r.q = q
+				}
+			}
+		}
+		ranges = append(ranges, r)
+	}
+
+	best, bestQ := offers[0], 0.0
+	for _, offer := range offers {
+		kind := offer[:strings.Index(offer, "/")+1]
+		q, specificity := 0.0, -1
+		for _, r := range ranges {
+			s := -1
+			switch r.mediaType {
+			case offer:
+				s = 2
+			case kind + "*":
+				s = 1
+			case "*/*":
+				s = 0
+			}
+			if s > specificity {
+				q, specificity = r.q, s
+			}
+		}
+		if q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
+
+// bookSchemaOrg describes a book as a schema.org Book, offered for loan with
+// the copies available now
+func bookSchemaOrg(base string, book models.Book, available int) schemaorg.Book {
+	url := bookURL(base, book.ID)
+	doc := schemaorg.Book{
+		Context:     schemaorg.Context,
+		Type:        "Book",
+		ID:          url,
+		URL:         url,
+		Name:        book.Title,
+		ISBN:        derefString(book.ISBN),
+		BookEdition: derefString(book.Edition),
+		InLanguage:  derefString(book.Language),
+		Description: derefString(book.Description),
+	}
+	switch book.Format {
+	case models.FormatAudiobook:
+		doc.BookFormat = schemaorg.AudiobookFormat
+	case models.FormatEbook:
+		doc.BookFormat = schemaorg.EBook
+	}
+	if book.PageCount != nil {
+		doc.NumberOfPages = *book.PageCount
+	}
+	if book.Year != nil {
+		doc.DatePublished = strconv.Itoa(*book.Year)
+	}
+	if book.Publisher != nil {
+		doc.Publisher = &schemaorg.Organization{Type: "Organization", Name: *book.Publisher}
+	}
+	if book.Cover != nil {
+		doc.Image = covers.URL(*book.Cover)
+		if strings.HasPrefix(doc.Image, "/") {
+			doc.Image = base + doc.Image
+		}
+	}
+
+	for _, contribution := range book.Contributors {
+		if contribution.Author == nil {
+			continue
+		}
+		person := schemaorg.Person{
+			Type: "Person",
+			ID:   base + "/api/authors/" + strconv.FormatUint(uint64(contribution.AuthorID), 10),
+			Name: contribution.Author.Name,
+		}
+		switch contribution.Role {
+		case models.RoleAuthor:
+			doc.Author = append(doc.Author, person)
+		case models.RoleEditor:
+			doc.Editor = append(doc.Editor, person)
+		case models.RoleTranslator:
+			doc.Translator = append(doc.Translator, person)
+		case models.RoleIllustrator:
+			doc.Illustrator = append(doc.Illustrator, person)
+		}
+	}
+	if len(book.Contributors) == 0 {
+		for _, name := range models.SplitAuthorNames(book.Author) {
+			doc.Author = append(doc.Author, schemaorg.Person{Type: "Person", Name: name})
+		}
+	}
+
+	for _, category := range book.Categories {
+		if category.Kind == models.KindGenre {
+			doc.Genre = append(doc.Genre, category.Name)
+		} else {
+			doc.About = append(doc.About, schemaorg.Thing{Type: "Thing", Name: category.Name})
+		}
+	}
+	for _, tag := range book.Tags {
+		doc.Keywords = append(doc.Keywords, tag.Name)
+	}
+
+	availability := schemaorg.OutOfStock
+	if available > 0 {
+		availability = schemaorg.InStock
+	}
+	doc.Offers = []schemaorg.Offer{{
+		Type:             "Offer",
+		Availability:     availability,
+		BusinessFunction: schemaorg.LeaseOut,
+		InventoryLevel:   &schemaorg.QuantitativeValue{Type: "QuantitativeValue", Value: available},
+		OfferedBy:        &schemaorg.Organization{Type: "Library", Name: config.OAIRepositoryName, URL: base},
+	}}
+	return doc
+}
+
+// bookURL is the public address of a book, which the identifiers in its
+// linked data and harvested records point at
+func bookURL(base string, id uint) string {
+	return base + "/books/" + strconv.FormatUint(uint64(id), 10)
+}
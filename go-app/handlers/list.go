@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"library-go/query"
+)
+
+// listResponse is the envelope returned by list endpoints
+type listResponse struct {
+	Data       interface{} `json:"data"`
+	Total      *int64      `json:"total,omitempty"`
+	Page       int         `json:"page,omitempty"`
+	Limit      int         `json:"limit"`
+	Next       *string     `json:"next"`
+	Prev       *string     `json:"prev"`
+	NextCursor *string     `json:"next_cursor,omitempty"`
+	Facets     interface{} `json:"facets,omitempty"`
+}
+
+// parseListParams parses filter, sort and field selection parameters, writing a 400 response on failure
+func parseListParams(c *gin.Context, resource query.Resource, extra ...string) (*query.Params, bool) {
+	params, err := query.Parse(c.Request.URL.Query(), resource, extra...)
+	if err != nil {
+		respondQueryError(c, err)
+		return nil, false
+	}
+	return params, true
+}
+
+// parsePage parses page and limit parameters, writing a 400 response on failure
+func parsePage(c *gin.Context, defaultLimit int) (query.Page, bool) {
+	page, err := query.ParsePage(c.Request.URL.Query(), defaultLimit)
+	if err != nil {
+		respondQueryError(c, err)
+		return query.Page{}, false
+	}
+	return page, true
+}
+
+func respondQueryError(c *gin.Context, err error) {
+	var queryErr *query.Error
+	if errors.As(err, &queryErr) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": queryErr.Message})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+}
+
+// respondPage writes an offset paginated list with totals, next/prev links and a Link header
+func respondPage(c *gin.Context, params *query.Params, page query.Page, total int64, items interface{}) {
+	data, ok := selectFields(c, params, items)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, pageResponse(c, page, total, data))
+}
+
+// pageResponse builds the envelope of an offset paginated list and sets the Link and X-Total-Count headers
+func pageResponse(c *gin.Context, page query.Page, total int64, data interface{}) listResponse {
+	response := listResponse{
+		Data:  data,
+		Total: &total,
+		Page:  page.Number,
+		Limit: page.Limit,
+	}
+
+	lastPage := page.LastPage(total)
+	links := []string{
+		linkHeader(pageURL(c, "page", "1"), "first"),
+		linkHeader(pageURL(c, "page", strconv.Itoa(lastPage)), "last"),
+	}
+	if page.Number < lastPage {
+		next := pageURL(c, "page", strconv.Itoa(page.Number+1))
+		response.Next = &next
+		links = append(links, linkHeader(next, "next"))
+	}
+	if page.Number > 1 {
+		prev := pageURL(c, "page", strconv.Itoa(minInt(page.Number-1, lastPage)))
+		response.Prev = &prev
+		links = append(links, linkHeader(prev, "prev"))
+	}
+
+	c.Header("Link", strings.Join(links, ", "))
+	c.Header("X-Total-Count", strconv.FormatInt(total, 10))
+	return response
+}
+
+// respondCursor writes a keyset paginated list, nextCursor is empty on the last page
+func respondCursor(c *gin.Context, params *query.Params, limit int, nextCursor string, items interface{}) {
+	data, ok := selectFields(c, params, items)
+	if !ok {
+		return
+	}
+
+	response := listResponse{
+		Data:  data,
+		Limit: limit,
+	}
+	if nextCursor != "" {
+		next := pageURL(c, "cursor", nextCursor)
+		response.Next = &next
+		response.NextCursor = &nextCursor
+		c.Header("Link", linkHeader(next, "next"))
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func selectFields(c *gin.Context, params *query.Params, items interface{}) (interface{}, bool) {
+	if params == nil {
+		return items, true
+	}
+	selected, err := params.Select(items)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encode response"})
+		return nil, false
+	}
+	return selected, true
+}
+
+// pageURL returns the current request URL with one query parameter replaced
+func pageURL(c *gin.Context, key, value string) string {
+	u := *c.Request.URL
+	values := u.Query()
+	values.Set(key, value)
+	u.RawQuery = values.Encode()
+	return u.RequestURI()
+}
+
+func linkHeader(target, rel string) string {
+	return fmt.Sprintf(`<%s>; rel="%s"`, target, rel)
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+
+	"library-go/database"
+	"library-go/metadata"
+	"library-go/models"
+)
+
+// LookupBook returns what the external catalogues know about an ISBN, and the
+// catalogued book that already has it, if any
+func (h *BookHandler) LookupBook(c *gin.Context) {
+	isbn, err := models.NormalizeISBN(c.Query("isbn"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	record, err := metadata.Lookup(c.Request.Context(), isbn)
+	if err != nil {
+		respondLookupError(c, err)
+		return
+	}
+
+	response := struct {
+		*metadata.Record
+		BookID *uint `json:"book_id,omitempty"` // Catalogued book with this ISBN
+	}{Record: record}
+	var book models.Book
+	if err := database.DB.Select("id").Where("isbn = ?", isbn).Limit(1).Find(&book).Error; err == nil && book.ID != 0 {
+		response.BookID = &book.ID
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func respondLookupError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, metadata.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "No metadata found for this ISBN"})
+	case errors.Is(err, metadata.ErrDisabled):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Metadata lookup is disabled"})
+	default:
+		log.Println("Metadata lookup failed:", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to look up book metadata"})
+	}
+}
+
+// enrichBook fills the empty fields of a new book, and its authors when it
+// has none, from the catalogue record of its ISBN, writing an error response
+// on failure. A failed lookup only matters when title or author is missing.
+func enrichBook(c *gin.Context, book *models.Book, contributors *[]contributorInput) bool {
+	if book.ISBN == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "enrich requires an isbn"})
+		return false
+	}
+	isbn, err := models.NormalizeISBN(*book.ISBN)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+
+	hasAuthor := strings.TrimSpace(book.Author) != "" || len(*contributors) > 0
+	record, err := metadata.Lookup(c.Request.Context(), isbn)
+	if err != nil {
+		if strings.TrimSpace(book.Title) != "" && hasAuthor {
+			log.Println("Metadata lookup for", isbn, "failed:", err)
+			return true
+		}
+		if errors.Is(err, metadata.ErrNotFound) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "No metadata found for this ISBN, so title and author are required"})
+			return false
+		}
+		respondLookupError(c, err)
+		return false
+	}
+
+	// Values the book would reject are left out rather than failing the request
+	if strings.TrimSpace(book.Title) == "" && len(record.Title) <= 500 {
+		book.Title = record.Title
+	}
+	if !hasAuthor {
+		for _, name := range record.Authors {
+			*contributors = append(*contributors, contributorInput{Name: name, Role: models.RoleAuthor})
+		}
+	}
+	if book.Year == nil && record.Year != nil && *record.Year >= 1000 && *record.Year <= time.Now().Year()+10 {
+		book.Year = record.Year
+	}
+	if book.Publisher == nil && record.Publisher != nil && len(*record.Publisher) <= 200 {
+		book.Publisher = record.Publisher
+	}
+	if book.PageCount == nil && record.PageCount != nil && *record.PageCount <= 100000 {
+		book.PageCount = record.PageCount
+	}
+	if book.Language == nil && record.Language != nil {
+		if code, err := models.NormalizeLanguage(*record.Language); err == nil {
+			book.Language = &code
+		}
+	}
+	if book.Description == nil && record.Description != nil {
+		description := truncateText(*record.Description, 2000)
+		book.Description = &description
+	}
+	return true
+}
+
+// truncateText shortens text to at most max bytes without splitting a character
+func truncateText(text string, max int) string {
+	if len(text) <= max {
+		return text
+	}
+	for max > 0 && !utf8.RuneStart(text[max]) {
+		max--
+	}
+	return text[:max]
+}
@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"library-go/database"
+	"library-go/marc"
+	"library-go/models"
+)
+
+type MARCHandler struct{}
+
+// exportBatchSize is the number of books loaded at a time while streaming an export
+const exportBatchSize = 500
+
+// recordReader is implemented by the ISO 2709 and MARCXML readers
+type recordReader interface {
+	Read() (*marc.Record, error)
+}
+
+func NewMARCHandler() *MARCHandler {
+	return &MARCHandler{}
+}
+
+// ImportMARC creates books from ISO 2709 or MARCXML records, given as the
+// request body or a "file" form field. Records whose ISBN is already in the
+// catalogue are skipped. With dry_run=true only the report is produced.
+func (h *MARCHandler) ImportMARC(c *gin.Context) {
+	dryRun, err := strconv.ParseBool(c.DefaultQuery("dry_run", "false"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "dry_run must be true or false"})
+		return
+	}
+
+	body, ok := importBody(c)
+	if !ok {
+		return
+	}
+	defer body.Close()
+
+	// MARCXML starts with markup, ISO 2709 with the five-digit record length
+	buffered := bufio.NewReader(body)
+	var reader recordReader
+	if start, _ := buffered.Peek(512); strings.HasPrefix(strings.TrimSpace(string(start)), "<") {
+		reader = marc.NewXMLReader(buffered)
+	} else {
+		reader = marc.NewReader(buffered)
+	}
+
+	var entries []marc.Entry
+	var readErr error
+	undecoded := make(map[int]error) // Records that could be skipped, by entry index
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		var recordErr *marc.RecordError
+		if errors.As(err, &recordErr) {
+			undecoded[len(entries)] = recordErr
+			entries = append(entries, marc.Entry{})
+			continue
+		}
+		if err != nil {
+			readErr = err
+			break
+		}
+		entries = append(entries, marc.ToBook(record))
+	}
+	if readErr != nil && len(entries) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read MARC records: " + readErr.Error()})
+		return
+	}
+
+	var isbns []string
+	for _, entry := range entries {
+		if entry.Book.ISBN != nil {
+			isbns = append(isbns, *entry.Book.ISBN)
+		}
+	}
+	existing, err := isbnBookIDs(isbns)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check existing books"})
+		return
+	}
+
+	report := newImportReport(dryRun)
+	for i, entry := range entries {
+		book := entry.Book
+		result := importRecord{Index: i + 1, Title: book.Title, ISBN: book.ISBN, Warnings: entry.Warnings}
+		if err, ok := undecoded[i]; ok {
+			result.Status = importInvalid
+			result.Errors = []string{err.Error()}
+			report.add(result)
+			continue
+		}
+
+		inputs := make([]contributorInput, len(entry.Credits))
+		for j, credit := range entry.Credits {
+			inputs[j] = contributorInput{Name: credit.Name, Role: credit.Role}
+		}
+		if err := book.Validate(); err != nil {
+			result.Status = importInvalid
+			result.Errors = []string{err.Error()}
+			report.add(result)
+			continue
+		}
+		if book.ISBN != nil {
+			if id, ok := existing[*book.ISBN]; ok {
+				result.Status = importDuplicate
+				if id != 0 {
+					result.BookID = &id
+				}
+				report.add(result)
+				continue
+			}
+		}
+
+		if dryRun {
+			result.Status = importWouldCreate
+		} else if err := insertBook(&book, inputs); err != nil {
+			result.Status = importInvalid
+			result.Errors = []string{"failed to create book"}
+			log.Println("MARC import failed for record", i+1, err)
+		} else {
+			result.Status = importCreated
+			result.BookID = &book.ID
+		}
+		// Later records with the same ISBN are duplicates of this one
+		if book.ISBN != nil && result.Status != importInvalid {
+			existing[*book.ISBN] = derefUint(result.BookID)
+		}
+		report.add(result)
+	}
+	if readErr != nil {
+		report.Error = fmt.Sprintf("stopped after record %d: %v", len(entries), readErr)
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// ExportMARC streams the books matching the list filters as ISO 2709 records
+func (h *MARCHandler) ExportMARC(c *gin.Context) {
+	h.export(c, "application/marc", "books.mrc", func(w io.Writer) marcWriter {
+		return isoWriter{marc.NewWriter(w)}
+	})
+}
+
+// ExportMARCXML streams the books matching the list filters as a MARCXML collection
+func (h *MARCHandler) ExportMARCXML(c *gin.Context) {
+	h.export(c, "application/marcxml+xml", "books.xml", func(w io.Writer) marcWriter {
+		return marc.NewXMLWriter(w)
+	})
+}
+
+// marcWriter is a record encoder that is closed once every record is written
+type marcWriter interface {
+	Write(*marc.Record) error
+	Close() error
+}
+
+// isoWriter adapts the ISO 2709 writer, which needs no closing, to marcWriter
+type isoWriter struct {
+	*marc.Writer
+}
+
+func (isoWriter) Close() error { return nil }
+
+// export streams the filtered books in batches, so large catalogues are never held in memory
+func (h *MARCHandler) export(c *gin.Context, contentType, filename string, newWriter func(io.Writer) marcWriter) {
+	params, ok := parseListParams(c, bookFields, "category", "tag")
+	if !ok {
+		return
+	}
+	related, ok := relatedBookScopes(c)
+	if !ok {
+		return
+	}
+
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Status(http.StatusOK)
+
+	writer := newWriter(c.Writer)
+	for offset := 0; ; offset += exportBatchSize {
+		var books []models.Book
+		if err := database.DB.Scopes(withContributors, withCategories, withTags, params.Scope("id")).Scopes(related...).
+			Offset(offset).Limit(exportBatchSize).Find(&books).Error; err != nil {
+			// The status is already sent, so the truncated download is all the client sees
+			log.Println("MARC export failed:", err)
+			return
+		}
+		for i := range books {
+			if err := writer.Write(marc.FromBook(&books[i])); err != nil {
+				if errors.Is(err, marc.ErrRecordTooLong) {
+					log.Println("MARC export skipped book", books[i].ID, err)
+					continue
+				}
+				log.Println("MARC export failed:", err)
+				return
+			}
+		}
+		if len(books) < exportBatchSize {
+			break
+		}
+	}
+	if err := writer.Close(); err != nil {
+		log.Println("MARC export failed:", err)
+	}
+}
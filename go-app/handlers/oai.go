@@ -0,0 +1,572 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"library-go/config"
+	"library-go/database"
+	"library-go/dublincore"
+	"library-go/marc"
+	"library-go/models"
+	"library-go/oai"
+)
+
+type OAIHandler struct{}
+
+// oaiPageSize is the number of headers or records in each part of a list
+const oaiPageSize = 100
+
+// Parts of a list, walked in order: the catalogued books, then the deleted ones
+const (
+	oaiPhaseBooks = iota
+	oaiPhaseDeleted
+)
+
+// oaiFormats are the metadata formats books are disseminated in
+var oaiFormats = []oai.MetadataFormat{
+	{Prefix: "oai_dc", Schema: oai.DCSchema, Namespace: oai.DCNamespace},
+	{Prefix: "marc21", Schema: "http://www.loc.gov/standards/marcxml/schema/MARC21slim.xsd", Namespace: marc.Namespace},
+}
+
+// oaiArguments lists the arguments each verb accepts
+var oaiArguments = map[string][]string{
+	oai.VerbIdentify:            {},
+	oai.VerbListMetadataFormats: {"identifier"},
+	oai.VerbListSets:            {"resumptionToken"},
+	oai.VerbListIdentifiers:     {"metadataPrefix", "from", "until", "set", "resumptionToken"},
+	oai.VerbListRecords:         {"metadataPrefix", "from", "until", "set", "resumptionToken"},
+	oai.VerbGetRecord:           {"identifier", "metadataPrefix"},
+}
+
+func NewOAIHandler() *OAIHandler {
+	return &OAIHandler{}
+}
+
+// HandleRequest answers an OAI-PMH request, given in the query string or as a form.
+// Protocol errors are part of the response, which is always 200 OK.
+func (h *OAIHandler) HandleRequest(c *gin.Context) {
+	response := &oai.Response{Date: time.Now(), BaseURL: requestBaseURL(c) + c.Request.URL.Path, Args: map[string]string{}}
+
+	var oaiErr *oai.Error
+	if err := h.handle(c, response); errors.As(err, &oaiErr) {
+		response.Errors = []*oai.Error{oaiErr}
+	} else if err != nil {
+		log.Println("OAI-PMH request failed:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to answer OAI-PMH request"})
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := oai.Write(&buf, response); err != nil {
+		log.Println("OAI-PMH response failed:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encode OAI-PMH response"})
+		return
+	}
+	c.Data(http.StatusOK, "text/xml; charset=utf-8", buf.Bytes())
+}
+
+// handle checks the verb and its arguments and fills the response
+func (h *OAIHandler) handle(c *gin.Context, response *oai.Response) error {
+	if err := c.Request.ParseForm(); err != nil {
+		return oai.NewError(oai.BadArgument, "Malformed request")
+	}
+	verbs := c.Request.Form["verb"]
+	if len(verbs) != 1 {
+		return oai.NewError(oai.BadVerb, "The verb argument is missing or repeated")
+	}
+	response.Verb = verbs[0]
+	allowed, ok := oaiArguments[response.Verb]
+	if !ok {
+		return oai.NewError(oai.BadVerb, "Illegal verb "+response.Verb)
+	}
+
+	for name, values := range c.Request.Form {
+		if name == "verb" {
+			continue
+		}
+		legal := false
+		for _, arg := range allowed {
+			legal = legal || arg == name
+		}
+		if !legal {
+			return oai.NewError(oai.BadArgument, "Illegal argument "+name+" for "+response.Verb)
+		}
+		if len(values) > 1 {
+			return oai.NewError(oai.BadArgument, "Repeated argument "+name)
+		}
+		response.Args[name] = values[0]
+	}
+
+	switch response.Verb {
+	case oai.VerbIdentify:
+		return h.identify(response)
+	case oai.VerbListMetadataFormats:
+		return h.listMetadataFormats(response)
+	case oai.VerbListSets:
+		return h.listSets(response)
+	case oai.VerbGetRecord:
+		return h.getRecord(c, response)
+	default:
+		return h.list(c, response)
+	}
+}
+
+func (h *OAIHandler) identify(response *oai.Response) error {
+	// The earliest datestamp is that of the oldest book or tombstone, or now for an empty catalogue
+	earliest := response.Date
+	var book models.Book
+	if err := database.DB.Select("updated_at").Order("updated_at").Limit(1).Find(&book).Error; err != nil {
+		return err
+	}
+	if book.UpdatedAt.Before(earliest) && !book.UpdatedAt.IsZero() {
+		earliest = book.UpdatedAt
+	}
+	var deleted models.DeletedBook
+	if err := database.DB.Order("deleted_at").Limit(1).Find(&deleted).Error; err != nil {
+		return err
+	}
+	if deleted.DeletedAt.Before(earliest) && !deleted.DeletedAt.IsZero() {
+		earliest = deleted.DeletedAt
+	}
+
+	response.Identify = &oai.Identify{
+		RepositoryName:       config.OAIRepositoryName,
+		AdminEmails:          []string{config.OAIAdminEmail},
+		EarliestDatestamp:    earliest,
+		DeletedRecord:        "persistent",
+		RepositoryIdentifier: config.OAIRepositoryIdentifier,
+		SampleIdentifier:     oaiIdentifier(1),
+	}
+	return nil
+}
+
+func (h *OAIHandler) listMetadataFormats(response *oai.Response) error {
+	if identifier, ok := response.Args["identifier"]; ok {
+		id, ok := parseOAIIdentifier(identifier)
+		if !ok {
+			return oai.NewError(oai.IDDoesNotExist, "Unknown identifier "+identifier)
+		}
+		var books, deleted int64
+		if err := database.DB.Model(&models.Book{}).Where("id = ?", id).Count(&books).Error; err != nil {
+			return err
+		}
+		if err := database.DB.Model(&models.DeletedBook{}).Where("book_id = ?", id).Count(&deleted).Error; err != nil {
+			return err
+		}
+		if books == 0 && deleted == 0 {
+			return oai.NewError(oai.IDDoesNotExist, "Unknown identifier "+identifier)
+		}
+	}
+	response.Formats = oaiFormats
+	return nil
+}
+
+// listSets lists the categories as sets, whose specs are their slug paths
+func (h *OAIHandler) listSets(response *oai.Response) error {
+	if _, ok := response.Args["resumptionToken"]; ok {
+		return oai.NewError(oai.BadResumptionToken, "Set lists are never split")
+	}
+	tree, err := loadCategoryTree(database.DB, false)
+	if err != nil {
+		return err
+	}
+	if len(tree.roots) == 0 {
+		return oai.NewError(oai.NoSetHierarchy, "The repository has no categories")
+	}
+
+	var walk func(nodes []*categoryNode, parents string)
+	walk = func(nodes []*categoryNode, parents string) {
+		for _, node := range nodes {
+			name := parents + node.Name
+			response.Sets = append(response.Sets, oai.Set{Spec: oaiSetSpec(tree, node.ID), Name: name})
+			walk(node.Children, name+" / ")
+		}
+	}
+	walk(tree.roots, "")
+	return nil
+}
+
+func (h *OAIHandler) getRecord(c *gin.Context, response *oai.Response) error {
+	identifier, ok := response.Args["identifier"]
+	if !ok {
+		return oai.NewError(oai.BadArgument, "identifier is required")
+	}
+	prefix, ok := response.Args["metadataPrefix"]
+	if !ok {
+		return oai.NewError(oai.BadArgument, "metadataPrefix is required")
+	}
+	if !oaiFormatKnown(prefix) {
+		return oai.NewError(oai.CannotDisseminateFormat, "Unknown metadata format "+prefix)
+	}
+	id, ok := parseOAIIdentifier(identifier)
+	if !ok {
+		return oai.NewError(oai.IDDoesNotExist, "Unknown identifier "+identifier)
+	}
+	tree, err := loadCategoryTree(database.DB, false)
+	if err != nil {
+		return err
+	}
+
+	var books []models.Book
+	if err := database.DB.Scopes(withContributors, withCategories, withTags).Where("id = ?", id).Limit(1).Find(&books).Error; err != nil {
+		return err
+	}
+	if len(books) == 1 {
+		record, err := bookOAIRecord(c, tree, books[0], prefix)
+		if err != nil {
+			return err
+		}
+		response.Records = []oai.Record{record}
+		return nil
+	}
+
+	var deleted []models.DeletedBook
+	if err := database.DB.Where("book_id = ?", id).Limit(1).Find(&deleted).Error; err != nil {
+		return err
+	}
+	if len(deleted) == 0 {
+		return oai.NewError(oai.IDDoesNotExist, "Unknown identifier "+identifier)
+	}
+	response.Records = []oai.Record{{Header: deletedOAIHeader(deleted[0])}}
+	return nil
+}
+
+// list answers ListIdentifiers and ListRecords, a page of books followed by
+// tombstones, continued by resumption tokens
+func (h *OAIHandler) list(c *gin.Context, response *oai.Response) error {
+	var token oai.Token
+	var err error
+	if value, ok := response.Args["resumptionToken"]; ok {
+		if len(response.Args) > 1 {
+			return oai.NewError(oai.BadArgument, "resumptionToken is an exclusive argument")
+		}
+		token, err = oai.DecodeToken(value)
+	} else {
+		token, err = parseOAIListArgs(response.Args)
+	}
+	if err != nil {
+		return err
+	}
+	if !oaiFormatKnown(token.Prefix) {
+		return oai.NewError(oai.CannotDisseminateFormat, "Unknown metadata format "+token.Prefix)
+	}
+
+	tree, err := loadCategoryTree(database.DB, false)
+	if err != nil {
+		return err
+	}
+	bookScope, deletedScope, err := oaiListScopes(tree, token)
+	if err != nil {
+		return err
+	}
+
+	var books, deletedBooks int64
+	if err := database.DB.Model(&models.Book{}).Scopes(bookScope).Count(&books).Error; err != nil {
+		return err
+	}
+	if err := database.DB.Model(&models.DeletedBook{}).Scopes(deletedScope).Count(&deletedBooks).Error; err != nil {
+		return err
+	}
+
+	withRecords := response.Verb == oai.VerbListRecords
+	next := token
+	var records []oai.Record
+	more := false
+	if next.Phase == oaiPhaseBooks {
+		var page []models.Book
+		query := database.DB.Scopes(bookScope, withCategories).Where("id > ?", next.After).Order("id").Limit(oaiPageSize + 1)
+		if withRecords {
+			query = query.Scopes(withContributors, withTags)
+		}
+		if err := query.Find(&page).Error; err != nil {
+			return err
+		}
+		if len(page) > oaiPageSize {
+			more = true
+			page = page[:oaiPageSize]
+			next.After = page[len(page)-1].ID
+		} else {
+			next.Phase, next.After = oaiPhaseDeleted, 0
+		}
+		for _, book := range page {
+			record := oai.Record{Header: bookOAIHeader(tree, book)}
+			if withRecords {
+				if record, err = bookOAIRecord(c, tree, book, token.Prefix); err != nil {
+					return err
+				}
+			}
+			records = append(records, record)
+		}
+	}
+	if !more {
+		room := oaiPageSize - len(records)
+		var page []models.DeletedBook
+		if err := database.DB.Scopes(deletedScope).Where("book_id > ?", next.After).Order("book_id").Limit(room + 1).Find(&page).Error; err != nil {
+			return err
+		}
+		if len(page) > room {
+			more = true
+			page = page[:room]
+			if room > 0 {
+				next.After = page[room-1].BookID
+			}
+		}
+		for _, deleted := range page {
+			records = append(records, oai.Record{Header: deletedOAIHeader(deleted)})
+		}
+	}
+
+	if len(records) == 0 {
+		return oai.NewError(oai.NoRecordsMatch, "No records match the request")
+	}
+	if withRecords {
+		response.Records = records
+	} else {
+		for _, record := range records {
+			response.Headers = append(response.Headers, record.Header)
+		}
+	}
+
+	// Lists that fit one response have no token, the last part of longer ones an empty one
+	if more || token.Cursor > 0 {
+		response.Token = &oai.ResumptionToken{CompleteListSize: books + deletedBooks, Cursor: token.Cursor}
+		if more {
+			next.Cursor = token.Cursor + len(records)
+			response.Token.Value = next.Encode()
+		}
+	}
+	return nil
+}
+
+// parseOAIListArgs reads the arguments of a list request that starts a new list
+func parseOAIListArgs(args map[string]string) (oai.Token, error) {
+	prefix, ok := args["metadataPrefix"]
+	if !ok {
+		return oai.Token{}, oai.NewError(oai.BadArgument, "metadataPrefix is required")
+	}
+	token := oai.Token{Prefix: prefix, Set: args["set"]}
+
+	var from, until time.Time
+	var fromDay, untilDay bool
+	var err error
+	if value, ok := args["from"]; ok {
+		if from, fromDay, err = oai.ParseDatestamp(value); err != nil {
+			return oai.Token{}, err
+		}
+		token.From = &from
+	}
+	if value, ok := args["until"]; ok {
+		if until, untilDay, err = oai.ParseDatestamp(value); err != nil {
+			return oai.Token{}, err
+		}
+		// Datestamps are truncated, so until covers the whole second or day it names
+		before := until.Add(time.Second)
+		if untilDay {
+			before = until.AddDate(0, 0, 1)
+		}
+		token.Before = &before
+	}
+	if token.From != nil && token.Before != nil {
+		if fromDay != untilDay {
+			return oai.Token{}, oai.NewError(oai.BadArgument, "from and until must have the same granularity")
+		}
+		if from.After(until) {
+			return oai.Token{}, oai.NewError(oai.BadArgument, "from must not be later than until")
+		}
+	}
+	return token, nil
+}
+
+// oaiListScopes returns the filters of a list on books and on tombstones
+func oaiListScopes(tree *categoryTree, token oai.Token) (books, deleted func(*gorm.DB) *gorm.DB, err error) {
+	var categoryIDs []uint
+	if token.Set != "" {
+		if len(tree.roots) == 0 {
+			return nil, nil, oai.NewError(oai.NoSetHierarchy, "The repository has no categories")
+		}
+		for id := range tree.nodes {
+			if oaiSetSpec(tree, id) == token.Set {
+				categoryIDs = tree.descendants(id)
+				break
+			}
+		}
+		if categoryIDs == nil {
+			return nil, nil, oai.NewError(oai.NoRecordsMatch, "Unknown set "+token.Set)
+		}
+	}
+
+	// Times are compared in the zone GORM stores them in
+	books = func(db *gorm.DB) *gorm.DB {
+		if token.From != nil {
+			db = db.Where("updated_at >= ?", token.From.Local())
+		}
+		if token.Before != nil {
+			db = db.Where("updated_at < ?", token.Before.Local())
+		}
+		if categoryIDs != nil {
+			db = db.Where("id IN (?)", database.DB.Table("book_categories").Select("book_id").Where("category_id IN ?", categoryIDs))
+		}
+		return db
+	}
+	deleted = func(db *gorm.DB) *gorm.DB {
+		// A tombstone whose ID was taken again by a new book is superseded by it
+		db = db.Where("book_id NOT IN (?)", database.DB.Model(&models.Book{}).Select("id"))
+		if token.From != nil {
+			db = db.Where("deleted_at >= ?", token.From.Local())
+		}
+		if token.Before != nil {
+			db = db.Where("deleted_at < ?", token.Before.Local())
+		}
+		if token.Set != "" {
+			db = db.Where("sets LIKE ? OR sets LIKE ?", "% "+token.Set+" %", "% "+token.Set+":%")
+		}
+		return db
+	}
+	return books, deleted, nil
+}
+
+// recordDeletedBook leaves the tombstone of a book about to be deleted, with
+// the sets it is in; it must run before the book loses its categories
+func recordDeletedBook(tx *gorm.DB, book *models.Book) error {
+	var categories []models.Category
+	if err := tx.Model(book).Association("Categories").Find(&categories); err != nil {
+		return err
+	}
+	tree, err := loadCategoryTree(tx, false)
+	if err != nil {
+		return err
+	}
+	sets := " " + strings.Join(oaiBookSets(tree, categories), " ") + " "
+	return tx.Save(&models.DeletedBook{BookID: book.ID, Sets: sets, DeletedAt: time.Now()}).Error
+}
+
+// oaiSetSpec returns the set spec of a category, the slugs from its root down
+func oaiSetSpec(tree *categoryTree, id uint) string {
+	var slugs []string
+	for _, node := range tree.path(id) {
+		slugs = append(slugs, node.Slug)
+	}
+	return strings.Join(slugs, ":")
+}
+
+func oaiBookSets(tree *categoryTree, categories []models.Category) []string {
+	sets := make([]string, 0, len(categories))
+	for _, category := range categories {
+		sets = append(sets, oaiSetSpec(tree, category.ID))
+	}
+	return sets
+}
+
+func oaiIdentifier(id uint) string {
+	return "oai:" + config.OAIRepositoryIdentifier + ":" + strconv.FormatUint(uint64(id), 10)
+}
+
+func parseOAIIdentifier(identifier string) (uint, bool) {
+	rest := strings.TrimPrefix(identifier, "oai:"+config.OAIRepositoryIdentifier+":")
+	if rest == identifier {
+		return 0, false
+	}
+	id, err := strconv.ParseUint(rest, 10, 32)
+	if err != nil || id == 0 {
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func oaiFormatKnown(prefix string) bool {
+	for _, format := range oaiFormats {
+		if format.Prefix == prefix {
+			return true
+		}
+	}
+	return false
+}
+
+func bookOAIHeader(tree *categoryTree, book models.Book) oai.Header {
+	return oai.Header{Identifier: oaiIdentifier(book.ID), Datestamp: book.UpdatedAt, Sets: oaiBookSets(tree, book.Categories)}
+}
+
+func deletedOAIHeader(deleted models.DeletedBook) oai.Header {
+	return oai.Header{Identifier: oaiIdentifier(deleted.BookID), Datestamp: deleted.DeletedAt, Sets: strings.Fields(deleted.Sets), Deleted: true}
+}
+
+// bookOAIRecord describes a book in a metadata format; contributors,
+// categories and tags must be loaded
+func bookOAIRecord(c *gin.Context, tree *categoryTree, book models.Book, prefix string) (oai.Record, error) {
+	record := oai.Record{Header: bookOAIHeader(tree, book)}
+	var err error
+	if prefix == "marc21" {
+		record.Metadata, err = marc.MarshalXMLRecord(marc.FromBook(&book))
+	} else {
+		record.Metadata, err = oai.MarshalDC(bookDublinCore(requestBaseURL(c), book))
+	}
+	return record, err
+}
+
+// bookDublinCore describes a book in simple Dublin Core
+func bookDublinCore(base string, book models.Book) dublincore.Record {
+	dc := dublincore.Record{
+		Title:      []string{book.Title},
+		Type:       []string{dcmiType(book.Format)},
+		Identifier: []string{bookURL(base, book.ID)},
+	}
+	for _, contribution := range book.Contributors {
+		if contribution.Author == nil {
+			continue
+		}
+		if contribution.Role == models.RoleAuthor {
+			dc.Creator = append(dc.Creator, contribution.Author.Name)
+		} else {
+			dc.Contributor = append(dc.Contributor, contribution.Author.Name)
+		}
+	}
+	if len(dc.Creator) == 0 && len(dc.Contributor) == 0 && book.Author != "" {
+		dc.Creator = []string{book.Author}
+	}
+	for _, category := range book.Categories {
+		dc.Subject = append(dc.Subject, category.Name)
+	}
+	for _, tag := range book.Tags {
+		dc.Subject = append(dc.Subject, tag.Name)
+	}
+	if book.Description != nil {
+		dc.Description = []string{*book.Description}
+	}
+	if book.Publisher != nil {
+		dc.Publisher = []string{*book.Publisher}
+	}
+	if book.Year != nil {
+		dc.Date = []string{strconv.Itoa(*book.Year)}
+	}
+	if book.PageCount != nil {
+		dc.Format = []string{strconv.Itoa(*book.PageCount) + " pages"}
+	}
+	if book.ISBN != nil {
+		dc.Identifier = append([]string{"urn:isbn:" + *book.ISBN}, dc.Identifier...)
+	}
+	if book.Language != nil {
+		dc.Language = []string{*book.Language}
+	}
+	return dc
+}
+
+// dcmiType returns the DCMI type of a book format
+func dcmiType(format string) string {
+	switch format {
+	case models.FormatAudiobook:
+		return "Sound"
+	case models.FormatDVD:
+		return "MovingImage"
+	default:
+		return "Text"
+	}
+}
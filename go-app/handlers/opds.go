@@ -0,0 +1,494 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"library-go/circulation"
+	"library-go/covers"
+	"library-go/database"
+	"library-go/models"
+	"library-go/opds"
+	"library-go/query"
+	"library-go/schemaorg"
+	"library-go/search"
+)
+
+type OPDSHandler struct{}
+
+// opdsPageSize is the number of publications on a page of an acquisition feed
+const opdsPageSize = 25
+
+// Path prefixes of the OPDS 1.2 and OPDS 2.0 catalogs, which share their feeds
+const (
+	opdsPrefix   = "/opds"
+	opdsV2Prefix = "/opds/v2"
+)
+
+func NewOPDSHandler() *OPDSHandler {
+	return &OPDSHandler{}
+}
+
+// Root is the start feed of the catalog, leading to the other feeds
+func (h *OPDSHandler) Root(c *gin.Context) {
+	prefix := catalogPrefix(c)
+	feed := newCatalogFeed(c, "Library catalog")
+	feed.Navigation = []opds.Navigation{
+		{Title: "New arrivals", Href: prefix + "/new", Type: opds.AcquisitionType, Summary: "Books most recently added to the catalog"},
+		{Title: "All books", Href: prefix + "/books", Type: opds.AcquisitionType, Summary: "The whole catalog by title"},
+		{Title: "By author", Href: prefix + "/authors", Type: opds.NavigationType, Summary: "Books grouped by author"},
+		{Title: "By subject", Href: prefix + "/subjects", Type: opds.NavigationType, Summary: "Books grouped by subject and genre"},
+	}
+	for i := range feed.Navigation {
+		feed.Navigation[i].ID = requestBaseURL(c) + feed.Navigation[i].Href
+	}
+	respondCatalog(c, feed, opds.NavigationType)
+}
+
+// NewArrivals lists the books most recently added, newest first
+func (h *OPDSHandler) NewArrivals(c *gin.Context) {
+	page, ok := parsePage(c, opdsPageSize)
+	if !ok {
+		return
+	}
+	params, ok := parseListParams(c, bookFields)
+	if !ok {
+		return
+	}
+
+	var total int64
+	if err := database.DB.Model(&models.Book{}).Scopes(params.FilterScope()).Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch books"})
+		return
+	}
+	var books []models.Book
+	if err := database.DB.Scopes(withContributors, withCategories, params.FilterScope()).Order("created_at DESC, id DESC").
+		Offset(page.Offset()).Limit(page.Limit).Find(&books).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch books"})
+		return
+	}
+
+	respondPublications(c, newCatalogFeed(c, "New arrivals"), page, total, books)
+}
+
+// GetBooks lists the catalog by title, accepting the filters of the book list
+func (h *OPDSHandler) GetBooks(c *gin.Context) {
+	page, ok := parsePage(c, opdsPageSize)
+	if !ok {
+		return
+	}
+	params, ok := parseListParams(c, bookFields, "category", "tag")
+	if !ok {
+		return
+	}
+	related, ok := relatedBookScopes(c)
+	if !ok {
+		return
+	}
+
+	var total int64
+	if err := database.DB.Model(&models.Book{}).Scopes(params.FilterScope()).Scopes(related...).Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch books"})
+		return
+	}
+	var books []models.Book
+	if err := database.DB.Scopes(withContributors, withCategories, params.Scope("title")).Scopes(related...).
+		Offset(page.Offset()).Limit(page.Limit).Find(&books).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch books"})
+		return
+	}
+
+	respondPublications(c, newCatalogFeed(c, "All books"), page, total, books)
+}
+
+// Search runs a catalog search for the q parameter, or query as OPDS 2.0 names it
+func (h *OPDSHandler) Search(c *gin.Context) {
+	page, ok := parsePage(c, opdsPageSize)
+	if !ok {
+		return
+	}
+	terms := strings.TrimSpace(c.Query("q"))
+	if terms == "" {
+		terms = strings.TrimSpace(c.Query("query"))
+	}
+	if terms == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Query parameter q is required"})
+		return
+	}
+
+	result, err := search.Books(database.DB, search.Request{Query: terms, Limit: page.Limit, Offset: page.Offset()})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search books"})
+		return
+	}
+
+	// Hits come without their credits and categories
+	books, err := hitBooks(result.Hits, withContributors, withCategories)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search books"})
+		return
+	}
+
+	respondPublications(c, newCatalogFeed(c, fmt.Sprintf("Search results for %q", terms)), page, result.Total, books)
+}
+
+// OpenSearch describes the search feed, so OPDS 1.2 apps can offer a search box
+func (h *OPDSHandler) OpenSearch(c *gin.Context) {
+	var buf bytes.Buffer
+	template := requestBaseURL(c) + opdsPrefix + "/search?q={searchTerms}"
+	if err := opds.WriteOpenSearch(&buf, "Library", "Search the library catalog", template); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encode search description"})
+		return
+	}
+	c.Data(http.StatusOK, opds.OpenSearchType, buf.Bytes())
+}
+
+// GetAuthors lists the authors with books in the catalog, by name
+func (h *OPDSHandler) GetAuthors(c *gin.Context) {
+	page, ok := parsePage(c, query.DefaultLimit)
+	if !ok {
+		return
+	}
+
+	credited := database.DB.Model(&models.Contribution{}).Select("author_id")
+	var total int64
+	if err := database.DB.Model(&models.Author{}).Where("id IN (?)", credited).Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch authors"})
+		return
+	}
+	var authors []models.Author
+	if err := database.DB.Where("id IN (?)", credited).Order("name, id").
+		Offset(page.Offset()).Limit(page.Limit).Find(&authors).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch authors"})
+		return
+	}
+
+	ids := make([]uint, len(authors))
+	for i, author := range authors {
+		ids[i] = author.ID
+	}
+	var counts []struct {
+		AuthorID uint
+		Count    int
+	}
+	if len(ids) > 0 {
+		if err := database.DB.Model(&models.Contribution{}).Select("author_id, count(DISTINCT book_id) AS count").
+			Where("author_id IN ?", ids).Group("author_id").Scan(&counts).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch authors"})
+			return
+		}
+	}
+	bookCounts := make(map[uint]int, len(counts))
+	for _, count := range counts {
+		bookCounts[count.AuthorID] = count.Count
+	}
+
+	feed := newCatalogFeed(c, "Authors")
+	prefix := catalogPrefix(c)
+	for _, author := range authors {
+		href := prefix + "/authors/" + strconv.FormatUint(uint64(author.ID), 10)
+		feed.Navigation = append(feed.Navigation, opds.Navigation{
+			ID:      requestBaseURL(c) + href,
+			Title:   author.Name,
+			Href:    href,
+			Type:    opds.AcquisitionType,
+			Summary: countText(bookCounts[author.ID], "book"),
+			Count:   bookCounts[author.ID],
+		})
+	}
+	paginateFeed(c, feed, page, total)
+	respondCatalog(c, feed, opds.NavigationType)
+}
+
+// GetAuthorBooks lists the books an author is credited on
+func (h *OPDSHandler) GetAuthorBooks(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid author ID"})
+		return
+	}
+	var author models.Author
+	if err := database.DB.First(&author, uint(id)).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Author not found"})
+		return
+	}
+
+	contributions := database.DB.Model(&models.Contribution{}).Select("book_id").Where("author_id = ?", author.ID)
+	h.listBooks(c, author.Name, func(db *gorm.DB) *gorm.DB {
+		return db.Where("id IN (?)", contributions)
+	})
+}
+
+// GetSubjects lists every category with books in it, by its path in the category tree
+func (h *OPDSHandler) GetSubjects(c *gin.Context) {
+	tree, err := loadCategoryTree(database.DB, true)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch categories"})
+		return
+	}
+
+	feed := newCatalogFeed(c, "Subjects")
+	prefix := catalogPrefix(c)
+	var walk func(nodes []*categoryNode, parents string)
+	walk = func(nodes []*categoryNode, parents string) {
+		for _, node := range nodes {
+			title := parents + node.Name
+			if node.TotalCount > 0 {
+				href := prefix + "/subjects/" + strconv.FormatUint(uint64(node.ID), 10)
+				feed.Navigation = append(feed.Navigation, opds.Navigation{
+					ID:      requestBaseURL(c) + href,
+					Title:   title,
+					Href:    href,
+					Type:    opds.AcquisitionType,
+					Summary: countText(node.TotalCount, "book"),
+					Count:   node.TotalCount,
+				})
+			}
+			walk(node.Children, title+" / ")
+		}
+	}
+	walk(tree.roots, "")
+	respondCatalog(c, feed, opds.NavigationType)
+}
+
+// GetSubjectBooks lists the books in a category, given by ID or slug, and its descendants
+func (h *OPDSHandler) GetSubjectBooks(c *gin.Context) {
+	tree, err := loadCategoryTree(database.DB, false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch categories"})
+		return
+	}
+	node, ok := tree.find(c.Param("id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+		return
+	}
+
+	inCategory := database.DB.Table("book_categories").Select("book_id").Where("category_id IN ?", tree.descendants(node.ID))
+	h.listBooks(c, node.Name, func(db *gorm.DB) *gorm.DB {
+		return db.Where("id IN (?)", inCategory)
+	})
+}
+
+// listBooks responds with a page of the books matching scope, by title
+func (h *OPDSHandler) listBooks(c *gin.Context, title string, scope func(*gorm.DB) *gorm.DB) {
+	page, ok := parsePage(c, opdsPageSize)
+	if !ok {
+		return
+	}
+	params, ok := parseListParams(c, bookFields)
+	if !ok {
+		return
+	}
+
+	var total int64
+	if err := database.DB.Model(&models.Book{}).Scopes(scope, params.FilterScope()).Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch books"})
+		return
+	}
+	var books []models.Book
+	if err := database.DB.Scopes(withContributors, withCategories, scope, params.Scope("title")).
+		Offset(page.Offset()).Limit(page.Limit).Find(&books).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch books"})
+		return
+	}
+
+	respondPublications(c, newCatalogFeed(c, title), page, total, books)
+}
+
+// catalogPrefix returns the path prefix of the catalog version the request is for
+func catalogPrefix(c *gin.Context) string {
+	if strings.HasPrefix(c.FullPath(), opdsV2Prefix) {
+		return opdsV2Prefix
+	}
+	return opdsPrefix
+}
+
+// requestBaseURL returns the scheme and host the client reached the server at,
+// for the absolute URLs feeds use as identifiers
+func requestBaseURL(c *gin.Context) string {
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + c.Request.Host
+}
+
+// newCatalogFeed starts a feed with the links every feed of the catalog has
+func newCatalogFeed(c *gin.Context, title string) *opds.Feed {
+	prefix := catalogPrefix(c)
+	feed := &opds.Feed{
+		ID:      requestBaseURL(c) + c.Request.URL.Path,
+		Title:   title,
+		Updated: time.Now(),
+		Links:   []opds.Link{{Rel: "self", Href: c.Request.URL.RequestURI()}},
+	}
+	if prefix == opdsV2Prefix {
+		feed.Links = append(feed.Links,
+			opds.Link{Rel: "start", Href: prefix},
+			opds.Link{Rel: "search", Href: prefix + "/search{?query}", Type: opds.JSONType, Templated: true})
+	} else {
+		feed.Links = append(feed.Links,
+			opds.Link{Rel: "start", Href: prefix, Type: opds.NavigationType},
+			opds.Link{Rel: "search", Href: prefix + "/opensearch.xml", Type: opds.OpenSearchType})
+	}
+	return feed
+}
+
+// paginateFeed adds the pagination metadata and links of a page to the feed
+func paginateFeed(c *gin.Context, feed *opds.Feed, page query.Page, total int64) {
+	feed.TotalResults, feed.ItemsPerPage, feed.Page = total, page.Limit, page.Number
+
+	lastPage := page.LastPage(total)
+	feed.Links = append(feed.Links,
+		opds.Link{Rel: "first", Href: pageURL(c, "page", "1")},
+		opds.Link{Rel: "last", Href: pageURL(c, "page", strconv.Itoa(lastPage))})
+	if page.Number < lastPage {
+		feed.Links = append(feed.Links, opds.Link{Rel: "next", Href: pageURL(c, "page", strconv.Itoa(page.Number+1))})
+	}
+	if page.Number > 1 {
+		feed.Links = append(feed.Links, opds.Link{Rel: "previous", Href: pageURL(c, "page", strconv.Itoa(minInt(page.Number-1, lastPage)))})
+	}
+}
+
+// respondPublications responds with an acquisition feed of a page of books
+// and their availability
+func respondPublications(c *gin.Context, feed *opds.Feed, page query.Page, total int64, books []models.Book) {
+	available, err := circulation.AvailableCopies(database.DB, books)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch availability"})
+		return
+	}
+	holds, err := waitingHolds(books)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch availability"})
+		return
+	}
+
+	base := requestBaseURL(c)
+	for _, book := range books {
+		feed.Publications = append(feed.Publications, bookPublication(base, book, available[book.ID], holds[book.ID]))
+	}
+	paginateFeed(c, feed, page, total)
+	respondCatalog(c, feed, opds.AcquisitionType)
+}
+
+// bookPublication describes a book for a feed
+func bookPublication(base string, book models.Book, available, holds int) opds.Publication {
+	id := strconv.FormatUint(uint64(book.ID), 10)
+	pub := opds.Publication{
+		ID:          bookURL(base, book.ID),
+		Title:       book.Title,
+		Language:    derefString(book.Language),
+		Publisher:   derefString(book.Publisher),
+		Description: derefString(book.Description),
+		ISBN:        derefString(book.ISBN),
+		Updated:     book.UpdatedAt,
+		Links:       []opds.Link{{Rel: "alternate", Href: "/books/" + id, Type: schemaorg.ContentType}},
+		// Holds are placed through the API with the book ID
+		Borrow: &opds.Borrow{Href: "/api/holds", Type: "application/json", Copies: book.Copies, Available: available, Holds: holds},
+	}
+	if book.Year != nil {
+		pub.Year = *book.Year
+	}
+	if book.PageCount != nil {
+		pub.Pages = *book.PageCount
+	}
+	for _, contribution := range book.Contributors {
+		if contribution.Author != nil {
+			pub.Contributors = append(pub.Contributors, opds.Contributor{Name: contribution.Author.Name, Role: contribution.Role})
+		}
+	}
+	if len(pub.Contributors) == 0 && book.Author != "" {
+		pub.Contributors = []opds.Contributor{{Name: book.Author, Role: models.RoleAuthor}}
+	}
+	for _, category := range book.Categories {
+		pub.Subjects = append(pub.Subjects, opds.Subject{Name: category.Name, Code: category.Slug, Scheme: base + "/api/categories"})
+	}
+	if book.Cover != nil {
+		href := covers.URL(*book.Cover)
+		pub.Images = append(pub.Images, opds.Image{Href: href, Type: mime.TypeByExtension(path.Ext(href))})
+		for _, size := range covers.Sizes {
+			href := covers.URL(covers.ThumbnailKey(*book.Cover, size.Name))
+			pub.Images = append(pub.Images, opds.Image{Href: href, Type: "image/jpeg", Width: size.Width, Thumbnail: true})
+		}
+	}
+	return pub
+}
+
+// respondCatalog writes a feed as OPDS 2.0 JSON or OPDS 1.2 Atom, by the catalog
+// the request is for; kind is the Atom media type of the feed
+func respondCatalog(c *gin.Context, feed *opds.Feed, kind string) {
+	if catalogPrefix(c) == opdsV2Prefix {
+		for i := range feed.Links {
+			if feed.Links[i].Type == "" {
+				feed.Links[i].Type = opds.JSONType
+			}
+		}
+		c.Header("Content-Type", opds.JSONType)
+		c.JSON(http.StatusOK, opds.JSON(feed))
+		return
+	}
+
+	for i := range feed.Links {
+		if feed.Links[i].Type == "" {
+			feed.Links[i].Type = kind
+		}
+	}
+	var buf bytes.Buffer
+	if err := opds.WriteAtom(&buf, feed); err != nil {
+		log.Println("OPDS feed failed:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encode feed"})
+		return
+	}
+	c.Data(http.StatusOK, kind, buf.Bytes())
+}
+
+// waitingHolds counts the waiting holds on each of the books
+func waitingHolds(books []models.Book) (map[uint]int, error) {
+	holds := make(map[uint]int, len(books))
+	if len(books) == 0 {
+		return holds, nil
+	}
+	ids := make([]uint, len(books))
+	for i, book := range books {
+		ids[i] = book.ID
+	}
+	var counts []struct {
+		BookID uint
+		Count  int
+	}
+	if err := database.DB.Model(&models.Hold{}).Select("book_id, count(*) AS count").
+		Where("book_id IN ? AND status = ?", ids, models.HoldWaiting).Group("book_id").Scan(&counts).Error; err != nil {
+		return nil, err
+	}
+	for _, count := range counts {
+		holds[count.BookID] = count.Count
+	}
+	return holds, nil
+}
+
+// countText writes a count with its noun, such as "1 book" or "3 books"
+func countText(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return strconv.Itoa(n) + " " + noun + "s"
+}
+
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -3,14 +3,31 @@ package handlers
 import (
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 	"library-go/database"
 	"library-go/models"
+	"library-go/query"
+	"library-go/suggest"
 )
 
 type ReaderHandler struct{}
 
+// readerFields lists the reader fields usable in filters, sort and field selection
+var readerFields = query.Resource{
+	"id":         {Type: query.Int, Filter: true, Sort: true},
+	"first_name": {Type: query.String, Filter: true, Sort: true},
+	"last_name":  {Type: query.String, Filter: true, Sort: true},
+	"email":      {Type: query.String, Filter: true, Sort: true},
+	"phone":      {Type: query.String, Filter: true},
+	"address":    {Type: query.String, Filter: true},
+	"created_at": {Type: query.Time, Filter: true, Sort: true},
+	"updated_at": {Type: query.Time, Filter: true, Sort: true},
+}
+
 func NewReaderHandler() *ReaderHandler {
 	return &ReaderHandler{}
 }
@@ -20,16 +37,87 @@ func (h *ReaderHandler) GetReaders(c *gin.Context) {
 	var readers []models.Reader
 	
 	// Get pagination parameters
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
-	offset := (page - 1) * limit
+	page, ok := parsePage(c, query.DefaultLimit)
+	if !ok {
+		return
+	}
+
+	// Get filter, sort and field selection parameters
+	params, ok := parseListParams(c, readerFields)
+	if !ok {
+		return
+	}
 
-	if err := database.DB.Offset(offset).Limit(limit).Find(&readers).Error; err != nil {
+	var total int64
+	if err := database.DB.Model(&models.Reader{}).Scopes(params.FilterScope()).Count(&total).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch readers"})
 		return
 	}
 
-	c.JSON(http.StatusOK, readers)
+	if err := database.DB.Scopes(params.Scope("id")).Offset(page.Offset()).Limit(page.Limit).Find(&readers).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch readers"})
+		return
+	}
+
+	respondPage(c, params, page, total, readers)
+}
+
+// SearchReaders finds readers by name or email, tolerating typos and
+// differences between Cyrillic and Latin spellings
+func (h *ReaderHandler) SearchReaders(c *gin.Context) {
+	q := strings.TrimSpace(c.Query("q"))
+	if q == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Query parameter q is required"})
+		return
+	}
+
+	// Get pagination parameters
+	page, ok := parsePage(c, 20)
+	if !ok {
+		return
+	}
+
+	matches := suggest.Match(suggest.Readers, q, maxFuzzyCandidates)
+	total := int64(len(matches))
+	if page.Offset() < len(matches) {
+		matches = matches[page.Offset():]
+	} else {
+		matches = nil
+	}
+	if len(matches) > page.Limit {
+		matches = matches[:page.Limit]
+	}
+
+	ids := make([]uint, len(matches))
+	for i, match := range matches {
+		ids[i] = match.ID
+	}
+
+	var readers []models.Reader
+	if len(ids) > 0 {
+		if err := database.DB.Where("id IN ?", ids).Find(&readers).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search readers"})
+			return
+		}
+	}
+
+	// Keep the readers in match order, with their similarity score
+	byID := make(map[uint]models.Reader, len(readers))
+	for _, reader := range readers {
+		byID[reader.ID] = reader
+	}
+	type readerHit struct {
+		models.Reader
+		Score float64 `json:"score"`
+	}
+	hits := make([]readerHit, 0, len(matches))
+	for _, match := range matches {
+		if reader, ok := byID[match.ID]; ok {
+			hits = append(hits, readerHit{Reader: reader, Score: match.Score})
+		}
+	}
+
+	c.JSON(http.StatusOK, pageResponse(c, page, total, hits))
 }
 
 // GetReader retrieves a specific reader by ID
@@ -71,6 +159,7 @@ func (h *ReaderHandler) CreateReader(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create reader"})
 		return
 	}
+	suggest.AddReader(input)
 
 	c.JSON(http.StatusCreated, input)
 }
@@ -88,6 +177,7 @@ func (h *ReaderHandler) UpdateReader(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Reader not found"})
 		return
 	}
+	original := reader
 
 	var input struct {
 		FirstName *string `json:"first_name"`
@@ -137,6 +227,7 @@ func (h *ReaderHandler) UpdateReader(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update reader"})
 		return
 	}
+	suggest.UpdateReader(original, reader)
 
 	c.JSON(http.StatusOK, reader)
 }
@@ -164,10 +255,19 @@ func (h *ReaderHandler) DeleteReader(c *gin.Context) {
 		return
 	}
 
-	if err := database.DB.Delete(&reader).Error; err != nil {
+	// Waiting holds of the reader would otherwise keep copies from everyone behind them
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Hold{}).Where("reader_id = ? AND status = ?", reader.ID, models.HoldWaiting).
+			UpdateColumns(map[string]interface{}{"status": models.HoldCancelled, "cancelled_at": time.Now(), "updated_at": time.Now()}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&reader).Error
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete reader"})
 		return
 	}
+	suggest.RemoveReader(reader)
 
 	c.JSON(http.StatusOK, gin.H{"message": "Reader deleted successfully"})
 }
\ No newline at end of file
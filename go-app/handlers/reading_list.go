@@ -0,0 +1,482 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"library-go/circulation"
+	"library-go/database"
+	"library-go/models"
+	"library-go/query"
+)
+
+type ReadingListHandler struct{}
+
+// readingListFields lists the reading list fields usable in filters, sort and field selection
+var readingListFields = query.Resource{
+	"id":            {Type: query.Int, Filter: true, Sort: true},
+	"title":         {Type: query.String, Filter: true, Sort: true},
+	"is_published":  {Type: query.Bool, Filter: true, Sort: true},
+	"created_by_id": {Type: query.Int, Filter: true, Sort: true},
+	"created_at":    {Type: query.Time, Filter: true, Sort: true},
+	"updated_at":    {Type: query.Time, Filter: true, Sort: true},
+}
+
+// readingListEntryView is a list entry with the current availability of its book
+type readingListEntryView struct {
+	models.ReadingListEntry
+	AvailableCopies int  `json:"available_copies"`
+	Available       bool `json:"available"`
+}
+
+// readingListView is a reading list with its entries in order
+type readingListView struct {
+	models.ReadingList
+	Entries []readingListEntryView `json:"entries"`
+}
+
+func NewReadingListHandler() *ReadingListHandler {
+	return &ReadingListHandler{}
+}
+
+// GetReadingLists retrieves all reading lists without their entries
+func (h *ReadingListHandler) GetReadingLists(c *gin.Context) {
+	var lists []models.ReadingList
+
+	page, ok := parsePage(c, query.DefaultLimit)
+	if !ok {
+		return
+	}
+	params, ok := parseListParams(c, readingListFields)
+	if !ok {
+		return
+	}
+
+	var total int64
+	if err := database.DB.Model(&models.ReadingList{}).Scopes(params.FilterScope()).Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reading lists"})
+		return
+	}
+
+	if err := database.DB.Scopes(params.Scope("id")).Offset(page.Offset()).Limit(page.Limit).Find(&lists).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reading lists"})
+		return
+	}
+
+	respondPage(c, params, page, total, lists)
+}
+
+// GetReadingList retrieves a reading list with its books in order and their live availability
+func (h *ReadingListHandler) GetReadingList(c *gin.Context) {
+	list, ok := h.findList(c)
+	if !ok {
+		return
+	}
+
+	h.respondList(c, http.StatusOK, list)
+}
+
+// CreateReadingList creates a new, empty reading list owned by the current user
+func (h *ReadingListHandler) CreateReadingList(c *gin.Context) {
+	var input struct {
+		Title       string  `json:"title" binding:"required"`
+		Description *string `json:"description"`
+		IsPublished bool    `json:"is_published"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	list := models.ReadingList{
+		Title:       input.Title,
+		Description: input.Description,
+		IsPublished: input.IsPublished,
+		CreatedByID: c.MustGet("user").(models.User).ID,
+	}
+	if err := list.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := database.DB.Create(&list).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create reading list"})
+		return
+	}
+
+	h.respondList(c, http.StatusCreated, &list)
+}
+
+// UpdateReadingList updates the title, description or publication of a reading list
+func (h *ReadingListHandler) UpdateReadingList(c *gin.Context) {
+	list, ok := h.findList(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Title       *string `json:"title"`
+		Description *string `json:"description"`
+		IsPublished *bool   `json:"is_published"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if input.Title != nil {
+		list.Title = *input.Title
+	}
+	if input.Description != nil {
+		list.Description = input.Description
+	}
+	if input.IsPublished != nil {
+		list.IsPublished = *input.IsPublished
+	}
+
+	if err := list.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := database.DB.Omit("Entries").Save(list).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update reading list"})
+		return
+	}
+
+	h.respondList(c, http.StatusOK, list)
+}
+
+// DeleteReadingList deletes a reading list and its entries
+func (h *ReadingListHandler) DeleteReadingList(c *gin.Context) {
+	list, ok := h.findList(c)
+	if !ok {
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("reading_list_id = ?", list.ID).Delete(&models.ReadingListEntry{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(list).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete reading list"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Reading list deleted successfully"})
+}
+
+// AddEntry adds a book to a reading list, at the end unless a position is given
+func (h *ReadingListHandler) AddEntry(c *gin.Context) {
+	list, ok := h.findList(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		BookID   uint    `json:"book_id" binding:"required"`
+		Note     *string `json:"note"`
+		Position *int    `json:"position"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var book models.Book
+	if err := database.DB.First(&book, input.BookID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Book not found"})
+		return
+	}
+
+	var existing int64
+	database.DB.Model(&models.ReadingListEntry{}).Where("reading_list_id = ? AND book_id = ?", list.ID, book.ID).Count(&existing)
+	if existing > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Book is already on this reading list"})
+		return
+	}
+
+	entry := models.ReadingListEntry{ReadingListID: list.ID, BookID: book.ID, Note: input.Note}
+	if err := entry.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.ReadingListEntry{}).Where("reading_list_id = ?", list.ID).Count(&count).Error; err != nil {
+			return err
+		}
+		entry.Position = clampPosition(input.Position, int(count))
+
+		// Make room for the new entry
+		if err := tx.Model(&models.ReadingListEntry{}).
+			Where("reading_list_id = ? AND position >= ?", list.ID, entry.Position).
+			UpdateColumn("position", gorm.Expr("position + 1")).Error; err != nil {
+			return err
+		}
+		if err := tx.Omit("Book").Create(&entry).Error; err != nil {
+			return err
+		}
+		return touchList(tx, list)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add book to reading list"})
+		return
+	}
+
+	h.respondList(c, http.StatusCreated, list)
+}
+
+// UpdateEntry changes the note of an entry or moves it to another position
+func (h *ReadingListHandler) UpdateEntry(c *gin.Context) {
+	list, ok := h.findList(c)
+	if !ok {
+		return
+	}
+	entry, ok := h.findEntry(c, list)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Note     *string `json:"note"`
+		Position *int    `json:"position"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if input.Note != nil {
+		entry.Note = input.Note
+	}
+	if err := entry.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if input.Position != nil {
+			var ids []uint
+			if err := tx.Model(&models.ReadingListEntry{}).Where("reading_list_id = ? AND id <> ?", list.ID, entry.ID).
+				Order("position, id").Pluck("id", &ids).Error; err != nil {
+				return err
+			}
+			position := clampPosition(input.Position, len(ids))
+			ordered := append(append(append([]uint{}, ids[:position]...), entry.ID), ids[position:]...)
+			if err := renumberEntries(tx, ordered); err != nil {
+				return err
+			}
+			entry.Position = position
+		}
+		if err := tx.Omit("Book").Save(entry).Error; err != nil {
+			return err
+		}
+		return touchList(tx, list)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update reading list entry"})
+		return
+	}
+
+	h.respondList(c, http.StatusOK, list)
+}
+
+// RemoveEntry removes a book from a reading list, closing the gap it leaves
+func (h *ReadingListHandler) RemoveEntry(c *gin.Context) {
+	list, ok := h.findList(c)
+	if !ok {
+		return
+	}
+	entry, ok := h.findEntry(c, list)
+	if !ok {
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(entry).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.ReadingListEntry{}).
+			Where("reading_list_id = ? AND position > ?", list.ID, entry.Position).
+			UpdateColumn("position", gorm.Expr("position - 1")).Error; err != nil {
+			return err
+		}
+		return touchList(tx, list)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove book from reading list"})
+		return
+	}
+
+	h.respondList(c, http.StatusOK, list)
+}
+
+// ReorderEntries sets the order of a reading list from the complete list of its entry IDs
+func (h *ReadingListHandler) ReorderEntries(c *gin.Context) {
+	list, ok := h.findList(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		EntryIDs []uint `json:"entry_ids" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var ids []uint
+	if err := database.DB.Model(&models.ReadingListEntry{}).Where("reading_list_id = ?", list.ID).Pluck("id", &ids).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reorder reading list"})
+		return
+	}
+
+	current := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		current[id] = true
+	}
+	if len(uniqueIDs(input.EntryIDs)) != len(input.EntryIDs) || len(input.EntryIDs) != len(ids) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "entry_ids must list every entry of the reading list exactly once"})
+		return
+	}
+	for _, id := range input.EntryIDs {
+		if !current[id] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "entry_ids must list every entry of the reading list exactly once"})
+			return
+		}
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := renumberEntries(tx, input.EntryIDs); err != nil {
+			return err
+		}
+		return touchList(tx, list)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reorder reading list"})
+		return
+	}
+
+	h.respondList(c, http.StatusOK, list)
+}
+
+// findList loads the reading list named by the id parameter, writing an error response on failure
+func (h *ReadingListHandler) findList(c *gin.Context) (*models.ReadingList, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid reading list ID"})
+		return nil, false
+	}
+
+	var list models.ReadingList
+	if err := database.DB.First(&list, uint(id)).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Reading list not found"})
+		return nil, false
+	}
+	return &list, true
+}
+
+// findEntry loads the entry named by the entry_id parameter within a list
+func (h *ReadingListHandler) findEntry(c *gin.Context, list *models.ReadingList) (*models.ReadingListEntry, bool) {
+	id, err := strconv.ParseUint(c.Param("entry_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid entry ID"})
+		return nil, false
+	}
+
+	var entry models.ReadingListEntry
+	if err := database.DB.Where("reading_list_id = ?", list.ID).First(&entry, uint(id)).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Reading list entry not found"})
+		return nil, false
+	}
+	return &entry, true
+}
+
+// respondList writes a reading list with its entries and the availability of their books
+func (h *ReadingListHandler) respondList(c *gin.Context, status int, list *models.ReadingList) {
+	var entries []models.ReadingListEntry
+	if err := database.DB.Where("reading_list_id = ?", list.ID).Order("position, id").
+		Preload("Book", withContributors).Find(&entries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reading list"})
+		return
+	}
+
+	books := make([]models.Book, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Book != nil {
+			books = append(books, *entry.Book)
+		}
+	}
+	available, err := circulation.AvailableCopies(database.DB, books)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reading list"})
+		return
+	}
+
+	view := readingListView{ReadingList: *list, Entries: make([]readingListEntryView, 0, len(entries))}
+	for _, entry := range entries {
+		copies := available[entry.BookID]
+		view.Entries = append(view.Entries, readingListEntryView{ReadingListEntry: entry, AvailableCopies: copies, Available: copies > 0})
+	}
+
+	c.JSON(status, view)
+}
+
+// removeBookFromLists drops a book from every reading list, closing the gaps it leaves
+func removeBookFromLists(tx *gorm.DB, bookID uint) error {
+	var entries []models.ReadingListEntry
+	if err := tx.Where("book_id = ?", bookID).Find(&entries).Error; err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err := tx.Delete(&entry).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.ReadingListEntry{}).
+			Where("reading_list_id = ? AND position > ?", entry.ReadingListID, entry.Position).
+			UpdateColumn("position", gorm.Expr("position - 1")).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// renumberEntries stores the given order as consecutive positions
+func renumberEntries(tx *gorm.DB, ids []uint) error {
+	for position, id := range ids {
+		if err := tx.Model(&models.ReadingListEntry{}).Where("id = ?", id).UpdateColumn("position", position).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// touchList bumps the UpdatedAt of a list after its entries changed
+func touchList(tx *gorm.DB, list *models.ReadingList) error {
+	return tx.Model(list).Omit("Entries").Update("updated_at", time.Now()).Error
+}
+
+// clampPosition returns the requested position limited to [0, count], defaulting to the end
+func clampPosition(position *int, count int) int {
+	if position == nil || *position > count {
+		return count
+	}
+	if *position < 0 {
+		return 0
+	}
+	return *position
+}
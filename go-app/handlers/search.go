@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"library-go/database"
+	"library-go/models"
+	"library-go/search"
+)
+
+type SearchHandler struct{}
+
+func NewSearchHandler() *SearchHandler {
+	return &SearchHandler{}
+}
+
+// GetStatus reports the active search backend, the last reindex and, for
+// external backends, a consistency check against the Book table
+func (h *SearchHandler) GetStatus(c *gin.Context) {
+	response := gin.H{
+		"backend": search.ActiveBackend(),
+		"reindex": search.CurrentReindex(),
+	}
+
+	report, err := search.Check(database.DB)
+	switch {
+	case errors.Is(err, search.ErrNoBackend):
+		// The database keeps its own full-text index in sync
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check search index"})
+		return
+	default:
+		response["consistency"] = report
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// Reindex starts rebuilding the external search index in the background
+func (h *SearchHandler) Reindex(c *gin.Context) {
+	err := search.ReindexInBackground(database.DB)
+	switch {
+	case errors.Is(err, search.ErrNoBackend):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The database full-text index does not need reindexing"})
+		return
+	case errors.Is(err, search.ErrReindexRunning):
+		c.JSON(http.StatusConflict, gin.H{"error": "A reindex is already running"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start reindex"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Reindex started"})
+}
+
+// hitBooks reloads the books of search hits with the given preloads, in rank order
+func hitBooks(hits []search.Hit, scopes ...func(*gorm.DB) *gorm.DB) ([]models.Book, error) {
+	ids := make([]uint, len(hits))
+	for i, hit := range hits {
+		ids[i] = hit.Book.ID
+	}
+	books := make([]models.Book, 0, len(ids))
+	if len(ids) == 0 {
+		return books, nil
+	}
+
+	var loaded []models.Book
+	if err := database.DB.Scopes(scopes...).Where("id IN ?", ids).Find(&loaded).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]models.Book, len(loaded))
+	for _, book := range loaded {
+		byID[book.ID] = book
+	}
+	for _, id := range ids {
+		if book, ok := byID[id]; ok {
+			books = append(books, book)
+		}
+	}
+	return books, nil
+}
@@ -0,0 +1,285 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"library-go/database"
+	"library-go/models"
+	"library-go/query"
+)
+
+type SeriesHandler struct{}
+
+// seriesFields lists the series fields usable in filters, sort and field selection
+var seriesFields = query.Resource{
+	"id":          {Type: query.Int, Filter: true, Sort: true},
+	"name":        {Type: query.String, Filter: true, Sort: true},
+	"description": {Type: query.String, Filter: true},
+	"created_at":  {Type: query.Time, Filter: true, Sort: true},
+	"updated_at":  {Type: query.Time, Filter: true, Sort: true},
+	"entries":     {},
+}
+
+func NewSeriesHandler() *SeriesHandler {
+	return &SeriesHandler{}
+}
+
+// GetSeries retrieves all series without their works
+func (h *SeriesHandler) GetSeries(c *gin.Context) {
+	var series []models.Series
+
+	page, ok := parsePage(c, query.DefaultLimit)
+	if !ok {
+		return
+	}
+	params, ok := parseListParams(c, seriesFields)
+	if !ok {
+		return
+	}
+
+	var total int64
+	if err := database.DB.Model(&models.Series{}).Scopes(params.FilterScope()).Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch series"})
+		return
+	}
+
+	if err := database.DB.Scopes(params.Scope("name")).Offset(page.Offset()).Limit(page.Limit).Find(&series).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch series"})
+		return
+	}
+
+	respondPage(c, params, page, total, series)
+}
+
+// GetSeriesByID retrieves a series with its works in volume order
+func (h *SeriesHandler) GetSeriesByID(c *gin.Context) {
+	series, ok := findSeries(c, withSeriesEntries)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, series)
+}
+
+// CreateSeries creates a new, empty series
+func (h *SeriesHandler) CreateSeries(c *gin.Context) {
+	var input struct {
+		Name        string  `json:"name" binding:"required"`
+		Description *string `json:"description"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	series := models.Series{Name: input.Name, Description: input.Description}
+	if err := series.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := database.DB.Omit(clause.Associations).Create(&series).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create series"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, series)
+}
+
+// UpdateSeries updates the name or description of a series
+func (h *SeriesHandler) UpdateSeries(c *gin.Context) {
+	series, ok := findSeries(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Name        *string `json:"name"`
+		Description *string `json:"description"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if input.Name != nil {
+		series.Name = *input.Name
+	}
+	if input.Description != nil {
+		series.Description = input.Description
+	}
+	if err := series.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := database.DB.Omit(clause.Associations).Save(series).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update series"})
+		return
+	}
+
+	c.JSON(http.StatusOK, series)
+}
+
+// DeleteSeries deletes a series; its works are kept
+func (h *SeriesHandler) DeleteSeries(c *gin.Context) {
+	series, ok := findSeries(c)
+	if !ok {
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("series_id = ?", series.ID).Delete(&models.SeriesEntry{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(series).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete series"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Series deleted successfully"})
+}
+
+// SetSeriesWork adds a work to a series or changes its volume number. A book
+// may be given instead of a work; one is created for it if it has none yet.
+func (h *SeriesHandler) SetSeriesWork(c *gin.Context) {
+	series, ok := findSeries(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		WorkID *uint    `json:"work_id"`
+		BookID *uint    `json:"book_id"`
+		Volume *float64 `json:"volume"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if (input.WorkID == nil) == (input.BookID == nil) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Either work_id or book_id is required"})
+		return
+	}
+
+	entry := models.SeriesEntry{SeriesID: series.ID, Volume: input.Volume}
+	if err := entry.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var book models.Book
+	if input.WorkID != nil {
+		if err := validateWorkID(input.WorkID); err != nil {
+			respondWorkError(c, err)
+			return
+		}
+		entry.WorkID = *input.WorkID
+	} else if err := database.DB.First(&book, *input.BookID).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown book"})
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if input.BookID != nil {
+			work, err := bookWork(tx, &book)
+			if err != nil {
+				return err
+			}
+			entry.WorkID = work.ID
+		}
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "series_id"}, {Name: "work_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"volume"}),
+		}).Create(&entry).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add work to series"})
+		return
+	}
+
+	database.DB.Scopes(withSeriesEntries).First(series, series.ID)
+
+	c.JSON(http.StatusOK, series)
+}
+
+// RemoveSeriesWork removes a work from a series
+func (h *SeriesHandler) RemoveSeriesWork(c *gin.Context) {
+	series, ok := findSeries(c)
+	if !ok {
+		return
+	}
+
+	workID, err := strconv.ParseUint(c.Param("work_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid work ID"})
+		return
+	}
+
+	result := database.DB.Where("series_id = ? AND work_id = ?", series.ID, uint(workID)).Delete(&models.SeriesEntry{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove work from series"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Work is not in this series"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Work removed from series successfully"})
+}
+
+// findSeries loads the series named by the id parameter, writing an error response on failure
+func findSeries(c *gin.Context, scopes ...func(*gorm.DB) *gorm.DB) (*models.Series, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid series ID"})
+		return nil, false
+	}
+
+	var series models.Series
+	if err := database.DB.Scopes(scopes...).First(&series, uint(id)).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Series not found"})
+		return nil, false
+	}
+	return &series, true
+}
+
+// bookWork returns the work of a book, creating one titled after the book if it has none
+func bookWork(tx *gorm.DB, book *models.Book) (*models.Work, error) {
+	var work models.Work
+	if book.WorkID != nil {
+		if err := tx.First(&work, *book.WorkID).Error; err != nil {
+			return nil, err
+		}
+		return &work, nil
+	}
+
+	work = models.Work{Title: book.Title}
+	if err := tx.Omit(clause.Associations).Create(&work).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Model(book).UpdateColumn("work_id", work.ID).Error; err != nil {
+		return nil, err
+	}
+	return &work, nil
+}
+
+// withSeriesEntries preloads the works of series in volume order, unnumbered works last
+func withSeriesEntries(db *gorm.DB) *gorm.DB {
+	return db.Preload("Entries", func(db *gorm.DB) *gorm.DB {
+		return db.Order("volume IS NULL, volume, id")
+	}).Preload("Entries.Work").Preload("Entries.Work.Editions", func(db *gorm.DB) *gorm.DB {
+		return db.Order("year, id")
+	})
+}
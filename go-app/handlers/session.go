@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"library-go/database"
+	"library-go/models"
+)
+
+// sessionResponse is a session as shown to its owner
+type sessionResponse struct {
+	models.Session
+	Current bool `json:"current"`
+}
+
+// GetSessions lists the active sessions of the current user
+func (h *AuthHandler) GetSessions(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+	current := c.MustGet("session").(models.Session)
+
+	sessions, err := activeSessions(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch sessions"})
+		return
+	}
+
+	response := make([]sessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		response = append(response, sessionResponse{Session: session, Current: session.ID == current.ID})
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// DeleteSession signs out a single session, admins may terminate sessions of any user
+func (h *AuthHandler) DeleteSession(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session ID"})
+		return
+	}
+
+	user := c.MustGet("user").(models.User)
+
+	var session models.Session
+	if err := database.DB.First(&session, uint(id)).Error; err != nil || (session.UserID != user.ID && !user.IsAdmin) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+		return
+	}
+
+	if session.RevokedAt != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Session is already terminated"})
+		return
+	}
+
+	if _, err := revokeSessions(database.DB.Where("id = ?", session.ID)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to terminate session"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Session terminated successfully"})
+}
+
+// GetUserSessions lists the active sessions of any user (admin only)
+func (h *AuthHandler) GetUserSessions(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, uint(id)).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	sessions, err := activeSessions(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch sessions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, sessions)
+}
+
+// DeleteUserSessions terminates every active session of a user (admin only)
+func (h *AuthHandler) DeleteUserSessions(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, uint(id)).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	terminated, err := revokeSessions(database.DB.Where("user_id = ?", user.ID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to terminate sessions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Sessions terminated successfully",
+		"terminated": terminated,
+	})
+}
+
+// activeSessions returns the non-revoked, non-expired sessions of a user, most recently used first
+func activeSessions(userID uint) ([]models.Session, error) {
+	var sessions []models.Session
+	err := database.DB.
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+// revokeSessions marks the sessions matched by the scoped query as revoked and returns how many were affected
+func revokeSessions(tx *gorm.DB) (int64, error) {
+	result := tx.Model(&models.Session{}).Where("revoked_at IS NULL").Update("revoked_at", time.Now())
+	return result.RowsAffected, result.Error
+}
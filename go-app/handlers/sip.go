@@ -0,0 +1,540 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"library-go/circulation"
+	"library-go/config"
+	"library-go/database"
+	"library-go/models"
+	"library-go/sip2"
+)
+
+// SIPHandler answers self-check machines over SIP2 with the circulation
+// rules of the API. Patrons are identified by reader ID and items by book ID
+// or ISBN. Readers have no PIN, so patron passwords are refused unless
+// SIP_TRUST_PATRON_PASSWORDS is set.
+type SIPHandler struct{}
+
+// sipSupportedMessages flags, in the order of the specification, the
+// messages answered: patron status, checkout, checkin, SC/ACS status, resend,
+// login, patron information, end session, fee paid, item information and renew
+const sipSupportedMessages = "YYYNYYYYYYYNNNYN"
+
+// sipNoBlocks is the patron status of a patron with no privileges denied
+var sipNoBlocks = strings.Repeat(" ", 14)
+
+const sipSystemError = "The library system is unavailable, please ask at the desk"
+
+func NewSIPHandler() *SIPHandler {
+	return &SIPHandler{}
+}
+
+// ServeSIP answers a request, or returns nil for a message that is not supported
+func (h *SIPHandler) ServeSIP(s *sip2.Session, req *sip2.Message) *sip2.Message {
+	switch req.Code {
+	case sip2.Login:
+		return h.login(s, req)
+	case sip2.SCStatus:
+		return h.status(s, req)
+	case sip2.PatronStatusRequest:
+		return h.patronStatus(req)
+	case sip2.PatronInformation:
+		return h.patronInformation(req)
+	case sip2.Checkout:
+		return h.checkout(req)
+	case sip2.Checkin:
+		return h.checkin(req)
+	case sip2.Renew:
+		return h.renew(req)
+	case sip2.FeePaid:
+		return h.feePaid(req)
+	case sip2.ItemInformation:
+		return h.itemInformation(req)
+	case sip2.EndPatronSession:
+		return h.endSession(req)
+	}
+	return nil
+}
+
+// login authenticates the machine as an active user of the API
+func (h *SIPHandler) login(s *sip2.Session, req *sip2.Message) *sip2.Message {
+	var user models.User
+	err := database.DB.Where("email = ?", req.Get(sip2.FieldLoginUserID)).First(&user).Error
+	ok := err == nil && user.IsActive && user.CheckPassword(req.Get(sip2.FieldLoginPassword))
+	if !ok {
+		log.Println("SIP2 login failed for", req.Get(sip2.FieldLoginUserID), "from", s.RemoteAddr)
+	}
+
+	s.Authenticated = ok
+	s.UserID = req.Get(sip2.FieldLoginUserID)
+	s.Location = req.Get(sip2.FieldLocationCode)
+	return sip2.NewMessage(sip2.LoginResponse, sip2.Digit(ok))
+}
+
+// status reports what the server supports
+func (h *SIPHandler) status(s *sip2.Session, req *sip2.Message) *sip2.Message {
+	res := sip2.NewMessage(sip2.ACSStatus,
+		"Y",   // On-line
+		"Y",   // Checkin ok
+		"Y",   // Checkout ok
+		"Y",   // Renewal policy
+		"N",   // Status update ok
+		"N",   // Off-line ok
+		"030", // Timeout period
+		"003", // Retries allowed
+		sip2.FormatDate(time.Now()),
+		"2.00",
+	)
+	res.Add(sip2.FieldInstitutionID, config.SIPInstitutionID)
+	res.AddOptional(sip2.FieldLibraryName, config.OAIRepositoryName)
+	res.Add(sip2.FieldSupportedMessages, sipSupportedMessages)
+	res.AddOptional(sip2.FieldTerminalLocation, s.Location)
+	return res
+}
+
+// patronStatus reports whether a patron is known
+func (h *SIPHandler) patronStatus(req *sip2.Message) *sip2.Message {
+	reader, err := sipReader(req.Get(sip2.FieldPatronID))
+	res := sip2.NewMessage(sip2.PatronStatusResponse, sipNoBlocks, req.FixedField(0, 3), sip2.FormatDate(time.Now()))
+	res.Add(sip2.FieldInstitutionID, sipInstitution(req))
+	res.Add(sip2.FieldPatronID, req.Get(sip2.FieldPatronID))
+	res.Add(sip2.FieldPersonalName, readerName(reader))
+	res.Add(sip2.FieldValidPatron, sip2.Bool(reader != nil))
+	if req.Has(sip2.FieldPatronPassword) {
+		res.Add(sip2.FieldValidPassword, sip2.Bool(reader != nil && config.SIPTrustPatronPasswords))
+	}
+	return res.AddOptional(sip2.FieldScreenMessage, sipPatronMessage(reader, err))
+}
+
+// patronInformation reports a patron's loans and holds, listing the kinds of
+// items the summary asks for
+func (h *SIPHandler) patronInformation(req *sip2.Message) *sip2.Message {
+	reader, err := sipReader(req.Get(sip2.FieldPatronID))
+	var loans []models.Borrow
+	var holds []models.Hold
+	if reader != nil {
+		err = database.DB.Where("reader_id = ? AND is_returned = ?", reader.ID, false).Order("borrowed_at, id").Find(&loans).Error
+		if err == nil {
+			err = database.DB.Preload("Work").Where("reader_id = ? AND status = ?", reader.ID, models.HoldWaiting).Order("id").Find(&holds).Error
+		}
+		if err != nil {
+			reader, loans, holds = nil, nil, nil
+		}
+	}
+
+	now := time.Now()
+	var charged, overdue, unavailable []string
+	for _, loan := range loans {
+		id := strconv.FormatUint(uint64(loan.BookID), 10)
+		charged = append(charged, id)
+		if circulation.DueDate(&loan, config.SIPLoanDays).Before(now) {
+			overdue = append(overdue, id)
+		}
+	}
+	for _, hold := range holds {
+		// A hold on a work is for no one item, so it is listed by title
+		if hold.BookID != nil {
+			unavailable = append(unavailable, strconv.FormatUint(uint64(*hold.BookID), 10))
+		} else if hold.Work != nil {
+			unavailable = append(unavailable, hold.Work.Title)
+		}
+	}
+
+	res := sip2.NewMessage(sip2.PatronInformationResponse, sipNoBlocks, req.FixedField(0, 3), sip2.FormatDate(now),
+		sip2.Count(0, 4), // Hold items, as holds are never kept ready for pickup
+		sip2.Count(len(overdue), 4),
+		sip2.Count(len(charged), 4),
+		sip2.Count(0, 4), // Fine items
+		sip2.Count(0, 4), // Recall items
+		sip2.Count(len(unavailable), 4),
+	)
+	res.Add(sip2.FieldInstitutionID, sipInstitution(req))
+	res.Add(sip2.FieldPatronID, req.Get(sip2.FieldPatronID))
+	res.Add(sip2.FieldPersonalName, readerName(reader))
+	res.Add(sip2.FieldValidPatron, sip2.Bool(reader != nil))
+	if req.Has(sip2.FieldPatronPassword) {
+		res.Add(sip2.FieldValidPassword, sip2.Bool(reader != nil && config.SIPTrustPatronPasswords))
+	}
+
+	// The summary has a Y at the position of the one list wanted
+	summary := req.FixedField(21, 10)
+	lists := map[int]struct {
+		field string
+		items []string
+	}{
+		1: {sip2.FieldOverdueItems, overdue},
+		2: {sip2.FieldChargedItems, charged},
+		5: {sip2.FieldUnavailableHolds, unavailable},
+	}
+	if list, ok := lists[strings.IndexByte(summary, 'Y')]; ok {
+		for _, item := range sipItemRange(req, list.items) {
+			res.Add(list.field, item)
+		}
+	}
+
+	if reader != nil {
+		res.AddOptional(sip2.FieldHomeAddress, derefString(reader.Address))
+		res.AddOptional(sip2.FieldEmail, derefString(reader.Email))
+		res.AddOptional(sip2.FieldPhone, derefString(reader.Phone))
+	}
+	return res.AddOptional(sip2.FieldScreenMessage, sipPatronMessage(reader, err))
+}
+
+// sipItemRange returns the items between the start and end positions requested, counted from 1
+func sipItemRange(req *sip2.Message, items []string) []string {
+	start, err := strconv.Atoi(req.Get(sip2.FieldStartItem))
+	if err != nil || start < 1 {
+		start = 1
+	}
+	end, err := strconv.Atoi(req.Get(sip2.FieldEndItem))
+	if err != nil || end > len(items) {
+		end = len(items)
+	}
+	if start > end {
+		return nil
+	}
+	return items[start-1 : end]
+}
+
+// checkout lends an item to a patron. When the patron already has it and
+// the machine allows renewals, the loan is renewed instead.
+func (h *SIPHandler) checkout(req *sip2.Message) *sip2.Message {
+	reader, readerErr := sipReader(req.Get(sip2.FieldPatronID))
+	book, bookErr := sipBook(req.Get(sip2.FieldItemID))
+
+	var borrow *models.Borrow
+	renewed := false
+	message := sipPatronMessage(reader, readerErr)
+	if message == "" {
+		message = sipPasswordMessage(req)
+	}
+	if message == "" {
+		message = sipItemMessage(book, bookErr)
+	}
+	if message == "" {
+		err := database.DB.Transaction(func(tx *gorm.DB) error {
+			loan, err := circulation.Loan(tx, book, reader.ID)
+			if errors.Is(err, circulation.ErrNotOnLoan) {
+				borrow, err = circulation.Checkout(tx, book, reader.ID)
+				return err
+			}
+			if err != nil {
+				return err
+			}
+			if req.FixedField(0, 1) != "Y" {
+				return errAlreadyOnLoan
+			}
+			renewed = true
+			borrow, err = circulation.Renew(tx, loan)
+			return err
+		})
+		message = sipCirculationMessage(err)
+	}
+
+	ok := message == ""
+	res := sip2.NewMessage(sip2.CheckoutResponse, sip2.Digit(ok), sip2.Bool(ok && renewed), "N", sip2.Bool(ok), sip2.FormatDate(time.Now()))
+	return sipLoanFields(res, req, book, borrow, message)
+}
+
+// errAlreadyOnLoan is returned when a patron checks out an item they have and renewals are not allowed
+var errAlreadyOnLoan = errors.New("item already on loan to the patron")
+
+// renew extends a patron's loan of an item
+func (h *SIPHandler) renew(req *sip2.Message) *sip2.Message {
+	reader, readerErr := sipReader(req.Get(sip2.FieldPatronID))
+	book, bookErr := sipBook(req.Get(sip2.FieldItemID))
+
+	var borrow *models.Borrow
+	message := sipPatronMessage(reader, readerErr)
+	if message == "" {
+		message = sipPasswordMessage(req)
+	}
+	if message == "" {
+		message = sipItemMessage(book, bookErr)
+	}
+	if message == "" {
+		err := database.DB.Transaction(func(tx *gorm.DB) error {
+			loan, err := circulation.Loan(tx, book, reader.ID)
+			if err != nil {
+				return err
+			}
+			borrow, err = circulation.Renew(tx, loan)
+			return err
+		})
+		message = sipCirculationMessage(err)
+	}
+
+	ok := message == ""
+	res := sip2.NewMessage(sip2.RenewResponse, sip2.Digit(ok), sip2.Bool(ok), "N", sip2.Bool(ok), sip2.FormatDate(time.Now()))
+	return sipLoanFields(res, req, book, borrow, message)
+}
+
+// sipLoanFields adds the fields shared by the checkout and renew responses
+func sipLoanFields(res, req *sip2.Message, book *models.Book, borrow *models.Borrow, message string) *sip2.Message {
+	res.Add(sip2.FieldInstitutionID, sipInstitution(req))
+	res.Add(sip2.FieldPatronID, req.Get(sip2.FieldPatronID))
+	res.Add(sip2.FieldItemID, req.Get(sip2.FieldItemID))
+	res.Add(sip2.FieldTitle, bookTitle(book))
+	dueDate := ""
+	if borrow != nil {
+		dueDate = sip2.FormatDate(circulation.DueDate(borrow, config.SIPLoanDays))
+	}
+	res.Add(sip2.FieldDueDate, dueDate)
+	if book != nil {
+		res.Add(sip2.FieldMediaType, sipMediaType(book.Format))
+	}
+	return res.AddOptional(sip2.FieldScreenMessage, message)
+}
+
+// checkin returns an item, alerting the machine when a reader is waiting for it
+func (h *SIPHandler) checkin(req *sip2.Message) *sip2.Message {
+	book, err := sipBook(req.Get(sip2.FieldItemID))
+	message := sipItemMessage(book, err)
+
+	// Items returned while the machine was off-line carry their return date
+	returnedAt := time.Now()
+	if req.FixedField(0, 1) == "Y" {
+		if at, err := sip2.ParseDate(req.FixedField(19, 18)); err == nil {
+			returnedAt = at
+		}
+	}
+
+	var borrow *models.Borrow
+	var waiting int64
+	if message == "" {
+		err = database.DB.Transaction(func(tx *gorm.DB) error {
+			var err error
+			if borrow, err = circulation.Loan(tx, book, 0); err != nil {
+				return err
+			}
+			return circulation.Return(tx, borrow, returnedAt)
+		})
+		if err == nil {
+			waiting, err = sipHoldQueue(book)
+		}
+		message = sipCirculationMessage(err)
+	}
+
+	ok := message == ""
+	alert := ok && waiting > 0
+	res := sip2.NewMessage(sip2.CheckinResponse, sip2.Digit(ok), sip2.Bool(ok), "N", sip2.Bool(alert), sip2.FormatDate(time.Now()))
+	res.Add(sip2.FieldInstitutionID, sipInstitution(req))
+	res.Add(sip2.FieldItemID, req.Get(sip2.FieldItemID))
+	res.Add(sip2.FieldPermanentLocation, config.SIPInstitutionID)
+	res.AddOptional(sip2.FieldTitle, bookTitle(book))
+	if borrow != nil {
+		res.Add(sip2.FieldPatronID, strconv.FormatUint(uint64(borrow.ReaderID), 10))
+	}
+	if book != nil {
+		res.Add(sip2.FieldMediaType, sipMediaType(book.Format))
+	}
+	if alert {
+		res.Add(sip2.FieldAlertType, "01") // Hold for this library
+		message = "Reserved for a waiting reader, place on the hold shelf"
+	}
+	return res.AddOptional(sip2.FieldScreenMessage, message)
+}
+
+// feePaid declines payments, as the library charges no fees
+func (h *SIPHandler) feePaid(req *sip2.Message) *sip2.Message {
+	res := sip2.NewMessage(sip2.FeePaidResponse, "N", sip2.FormatDate(time.Now()))
+	res.Add(sip2.FieldInstitutionID, sipInstitution(req))
+	res.Add(sip2.FieldPatronID, req.Get(sip2.FieldPatronID))
+	return res.Add(sip2.FieldScreenMessage, "The library charges no fees")
+}
+
+// itemInformation reports whether an item is on the shelf and how many readers wait for it
+func (h *SIPHandler) itemInformation(req *sip2.Message) *sip2.Message {
+	book, err := sipBook(req.Get(sip2.FieldItemID))
+	message := sipItemMessage(book, err)
+
+	status := "01" // Other
+	var waiting int64
+	var due time.Time
+	if message == "" {
+		available, err := circulation.AvailableCopies(database.DB, []models.Book{*book})
+		if err == nil {
+			waiting, err = sipHoldQueue(book)
+		}
+		if err == nil && available[book.ID] == 0 {
+			// Charged until the first copy is due back
+			var loan *models.Borrow
+			if loan, err = circulation.Loan(database.DB, book, 0); err == nil {
+				due = circulation.DueDate(loan, config.SIPLoanDays)
+			} else if errors.Is(err, circulation.ErrNotOnLoan) {
+				err = nil
+			}
+		}
+		message = sipCirculationMessage(err)
+		switch {
+		case message != "":
+		case available[book.ID] > 0:
+			status = "03" // Available
+		default:
+			status = "04" // Charged
+		}
+	}
+
+	res := sip2.NewMessage(sip2.ItemInformationResponse, status, "00", "01", sip2.FormatDate(time.Now()))
+	if waiting > 0 {
+		res.Add(sip2.FieldHoldQueueLength, strconv.FormatInt(waiting, 10))
+	}
+	if !due.IsZero() {
+		res.Add(sip2.FieldDueDate, sip2.FormatDate(due))
+	}
+	res.Add(sip2.FieldItemID, req.Get(sip2.FieldItemID))
+	res.Add(sip2.FieldTitle, bookTitle(book))
+	if book != nil {
+		res.Add(sip2.FieldMediaType, sipMediaType(book.Format))
+		res.Add(sip2.FieldPermanentLocation, config.SIPInstitutionID)
+	}
+	return res.AddOptional(sip2.FieldScreenMessage, message)
+}
+
+// endSession acknowledges the end of a patron's session, which holds no state
+func (h *SIPHandler) endSession(req *sip2.Message) *sip2.Message {
+	res := sip2.NewMessage(sip2.EndSessionResponse, "Y", sip2.FormatDate(time.Now()))
+	res.Add(sip2.FieldInstitutionID, sipInstitution(req))
+	return res.Add(sip2.FieldPatronID, req.Get(sip2.FieldPatronID))
+}
+
+// sipReader finds the reader with the patron identifier, nil when there is none
+func sipReader(id string) (*models.Reader, error) {
+	readerID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		return nil, nil
+	}
+	var reader models.Reader
+	if err := database.DB.First(&reader, uint(readerID)).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &reader, nil
+}
+
+// sipBook finds the book with the item identifier, an ISBN or a book ID, nil when there is none
+func sipBook(id string) (*models.Book, error) {
+	query := database.DB
+	if isbn, err := models.NormalizeISBN(id); err == nil {
+		query = query.Where("isbn = ?", isbn)
+	} else if bookID, err := strconv.ParseUint(id, 10, 32); err == nil {
+		query = query.Where("id = ?", uint(bookID))
+	} else {
+		return nil, nil
+	}
+
+	var book models.Book
+	if err := query.First(&book).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &book, nil
+}
+
+// sipHoldQueue counts the waiting holds on a book or on its work
+func sipHoldQueue(book *models.Book) (int64, error) {
+	query := database.DB.Model(&models.Hold{}).Where("status = ?", models.HoldWaiting)
+	if book.WorkID != nil {
+		query = query.Where("book_id = ? OR work_id = ?", book.ID, *book.WorkID)
+	} else {
+		query = query.Where("book_id = ?", book.ID)
+	}
+	var count int64
+	err := query.Count(&count).Error
+	return count, err
+}
+
+func sipInstitution(req *sip2.Message) string {
+	if id := req.Get(sip2.FieldInstitutionID); id != "" {
+		return id
+	}
+	return config.SIPInstitutionID
+}
+
+// sipPatronMessage explains a failed patron lookup, empty when the patron was found
+func sipPatronMessage(reader *models.Reader, err error) string {
+	if err != nil {
+		log.Println("SIP2 patron lookup failed:", err)
+		return sipSystemError
+	}
+	if reader == nil {
+		return "Patron not found"
+	}
+	return ""
+}
+
+// sipPasswordMessage refuses a request with a patron password that cannot be
+// checked, empty when there is none or the machine is trusted. Machines that
+// do not ask for one send the field empty.
+func sipPasswordMessage(req *sip2.Message) string {
+	if req.Get(sip2.FieldPatronPassword) == "" || config.SIPTrustPatronPasswords {
+		return ""
+	}
+	return "Patron password cannot be verified, please ask at the desk"
+}
+
+// sipItemMessage explains a failed item lookup, empty when the item was found
+func sipItemMessage(book *models.Book, err error) string {
+	if err != nil {
+		log.Println("SIP2 item lookup failed:", err)
+		return sipSystemError
+	}
+	if book == nil {
+		return "Item not found"
+	}
+	return ""
+}
+
+// sipCirculationMessage explains why a circulation transaction failed, empty when it did not
+func sipCirculationMessage(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, circulation.ErrNoCopies):
+		return "No copies of this item are available"
+	case errors.Is(err, circulation.ErrReserved):
+		return "This item is reserved for another reader"
+	case errors.Is(err, circulation.ErrNotOnLoan):
+		return "This item is not checked out"
+	case errors.Is(err, errAlreadyOnLoan):
+		return "This item is already checked out to you"
+	}
+	log.Println("SIP2 transaction failed:", err)
+	return sipSystemError
+}
+
+// sipMediaType returns the SIP2 media type of a book format
+func sipMediaType(format string) string {
+	switch format {
+	case models.FormatPrint, models.FormatLargePrint:
+		return "001" // Book
+	case models.FormatAudiobook, models.FormatDVD:
+		return "006" // CD or CD-ROM, the closest to any disc
+	}
+	return "000" // Other
+}
+
+func readerName(reader *models.Reader) string {
+	if reader == nil {
+		return ""
+	}
+	return reader.FirstName + " " + reader.LastName
+}
+
+func bookTitle(book *models.Book) string {
+	if book == nil {
+		return ""
+	}
+	return book.Title
+}
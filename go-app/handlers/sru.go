@@ -0,0 +1,519 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/xml"
+	"log"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"library-go/config"
+	"library-go/cql"
+	"library-go/database"
+	"library-go/dublincore"
+	"library-go/marc"
+	"library-go/models"
+	"library-go/search"
+	"library-go/sru"
+)
+
+type SRUHandler struct{}
+
+// Number of records returned when the client does not ask, and at most
+const (
+	sruDefaultRecords = 10
+	sruMaximumRecords = 100
+)
+
+// Book fields the CQL indexes search
+const (
+	sruTitle      = "title"
+	sruAuthor     = "author"
+	sruISBN       = "isbn"
+	sruAnywhere   = "anywhere"
+	sruAllRecords = "all"
+)
+
+// sruIndexes maps the CQL indexes, qualified by context set or not, to the fields they search
+var sruIndexes = map[string]string{
+	"title":          sruTitle,
+	"dc.title":       sruTitle,
+	"bath.title":     sruTitle,
+	"author":         sruAuthor,
+	"dc.creator":     sruAuthor,
+	"bath.author":    sruAuthor,
+	"bath.name":      sruAuthor,
+	"isbn":           sruISBN,
+	"bath.isbn":      sruISBN,
+	"anywhere":       sruAnywhere,
+	"cql.anywhere":   sruAnywhere,
+	cql.ServerChoice: sruAnywhere,
+	"cql.allrecords": sruAllRecords,
+}
+
+// sruIndexTitles names the fields in the explain record, in this order
+var sruIndexTitles = []struct{ field, title string }{
+	{sruTitle, "Title"},
+	{sruAuthor, "Author"},
+	{sruISBN, "ISBN"},
+	{sruAnywhere, "Title, author, description or ISBN"},
+	{sruAllRecords, "All records"},
+}
+
+// sruColumns are the columns searched for each field, lower-cased for case-insensitive matching
+var sruColumns = map[string][]string{
+	sruTitle:    {"lower(books.title)"},
+	sruAuthor:   {"lower(books.author)"},
+	sruISBN:     {"replace(coalesce(books.isbn, ''), '-', '')"},
+	sruAnywhere: {"lower(books.title)", "lower(books.author)", "lower(coalesce(books.description, ''))", "replace(coalesce(books.isbn, ''), '-', '')"},
+}
+
+var sruContextSets = []sru.ContextSet{
+	{Name: "cql", Identifier: "info:srw/cql-context-set/1/cql-v1.2"},
+	{Name: "dc", Identifier: "info:srw/cql-context-set/1/dc-v1.1"},
+	{Name: "bath", Identifier: "http://zing.z3950.org/cql/bath/2.0/"},
+}
+
+var sruSchemas = []sru.Schema{
+	{Name: "dc", Identifier: "info:srw/schema/1/dc-v1.1", Title: "Dublin Core"},
+	{Name: "marcxml", Identifier: "info:srw/schema/1/marcxml-v1.1", Title: "MARC 21 in MARCXML"},
+}
+
+// sruParameters are the request parameters understood, besides x- extensions
+var sruParameters = map[string]bool{
+	"operation": true, "version": true, "query": true, "queryType": true,
+	"startRecord": true, "maximumRecords": true, "recordSchema": true,
+	"recordXMLEscaping": true, "recordPacking": true, "resultSetTTL": true,
+	"httpAccept": true, "sortKeys": true, "stylesheet": true,
+}
+
+func NewSRUHandler() *SRUHandler {
+	return &SRUHandler{}
+}
+
+// HandleRequest answers an SRU request: a searchRetrieve when there is a
+// query, explain otherwise. Diagnostics are part of the response, which is
+// always 200 OK.
+func (h *SRUHandler) HandleRequest(c *gin.Context) {
+	if err := c.Request.ParseForm(); err != nil {
+		h.respond(c, &sru.SearchRetrieveResponse{Diagnostics: []*sru.Diagnostic{sru.NewDiagnostic(sru.DiagGeneralError, "Malformed request")}})
+		return
+	}
+	form := c.Request.Form
+
+	operation := form.Get("operation")
+	if operation == "explain" || operation == "" && form.Get("query") == "" {
+		h.explain(c, sruCheckParameters(form))
+		return
+	}
+
+	response := &sru.SearchRetrieveResponse{Escaping: sru.EscapingXML}
+	diag := sruCheckParameters(form)
+	if diag == nil && operation != "" && operation != "searchRetrieve" {
+		diag = sru.NewDiagnostic(sru.DiagUnsupportedOperation, operation)
+	}
+	if diag == nil {
+		diag = h.searchRetrieve(c, response)
+	}
+	if diag != nil {
+		if diag.Code == sru.DiagGeneralError {
+			log.Println("SRU search failed:", diag.Details)
+			diag.Details = ""
+		}
+		response.Records, response.NextRecordPosition = nil, 0
+		response.Diagnostics = []*sru.Diagnostic{diag}
+	}
+	h.respond(c, response)
+}
+
+// sruCheckParameters rejects parameters and features the server does not support
+func sruCheckParameters(form map[string][]string) *sru.Diagnostic {
+	names := make([]string, 0, len(form))
+	for name := range form {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !sruParameters[name] && !strings.HasPrefix(name, "x-") {
+			return sru.NewDiagnostic(sru.DiagUnsupportedParameter, name)
+		}
+	}
+
+	get := func(name string) string {
+		if values := form[name]; len(values) > 0 {
+			return values[0]
+		}
+		return ""
+	}
+	if version := get("version"); version != "" && version != sru.Version {
+		return sru.NewDiagnostic(sru.DiagUnsupportedVersion, sru.Version)
+	}
+	if get("stylesheet") != "" {
+		return sru.NewDiagnostic(sru.DiagStylesheetUnsupported, "")
+	}
+	if get("sortKeys") != "" {
+		return sru.NewDiagnostic(sru.DiagSortUnsupported, "")
+	}
+	if packing := get("recordPacking"); packing != "" && packing != "packed" {
+		return sru.NewDiagnostic(sru.DiagUnsupportedValue, "recordPacking")
+	}
+	return nil
+}
+
+// searchRetrieve runs the query and fills the response with a page of records
+func (h *SRUHandler) searchRetrieve(c *gin.Context, response *sru.SearchRetrieveResponse) *sru.Diagnostic {
+	if queryType := c.Request.Form.Get("queryType"); queryType != "" && queryType != "cql" {
+		return sru.NewDiagnostic(sru.DiagUnsupportedValue, "queryType")
+	}
+	schema, ok := sruSchema(c.Request.Form.Get("recordSchema"))
+	if !ok {
+		return sru.NewDiagnostic(sru.DiagUnknownSchema, c.Request.Form.Get("recordSchema"))
+	}
+	switch escaping := c.Request.Form.Get("recordXMLEscaping"); escaping {
+	case "", sru.EscapingXML:
+	case sru.EscapingString:
+		response.Escaping = escaping
+	default:
+		return sru.NewDiagnostic(sru.DiagUnsupportedEscaping, escaping)
+	}
+
+	start, ok := sruNumber(c.Request.Form.Get("startRecord"), 1, 1)
+	if !ok {
+		return sru.NewDiagnostic(sru.DiagUnsupportedValue, "startRecord")
+	}
+	maximum, ok := sruNumber(c.Request.Form.Get("maximumRecords"), sruDefaultRecords, 0)
+	if !ok {
+		return sru.NewDiagnostic(sru.DiagUnsupportedValue, "maximumRecords")
+	}
+	maximum = minInt(maximum, sruMaximumRecords)
+
+	text := c.Request.Form.Get("query")
+	if strings.TrimSpace(text) == "" {
+		return sru.NewDiagnostic(sru.DiagMissingParameter, "query")
+	}
+	node, err := cql.Parse(text)
+	if err != nil {
+		return sru.NewDiagnostic(sru.DiagQuerySyntax, err.Error())
+	}
+	req, diag := sruSearchRequest(node)
+	if diag != nil {
+		return diag
+	}
+	req.Limit, req.Offset = maximum, start-1
+
+	result, err := search.Books(database.DB, req)
+	if err != nil {
+		return sru.NewDiagnostic(sru.DiagGeneralError, err.Error())
+	}
+	response.NumberOfRecords = result.Total
+	if int64(start) > result.Total && result.Total > 0 {
+		return sru.NewDiagnostic(sru.DiagFirstRecordOutOfRange, strconv.Itoa(start))
+	}
+
+	books, err := hitBooks(result.Hits, withContributors, withCategories, withTags)
+	if err != nil {
+		return sru.NewDiagnostic(sru.DiagGeneralError, err.Error())
+	}
+	for i, book := range books {
+		record := sru.Record{Schema: schema.Identifier, Position: start + i}
+		if schema.Name == "marcxml" {
+			record.Data, err = marc.MarshalXMLRecord(marc.FromBook(&book))
+		} else {
+			record.Data, err = sruDublinCore(requestBaseURL(c), book)
+		}
+		if err != nil {
+			return sru.NewDiagnostic(sru.DiagGeneralError, err.Error())
+		}
+		response.Records = append(response.Records, record)
+	}
+	if next := start + len(books); len(books) > 0 && int64(next) <= result.Total {
+		response.NextRecordPosition = next
+	}
+	return nil
+}
+
+func (h *SRUHandler) explain(c *gin.Context, diag *sru.Diagnostic) {
+	host, port, err := net.SplitHostPort(c.Request.Host)
+	if err != nil {
+		host, port = c.Request.Host, "80"
+		if strings.HasPrefix(requestBaseURL(c), "https:") {
+			port = "443"
+		}
+	}
+
+	explain := &sru.Explain{
+		Host:           host,
+		Port:           port,
+		Database:       strings.TrimPrefix(c.Request.URL.Path, "/"),
+		Title:          config.OAIRepositoryName,
+		Description:    "Catalogue of the library's books",
+		Sets:           sruContextSets,
+		Schemas:        sruSchemas,
+		DefaultRecords: sruDefaultRecords,
+		MaximumRecords: sruMaximumRecords,
+	}
+	for _, index := range sruIndexTitles {
+		entry := sru.Index{Title: index.title}
+		for name, field := range sruIndexes {
+			if set := strings.Index(name, "."); set > 0 && field == index.field {
+				entry.Names = append(entry.Names, sru.IndexName{Set: name[:set], Name: name[set+1:]})
+			}
+		}
+		sort.Slice(entry.Names, func(i, j int) bool {
+			return entry.Names[i].Set+"."+entry.Names[i].Name < entry.Names[j].Set+"."+entry.Names[j].Name
+		})
+		explain.Indexes = append(explain.Indexes, entry)
+	}
+
+	var diags []*sru.Diagnostic
+	if diag != nil {
+		diags = append(diags, diag)
+	}
+	var buf bytes.Buffer
+	if err := sru.WriteExplain(&buf, explain, diags); err != nil {
+		log.Println("SRU explain failed:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encode SRU response"})
+		return
+	}
+	c.Data(http.StatusOK, sru.ContentType, buf.Bytes())
+}
+
+func (h *SRUHandler) respond(c *gin.Context, response *sru.SearchRetrieveResponse) {
+	var buf bytes.Buffer
+	if err := sru.WriteSearchRetrieve(&buf, response); err != nil {
+		log.Println("SRU response failed:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encode SRU response"})
+		return
+	}
+	c.Data(http.StatusOK, sru.ContentType, buf.Bytes())
+}
+
+// sruSchema finds a record schema by short name or identifier, Dublin Core by default
+func sruSchema(name string) (sru.Schema, bool) {
+	if name == "" {
+		return sruSchemas[0], true
+	}
+	for _, schema := range sruSchemas {
+		if name == schema.Name || name == schema.Identifier {
+			return schema, true
+		}
+	}
+	return sru.Schema{}, false
+}
+
+// sruNumber parses an optional positive integer parameter
+func sruNumber(value string, fallback, min int) (int, bool) {
+	if value == "" {
+		return fallback, true
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < min {
+		return 0, false
+	}
+	return n, true
+}
+
+// sruDublinCore encodes a book as an srw_dc:dc record
+func sruDublinCore(base string, book models.Book) ([]byte, error) {
+	return dublincore.Marshal(bookDublinCore(base, book), "srw_dc:dc",
+		xml.Attr{Name: xml.Name{Local: "xmlns:srw_dc"}, Value: "info:srw/schema/1/dc-schema"})
+}
+
+// sruSearchRequest turns a CQL query into a book search. Free-text clauses
+// and-ed at the top level become the ranked full-text query; every other
+// clause becomes a SQL condition on the books.
+func sruSearchRequest(node cql.Node) (search.Request, *sru.Diagnostic) {
+	var req search.Request
+	var terms, conditions []string
+	var args []interface{}
+	for _, part := range sruConjuncts(node) {
+		if clause, ok := part.(*cql.Clause); ok && sruRanked(clause) {
+			terms = append(terms, clause.Term)
+			continue
+		}
+		condition, conditionArgs, diag := sruCondition(part)
+		if diag != nil {
+			return req, diag
+		}
+		conditions = append(conditions, "("+condition+")")
+		args = append(args, conditionArgs...)
+	}
+
+	req.Query = strings.Join(terms, " ")
+	if len(conditions) > 0 {
+		where := strings.Join(conditions, " AND ")
+		req.Scopes = []func(*gorm.DB) *gorm.DB{func(db *gorm.DB) *gorm.DB {
+			return db.Where(where, args...)
+		}}
+	}
+	return req, nil
+}
+
+// sruConjuncts splits a query into the parts joined by unmodified ands
+func sruConjuncts(node cql.Node) []cql.Node {
+	if b, ok := node.(*cql.Boolean); ok && b.Op == "and" && len(b.Modifiers) == 0 {
+		return append(sruConjuncts(b.Left), sruConjuncts(b.Right)...)
+	}
+	return []cql.Node{node}
+}
+
+// sruRanked reports whether a clause can be answered by the full-text search,
+// which matches every word of the term by prefix anywhere in a book
+func sruRanked(clause *cql.Clause) bool {
+	if sruIndexes[clause.Index] != sruAnywhere || len(clause.Modifiers) > 0 {
+		return false
+	}
+	if clause.Relation != "=" && clause.Relation != "all" {
+		return false
+	}
+	return !strings.ContainsAny(clause.Term, `*?^\`) && len(search.Terms(clause.Term)) > 0
+}
+
+// sruCondition translates a query into a SQL condition on the books table
+func sruCondition(node cql.Node) (string, []interface{}, *sru.Diagnostic) {
+	if b, ok := node.(*cql.Boolean); ok {
+		if len(b.Modifiers) > 0 {
+			return "", nil, sru.NewDiagnostic(sru.DiagUnsupportedBooleanModifier, b.Modifiers[0].Name)
+		}
+		left, leftArgs, diag := sruCondition(b.Left)
+		if diag != nil {
+			return "", nil, diag
+		}
+		right, rightArgs, diag := sruCondition(b.Right)
+		if diag != nil {
+			return "", nil, diag
+		}
+		args := append(leftArgs, rightArgs...)
+		switch b.Op {
+		case "and":
+			return "(" + left + ") AND (" + right + ")", args, nil
+		case "or":
+			return "(" + left + ") OR (" + right + ")", args, nil
+		case "not":
+			return "(" + left + ") AND NOT (" + right + ")", args, nil
+		}
+		return "", nil, sru.NewDiagnostic(sru.DiagUnsupportedBoolean, b.Op)
+	}
+
+	clause := node.(*cql.Clause)
+	field, ok := sruIndexes[clause.Index]
+	if !ok {
+		return "", nil, sru.NewDiagnostic(sru.DiagUnsupportedIndex, clause.Index)
+	}
+	if len(clause.Modifiers) > 0 {
+		return "", nil, sru.NewDiagnostic(sru.DiagUnsupportedModifier, clause.Modifiers[0].Name)
+	}
+	if field == sruAllRecords {
+		return "1 = 1", nil, nil
+	}
+	if strings.TrimSpace(clause.Term) == "" {
+		return "", nil, sru.NewDiagnostic(sru.DiagEmptyTerm, "")
+	}
+
+	switch clause.Relation {
+	case "=", "all", "any":
+		// Every word must match, or any of them
+		joiner := " AND "
+		if clause.Relation == "any" {
+			joiner = " OR "
+		}
+		var conditions []string
+		var args []interface{}
+		for _, word := range strings.Fields(clause.Term) {
+			condition, wordArgs, diag := sruMatch(field, word, false)
+			if diag != nil {
+				return "", nil, diag
+			}
+			conditions = append(conditions, "("+condition+")")
+			args = append(args, wordArgs...)
+		}
+		return strings.Join(conditions, joiner), args, nil
+	case "adj":
+		return sruMatch(field, strings.Join(strings.Fields(clause.Term), " "), false)
+	case "==", "exact":
+		return sruMatch(field, clause.Term, true)
+	case "<>":
+		condition, args, diag := sruMatch(field, clause.Term, true)
+		return "NOT (" + condition + ")", args, diag
+	}
+	return "", nil, sru.NewDiagnostic(sru.DiagUnsupportedRelation, clause.Relation)
+}
+
+// sruMatch matches a term against the columns of a field, as a substring or,
+// when exact, as the whole value
+func sruMatch(field, term string, exact bool) (string, []interface{}, *sru.Diagnostic) {
+	if field == sruISBN {
+		term = strings.ReplaceAll(term, "-", "")
+		// A complete ISBN is compared in its stored ISBN-13 form
+		if isbn, err := models.NormalizeISBN(term); err == nil {
+			return "books.isbn = ?", []interface{}{isbn}, nil
+		}
+	}
+	pattern, diag := sruPattern(strings.ToLower(term), exact)
+	if diag != nil {
+		return "", nil, diag
+	}
+
+	columns := sruColumns[field]
+	conditions := make([]string, len(columns))
+	args := make([]interface{}, len(columns))
+	for i, column := range columns {
+		conditions[i] = column + ` LIKE ? ESCAPE '\'`
+		args[i] = pattern
+	}
+	return strings.Join(conditions, " OR "), args, nil
+}
+
+// sruPattern converts a CQL term to a LIKE pattern: * and ? are masking
+// characters, ^ anchors the term to the start or end of the value and a
+// backslash makes the next character literal
+func sruPattern(term string, exact bool) (string, *sru.Diagnostic) {
+	anchoredStart, anchoredEnd := exact, exact
+	var b strings.Builder
+	for i := 0; i < len(term); i++ {
+		switch c := term[i]; c {
+		case '\\':
+			if i+1 < len(term) {
+				i++
+				c = term[i]
+			}
+			if c == '%' || c == '_' || c == '\\' {
+				b.WriteByte('\\')
+			}
+			b.WriteByte(c)
+		case '*':
+			b.WriteByte('%')
+		case '?':
+			b.WriteByte('_')
+		case '^':
+			switch i {
+			case 0:
+				anchoredStart = true
+			case len(term) - 1:
+				anchoredEnd = true
+			default:
+				return "", sru.NewDiagnostic(sru.DiagUnsupportedAnchoring, term)
+			}
+		case '%', '_':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	pattern := b.String()
+	if !anchoredStart {
+		pattern = "%" + pattern
+	}
+	if !anchoredEnd {
+		pattern += "%"
+	}
+	return pattern, nil
+}
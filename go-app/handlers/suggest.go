@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"library-go/suggest"
+)
+
+// maxSuggestions caps the number of typeahead matches per request
+const maxSuggestions = 20
+
+type SuggestHandler struct{}
+
+func NewSuggestHandler() *SuggestHandler {
+	return &SuggestHandler{}
+}
+
+// Suggest returns typeahead matches for books, readers or authors
+func (h *SuggestHandler) Suggest(c *gin.Context) {
+	kind := suggest.Kind(c.DefaultQuery("type", string(suggest.Books)))
+	switch kind {
+	case suggest.Books, suggest.Readers, suggest.Authors:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "type must be one of book, reader or author"})
+		return
+	}
+
+	prefix := c.Query("prefix")
+	if prefix == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Query parameter prefix is required"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil || limit < 1 || limit > maxSuggestions {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and " + strconv.Itoa(maxSuggestions)})
+		return
+	}
+
+	// Fall back to typo tolerant matching when nothing starts with the prefix
+	suggestions := suggest.Lookup(kind, prefix, limit)
+	if len(suggestions) == 0 || c.Query("fuzzy") == "true" {
+		suggestions = suggest.Match(kind, prefix, limit)
+	}
+
+	c.JSON(http.StatusOK, suggestions)
+}
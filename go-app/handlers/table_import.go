@@ -0,0 +1,629 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"library-go/database"
+	"library-go/models"
+	"library-go/spreadsheet"
+	"library-go/suggest"
+)
+
+type ImportHandler struct{}
+
+// Strategies for rows matching an existing record by ISBN or email
+const (
+	duplicateSkip   = "skip"   // Leave the existing record alone
+	duplicateUpdate = "update" // Overwrite it with the non-empty cells of the row
+	duplicateFail   = "fail"   // Import nothing if any row matches
+)
+
+// bookColumns maps normalized column headers to the book fields they import into
+var bookColumns = map[string]string{
+	"title": "title", "author": "author", "authors": "author", "year": "year",
+	"publication_year": "year", "isbn": "isbn", "isbn_13": "isbn", "isbn13": "isbn",
+	"isbn_10": "isbn", "isbn10": "isbn", "copies": "copies", "description": "description",
+	"summary": "description", "publisher": "publisher", "language": "language",
+	"edition": "edition", "page_count": "page_count", "pages": "page_count", "format": "format",
+}
+
+// readerColumns maps normalized column headers to the reader fields they import into
+var readerColumns = map[string]string{
+	"first_name": "first_name", "firstname": "first_name", "given_name": "first_name",
+	"last_name": "last_name", "lastname": "last_name", "surname": "last_name",
+	"family_name": "last_name", "email": "email", "e_mail": "email", "phone": "phone",
+	"telephone": "phone", "address": "address",
+}
+
+// tableImport is an uploaded table with its columns mapped to fields
+type tableImport struct {
+	header      []string
+	rows        []spreadsheet.Row
+	columns     map[string]int // Column of each mapped field
+	dryRun      bool
+	onDuplicate string
+	csvReport   bool // Respond with the rows that were not imported, as CSV
+}
+
+func NewImportHandler() *ImportHandler {
+	return &ImportHandler{}
+}
+
+// ImportBooks creates books from the rows of a CSV or XLSX file. Columns are
+// matched to fields by header unless a mapping is given, and rows whose ISBN
+// is already catalogued are skipped, update the book or fail the import.
+func (h *ImportHandler) ImportBooks(c *gin.Context) {
+	table, ok := parseTableImport(c, bookColumns, "title", "author")
+	if !ok {
+		return
+	}
+
+	// Rows are checked against the catalogue and each other before anything is written
+	type bookRow struct {
+		record importRecord
+		book   models.Book
+		author bool // The author cell was set, so the credits follow it
+	}
+	var isbns []string
+	planned := make([]bookRow, 0, len(table.rows))
+	for _, row := range table.rows {
+		planned = append(planned, bookRow{record: importRecord{Index: row.Number}})
+		if isbn, ok := table.cell(row, "isbn"); ok {
+			if normalized, err := models.NormalizeISBN(isbn); err == nil {
+				isbns = append(isbns, normalized)
+			}
+		}
+	}
+	existing, err := isbnBookIDs(isbns)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check existing books"})
+		return
+	}
+
+	seen := make(map[string]int)
+	duplicates := 0
+	for i, row := range table.rows {
+		plan := &planned[i]
+		plan.book = models.Book{Copies: 1}
+		errs := applyBookRow(&plan.book, table, row)
+		plan.record.Title = plan.book.Title
+		if len(errs) == 0 && plan.book.ISBN != nil {
+			if isbn, err := models.NormalizeISBN(*plan.book.ISBN); err == nil {
+				plan.book.ISBN = &isbn
+				if first, ok := seen[isbn]; ok {
+					errs = append(errs, fmt.Sprintf("ISBN is already used on row %d", first))
+				}
+				seen[isbn] = row.Number
+			}
+		}
+		plan.record.ISBN = plan.book.ISBN
+		_, plan.author = table.cell(row, "author")
+
+		var id uint
+		if plan.book.ISBN != nil {
+			id = existing[*plan.book.ISBN]
+		}
+		switch {
+		case len(errs) > 0:
+		case id != 0 && table.onDuplicate == duplicateSkip:
+			plan.record.Status = importDuplicate
+			plan.record.BookID = &id
+			continue
+		case id != 0 && table.onDuplicate == duplicateFail:
+			plan.record.Status = importDuplicate
+			plan.record.BookID = &id
+			plan.record.Errors = []string{"a book with this ISBN already exists"}
+			duplicates++
+			continue
+		case id != 0:
+			// The row is laid over the existing book, so only its own cells are checked
+			var book models.Book
+			if err := database.DB.First(&book, id).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check existing books"})
+				return
+			}
+			errs = applyBookRow(&book, table, row)
+			plan.book = book
+			plan.record.BookID = &id
+		}
+		if len(errs) == 0 {
+			if err := plan.book.Validate(); err != nil {
+				errs = append(errs, err.Error())
+			}
+		}
+		if len(errs) > 0 {
+			plan.record.Status = importInvalid
+			plan.record.Errors = errs
+		} else if plan.book.ID != 0 {
+			plan.record.Status = importWouldUpdate
+		} else {
+			plan.record.Status = importWouldCreate
+		}
+	}
+
+	report := newImportReport(table.dryRun)
+	report.Mapping = table.mapping()
+	if duplicates > 0 {
+		for _, plan := range planned {
+			report.add(plan.record)
+		}
+		report.Error = fmt.Sprintf("%d rows match existing books, so nothing was imported", duplicates)
+		table.respond(c, http.StatusConflict, report)
+		return
+	}
+
+	for _, plan := range planned {
+		record := plan.record
+		book := plan.book
+		if !table.dryRun {
+			switch record.Status {
+			case importWouldCreate:
+				if err := insertBook(&book, authorInputs(book.Author)); err != nil {
+					record.Status = importInvalid
+					record.Errors = []string{"failed to create book"}
+					log.Println("Book import failed for row", record.Index, err)
+				} else {
+					record.Status = importCreated
+					record.BookID = &book.ID
+				}
+			case importWouldUpdate:
+				if err := updateImportedBook(&book, plan.author); err != nil {
+					record.Status = importInvalid
+					record.Errors = []string{"failed to update book"}
+					log.Println("Book import failed for row", record.Index, err)
+				} else {
+					record.Status = importUpdated
+				}
+			}
+		}
+		report.add(record)
+	}
+
+	table.respond(c, http.StatusOK, report)
+}
+
+// ImportReaders creates readers from the rows of a CSV or XLSX file. Rows
+// are matched to existing readers by email.
+func (h *ImportHandler) ImportReaders(c *gin.Context) {
+	table, ok := parseTableImport(c, readerColumns, "first_name", "last_name")
+	if !ok {
+		return
+	}
+
+	var emails []string
+	for _, row := range table.rows {
+		if email, ok := table.cell(row, "email"); ok {
+			emails = append(emails, email)
+		}
+	}
+	existing, err := emailReaderIDs(emails)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check existing readers"})
+		return
+	}
+
+	type readerRow struct {
+		record   importRecord
+		reader   models.Reader
+		original models.Reader
+	}
+	seen := make(map[string]int)
+	duplicates := 0
+	planned := make([]readerRow, 0, len(table.rows))
+	for _, row := range table.rows {
+		plan := readerRow{record: importRecord{Index: row.Number}}
+		applyReaderRow(&plan.reader, table, row)
+		plan.record.Name = strings.TrimSpace(plan.reader.FirstName + " " + plan.reader.LastName)
+		plan.record.Email = plan.reader.Email
+
+		var errs []string
+		var id uint
+		if email := plan.reader.Email; email != nil {
+			if first, ok := seen[*email]; ok {
+				errs = append(errs, fmt.Sprintf("email is already used on row %d", first))
+			}
+			seen[*email] = row.Number
+			id = existing[*email]
+		}
+		switch {
+		case len(errs) > 0:
+		case id != 0 && table.onDuplicate == duplicateSkip:
+			plan.record.Status = importDuplicate
+			plan.record.ReaderID = &id
+			planned = append(planned, plan)
+			continue
+		case id != 0 && table.onDuplicate == duplicateFail:
+			plan.record.Status = importDuplicate
+			plan.record.ReaderID = &id
+			plan.record.Errors = []string{"a reader with this email already exists"}
+			duplicates++
+			planned = append(planned, plan)
+			continue
+		case id != 0:
+			if err := database.DB.First(&plan.reader, id).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check existing readers"})
+				return
+			}
+			plan.original = plan.reader
+			applyReaderRow(&plan.reader, table, row)
+			plan.record.ReaderID = &id
+		}
+		if len(errs) == 0 {
+			if err := plan.reader.Validate(); err != nil {
+				errs = append(errs, err.Error())
+			}
+		}
+		if len(errs) > 0 {
+			plan.record.Status = importInvalid
+			plan.record.Errors = errs
+		} else if plan.reader.ID != 0 {
+			plan.record.Status = importWouldUpdate
+		} else {
+			plan.record.Status = importWouldCreate
+		}
+		planned = append(planned, plan)
+	}
+
+	report := newImportReport(table.dryRun)
+	report.Mapping = table.mapping()
+	if duplicates > 0 {
+		for _, plan := range planned {
+			report.add(plan.record)
+		}
+		report.Error = fmt.Sprintf("%d rows match existing readers, so nothing was imported", duplicates)
+		table.respond(c, http.StatusConflict, report)
+		return
+	}
+
+	for _, plan := range planned {
+		record := plan.record
+		reader := plan.reader
+		if !table.dryRun {
+			switch record.Status {
+			case importWouldCreate:
+				if err := database.DB.Create(&reader).Error; err != nil {
+					record.Status = importInvalid
+					record.Errors = []string{"failed to create reader"}
+					log.Println("Reader import failed for row", record.Index, err)
+				} else {
+					suggest.AddReader(reader)
+					record.Status = importCreated
+					record.ReaderID = &reader.ID
+				}
+			case importWouldUpdate:
+				if err := database.DB.Save(&reader).Error; err != nil {
+					record.Status = importInvalid
+					record.Errors = []string{"failed to update reader"}
+					log.Println("Reader import failed for row", record.Index, err)
+				} else {
+					suggest.UpdateReader(plan.original, reader)
+					record.Status = importUpdated
+				}
+			}
+		}
+		report.add(record)
+	}
+
+	table.respond(c, http.StatusOK, report)
+}
+
+// parseTableImport reads the uploaded table and the import options, and maps
+// its columns to fields: by the mapping parameter, a JSON object from column
+// header to field name ("" ignores the column), then by known header names
+func parseTableImport(c *gin.Context, columns map[string]string, required ...string) (*tableImport, bool) {
+	table := &tableImport{onDuplicate: c.DefaultQuery("on_duplicate", duplicateSkip)}
+	var err error
+	if table.dryRun, err = strconv.ParseBool(c.DefaultQuery("dry_run", "false")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "dry_run must be true or false"})
+		return nil, false
+	}
+	switch table.onDuplicate {
+	case duplicateSkip, duplicateUpdate, duplicateFail:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "on_duplicate must be one of: skip, update, fail"})
+		return nil, false
+	}
+	switch c.DefaultQuery("report", "json") {
+	case "json":
+	case "csv":
+		table.csvReport = true
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "report must be json or csv"})
+		return nil, false
+	}
+
+	body, ok := importBody(c)
+	if !ok {
+		return nil, false
+	}
+	defer body.Close()
+	data, err := io.ReadAll(body)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("Import file must be at most %d bytes", maxImportBytes)})
+			return nil, false
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read the import file"})
+		return nil, false
+	}
+	rows, err := spreadsheet.Read(data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read the import file: " + err.Error()})
+		return nil, false
+	}
+
+	// The first row with any content is the header, blank rows are skipped
+	for _, row := range rows {
+		if row.Blank() {
+			continue
+		}
+		if table.header == nil {
+			table.header = row.Cells
+			continue
+		}
+		table.rows = append(table.rows, row)
+	}
+	if table.header == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The import file is empty"})
+		return nil, false
+	}
+
+	mapping := map[string]string{}
+	if text := c.PostForm("mapping"); text != "" {
+		err = json.Unmarshal([]byte(text), &mapping)
+	} else if text := c.Query("mapping"); text != "" {
+		err = json.Unmarshal([]byte(text), &mapping)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "mapping must be a JSON object from column header to field"})
+		return nil, false
+	}
+	if err := table.mapColumns(mapping, columns); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	for _, field := range required {
+		if _, ok := table.columns[field]; !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "No column is mapped to " + field})
+			return nil, false
+		}
+	}
+	return table, true
+}
+
+// mapColumns assigns the header columns to fields
+func (t *tableImport) mapColumns(mapping map[string]string, columns map[string]string) error {
+	fields := make(map[string]bool, len(columns))
+	for _, field := range columns {
+		fields[field] = true
+	}
+
+	// Headers are compared the way they are normalized for the known names
+	explicit := make(map[string]string, len(mapping))
+	for header, field := range mapping {
+		if field != "" && !fields[field] {
+			return fmt.Errorf("mapping: unknown field %q", field)
+		}
+		found := false
+		for _, name := range t.header {
+			if headerKey(name) == headerKey(header) {
+				found = true
+			}
+		}
+		if !found {
+			return fmt.Errorf("mapping: no column is headed %q", header)
+		}
+		explicit[headerKey(header)] = field
+	}
+
+	t.columns = make(map[string]int)
+	for i, name := range t.header {
+		key := headerKey(name)
+		field, ok := explicit[key]
+		if !ok {
+			field = columns[key]
+		}
+		if field == "" {
+			continue
+		}
+		if previous, ok := t.columns[field]; ok {
+			return fmt.Errorf("columns %q and %q are both mapped to %s", t.header[previous], name, field)
+		}
+		t.columns[field] = i
+	}
+	return nil
+}
+
+// mapping returns the field of each mapped column by header, for the report
+func (t *tableImport) mapping() map[string]string {
+	mapping := make(map[string]string, len(t.columns))
+	for field, column := range t.columns {
+		mapping[t.header[column]] = field
+	}
+	return mapping
+}
+
+// cell returns the trimmed cell of a row for a field, and whether it is mapped and not empty
+func (t *tableImport) cell(row spreadsheet.Row, field string) (string, bool) {
+	column, ok := t.columns[field]
+	if !ok || column >= len(row.Cells) {
+		return "", false
+	}
+	value := strings.TrimSpace(row.Cells[column])
+	return value, value != ""
+}
+
+// respond writes the import report, or with report=csv the rows that were not
+// imported with their errors, so they can be corrected and uploaded again
+func (t *tableImport) respond(c *gin.Context, status int, report *importReport) {
+	if !t.csvReport {
+		c.JSON(status, report)
+		return
+	}
+
+	rows := make(map[int]spreadsheet.Row, len(t.rows))
+	for _, row := range t.rows {
+		rows[row.Number] = row
+	}
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="import-errors.csv"`)
+	c.Status(status)
+
+	writer := csv.NewWriter(c.Writer)
+	writer.Write(append([]string{"row", "status", "errors"}, t.header...))
+	for _, record := range report.Records {
+		if len(record.Errors) == 0 {
+			continue
+		}
+		writer.Write(append([]string{strconv.Itoa(record.Index), record.Status, strings.Join(record.Errors, "; ")}, rows[record.Index].Cells...))
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		log.Println("Import error report failed:", err)
+	}
+}
+
+// headerKey normalizes a column header such as "Page Count" to page_count
+func headerKey(header string) string {
+	var b strings.Builder
+	underscore := false
+	for _, r := range strings.ToLower(strings.TrimSpace(header)) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			if underscore && b.Len() > 0 {
+				b.WriteByte('_')
+			}
+			b.WriteRune(r)
+			underscore = false
+		} else {
+			underscore = true
+		}
+	}
+	return b.String()
+}
+
+// applyBookRow sets the fields of a book from the non-empty cells of a row,
+// returning the cells that could not be read. Values are checked by Validate.
+func applyBookRow(book *models.Book, t *tableImport, row spreadsheet.Row) []string {
+	var errs []string
+	text := func(field string, target **string) {
+		if value, ok := t.cell(row, field); ok {
+			*target = &value
+		}
+	}
+	number := func(field string, target **int) {
+		if value, ok := t.cell(row, field); ok {
+			n, err := wholeNumber(value)
+			if err != nil {
+				errs = append(errs, field+" must be a whole number")
+				return
+			}
+			*target = &n
+		}
+	}
+
+	if value, ok := t.cell(row, "title"); ok {
+		book.Title = value
+	}
+	if value, ok := t.cell(row, "author"); ok {
+		book.Author = value
+	}
+	if value, ok := t.cell(row, "format"); ok {
+		book.Format = value
+	}
+	if value, ok := t.cell(row, "copies"); ok {
+		copies, err := wholeNumber(value)
+		if err != nil {
+			errs = append(errs, "copies must be a whole number")
+		}
+		book.Copies = copies
+	}
+	number("year", &book.Year)
+	number("page_count", &book.PageCount)
+	text("isbn", &book.ISBN)
+	text("description", &book.Description)
+	text("publisher", &book.Publisher)
+	text("language", &book.Language)
+	text("edition", &book.Edition)
+	return errs
+}
+
+// applyReaderRow sets the fields of a reader from the non-empty cells of a row
+func applyReaderRow(reader *models.Reader, t *tableImport, row spreadsheet.Row) {
+	if value, ok := t.cell(row, "first_name"); ok {
+		reader.FirstName = value
+	}
+	if value, ok := t.cell(row, "last_name"); ok {
+		reader.LastName = value
+	}
+	for field, target := range map[string]**string{"email": &reader.Email, "phone": &reader.Phone, "address": &reader.Address} {
+		if value, ok := t.cell(row, field); ok {
+			*target = &value
+		}
+	}
+}
+
+// wholeNumber parses an integer cell, which spreadsheets may give as "320.0"
+func wholeNumber(value string) (int, error) {
+	if n, err := strconv.Atoi(value); err == nil {
+		return n, nil
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || f != math.Trunc(f) || math.Abs(f) > math.MaxInt32 {
+		return 0, errors.New("not a whole number")
+	}
+	return int(f), nil
+}
+
+// updateImportedBook saves a book changed by an import row, replacing its
+// author credits when the row gave an author
+func updateImportedBook(book *models.Book, author bool) error {
+	var original models.Book
+	if err := database.DB.First(&original, book.ID).Error; err != nil {
+		return err
+	}
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if author && book.Author != original.Author {
+			if err := replaceContributors(tx, book, authorInputs(book.Author), true); err != nil {
+				return err
+			}
+		} else if err := loadCreditLine(tx, book); err != nil {
+			return err
+		}
+		return tx.Omit(clause.Associations).Save(book).Error
+	})
+	if err != nil {
+		return err
+	}
+	suggest.UpdateBook(original, *book)
+	return nil
+}
+
+// emailReaderIDs returns the readers that already have one of the given emails, by email
+func emailReaderIDs(emails []string) (map[string]uint, error) {
+	existing := make(map[string]uint, len(emails))
+	if len(emails) == 0 {
+		return existing, nil
+	}
+	var readers []models.Reader
+	if err := database.DB.Select("id", "email").Where("email IN ?", emails).Find(&readers).Error; err != nil {
+		return nil, err
+	}
+	for _, reader := range readers {
+		existing[*reader.Email] = reader.ID
+	}
+	return existing, nil
+}
@@ -0,0 +1,296 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"library-go/database"
+	"library-go/models"
+	"library-go/query"
+)
+
+type TagHandler struct{}
+
+// tagFields lists the tag fields usable in filters, sort and field selection
+var tagFields = query.Resource{
+	"id":         {Type: query.Int, Filter: true, Sort: true},
+	"name":       {Type: query.String, Filter: true, Sort: true},
+	"book_count": {},
+	"created_at": {Type: query.Time, Filter: true, Sort: true},
+	"updated_at": {Type: query.Time, Filter: true, Sort: true},
+}
+
+// tagWithCount is a tag with the number of books carrying it
+type tagWithCount struct {
+	models.Tag
+	BookCount int64 `json:"book_count"`
+}
+
+func NewTagHandler() *TagHandler {
+	return &TagHandler{}
+}
+
+// GetTags retrieves all tags with their book counts
+func (h *TagHandler) GetTags(c *gin.Context) {
+	page, ok := parsePage(c, query.DefaultLimit)
+	if !ok {
+		return
+	}
+	params, ok := parseListParams(c, tagFields)
+	if !ok {
+		return
+	}
+
+	var total int64
+	if err := database.DB.Model(&models.Tag{}).Scopes(params.FilterScope()).Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tags"})
+		return
+	}
+
+	tags := []tagWithCount{}
+	if err := database.DB.Model(&models.Tag{}).
+		Select("tags.*, (SELECT count(*) FROM book_tags WHERE book_tags.tag_id = tags.id) AS book_count").
+		Scopes(params.Scope("name")).Offset(page.Offset()).Limit(page.Limit).
+		Scan(&tags).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tags"})
+		return
+	}
+
+	respondPage(c, params, page, total, tags)
+}
+
+// GetTagBooks retrieves the books carrying a tag, given by ID or name
+func (h *TagHandler) GetTagBooks(c *gin.Context) {
+	tag, ok := findTag(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	page, ok := parsePage(c, query.DefaultLimit)
+	if !ok {
+		return
+	}
+	params, ok := parseListParams(c, bookFields)
+	if !ok {
+		return
+	}
+
+	tagged := tagBookIDs(tag.ID)
+
+	var total int64
+	if err := database.DB.Model(&models.Book{}).Where("id IN (?)", tagged).Scopes(params.FilterScope()).Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch books"})
+		return
+	}
+
+	var books []models.Book
+	if err := database.DB.Scopes(withContributors, withCategories, withTags, params.Scope("id")).Where("id IN (?)", tagged).
+		Offset(page.Offset()).Limit(page.Limit).Find(&books).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch books"})
+		return
+	}
+
+	respondPage(c, params, page, total, books)
+}
+
+// CreateTag creates a new tag
+func (h *TagHandler) CreateTag(c *gin.Context) {
+	var input models.Tag
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := input.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var existing int64
+	database.DB.Model(&models.Tag{}).Where("name = ?", input.Name).Count(&existing)
+	if existing > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Tag already exists"})
+		return
+	}
+
+	if err := database.DB.Create(&input).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create tag"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, input)
+}
+
+// UpdateTag renames a tag
+func (h *TagHandler) UpdateTag(c *gin.Context) {
+	tag, ok := findTag(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Name string `json:"name" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tag.Name = input.Name
+	if err := tag.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var existing int64
+	database.DB.Model(&models.Tag{}).Where("name = ? AND id <> ?", tag.Name, tag.ID).Count(&existing)
+	if existing > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Tag already exists"})
+		return
+	}
+
+	if err := database.DB.Omit("Books").Save(tag).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update tag"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tag)
+}
+
+// DeleteTag deletes a tag, removing it from every book
+func (h *TagHandler) DeleteTag(c *gin.Context) {
+	tag, ok := findTag(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(tag).Association("Books").Clear(); err != nil {
+			return err
+		}
+		return tx.Delete(tag).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete tag"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Tag deleted successfully"})
+}
+
+// AddBookTags attaches tags to a book by name, creating the tags that do not exist yet
+func (h *TagHandler) AddBookTags(c *gin.Context) {
+	book, ok := findBook(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Tags []string `json:"tags" binding:"required,min=1"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tags := make([]models.Tag, 0, len(input.Tags))
+	for _, name := range input.Tags {
+		tag := models.Tag{Name: name}
+		if err := tag.Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		tags = append(tags, tag)
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		for i := range tags {
+			if err := tx.Where(models.Tag{Name: tags[i].Name}).FirstOrCreate(&tags[i]).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(book).Omit("Tags.*").Association("Tags").Append(tags); err != nil {
+			return err
+		}
+		return tx.Model(book).Association("Tags").Find(&book.Tags)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to tag book"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"book_id": book.ID, "tags": book.Tags})
+}
+
+// RemoveBookTag detaches a tag, given by ID or name, from a book
+func (h *TagHandler) RemoveBookTag(c *gin.Context) {
+	book, ok := findBook(c)
+	if !ok {
+		return
+	}
+	tag, ok := findTag(c, c.Param("tag"))
+	if !ok {
+		return
+	}
+
+	if err := database.DB.Model(book).Association("Tags").Delete(tag); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to untag book"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Tag removed successfully"})
+}
+
+// findTag loads a tag by ID or name, writing an error response on failure
+func findTag(c *gin.Context, ref string) (*models.Tag, bool) {
+	var tag models.Tag
+	var err error
+	if id, parseErr := strconv.ParseUint(ref, 10, 32); parseErr == nil {
+		err = database.DB.First(&tag, uint(id)).Error
+	} else {
+		err = database.DB.Where("name = ?", models.NormalizeTagName(ref)).First(&tag).Error
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tag not found"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tag"})
+		return nil, false
+	}
+	return &tag, true
+}
+
+// findBook loads the book named by the id parameter, writing an error response on failure
+func findBook(c *gin.Context) (*models.Book, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid book ID"})
+		return nil, false
+	}
+
+	var book models.Book
+	if err := database.DB.First(&book, uint(id)).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Book not found"})
+		return nil, false
+	}
+	return &book, true
+}
+
+// tagBookIDs returns a subquery of the books carrying a tag
+func tagBookIDs(tagID uint) *gorm.DB {
+	return database.DB.Table("book_tags").Select("book_id").Where("tag_id = ?", tagID)
+}
+
+// withTags preloads the tags of books by name
+func withTags(db *gorm.DB) *gorm.DB {
+	return db.Preload("Tags", func(db *gorm.DB) *gorm.DB {
+		return db.Order("name")
+	})
+}
@@ -0,0 +1,378 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"library-go/circulation"
+	"library-go/database"
+	"library-go/models"
+	"library-go/query"
+)
+
+type WorkHandler struct{}
+
+// workFields lists the work fields usable in filters, sort and field selection
+var workFields = query.Resource{
+	"id":          {Type: query.Int, Filter: true, Sort: true},
+	"title":       {Type: query.String, Filter: true, Sort: true},
+	"description": {Type: query.String, Filter: true},
+	"created_at":  {Type: query.Time, Filter: true, Sort: true},
+	"updated_at":  {Type: query.Time, Filter: true, Sort: true},
+	"editions":    {},
+	"series":      {},
+}
+
+var (
+	// errBookNotFound is returned when a request names a book that does not exist
+	errBookNotFound = errors.New("book not found")
+	// errWorkNotFound is returned when a request names a work that does not exist
+	errWorkNotFound = errors.New("work not found")
+)
+
+// editionAvailability is the loan status of one edition of a work
+type editionAvailability struct {
+	BookID          uint    `json:"book_id"`
+	Title           string  `json:"title"`
+	ISBN            *string `json:"isbn,omitempty"`
+	Year            *int    `json:"year,omitempty"`
+	Copies          int     `json:"copies"`
+	AvailableCopies int     `json:"available_copies"`
+}
+
+func NewWorkHandler() *WorkHandler {
+	return &WorkHandler{}
+}
+
+// GetWorks retrieves all works with their editions
+func (h *WorkHandler) GetWorks(c *gin.Context) {
+	var works []models.Work
+
+	page, ok := parsePage(c, query.DefaultLimit)
+	if !ok {
+		return
+	}
+	params, ok := parseListParams(c, workFields)
+	if !ok {
+		return
+	}
+
+	var total int64
+	if err := database.DB.Model(&models.Work{}).Scopes(params.FilterScope()).Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch works"})
+		return
+	}
+
+	if err := database.DB.Scopes(withEditions, withSeries, params.Scope("id")).
+		Offset(page.Offset()).Limit(page.Limit).Find(&works).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch works"})
+		return
+	}
+
+	respondPage(c, params, page, total, works)
+}
+
+// GetWork retrieves a work with its editions and the series it belongs to
+func (h *WorkHandler) GetWork(c *gin.Context) {
+	work, ok := findWork(c, withEditions, withSeries)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, work)
+}
+
+// GetWorkAvailability reports the copies on the shelf across every edition of a work
+func (h *WorkHandler) GetWorkAvailability(c *gin.Context) {
+	work, ok := findWork(c, withEditions)
+	if !ok {
+		return
+	}
+
+	available, err := circulation.AvailableCopies(database.DB, work.Editions)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch availability"})
+		return
+	}
+
+	var waiting int64
+	if err := database.DB.Model(&models.Hold{}).Where("status = ?", models.HoldWaiting).
+		Where("work_id = ? OR book_id IN (?)", work.ID, database.DB.Model(&models.Book{}).Select("id").Where("work_id = ?", work.ID)).
+		Count(&waiting).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch availability"})
+		return
+	}
+
+	editions := make([]editionAvailability, len(work.Editions))
+	copies, free := 0, 0
+	for i, book := range work.Editions {
+		editions[i] = editionAvailability{
+			BookID:          book.ID,
+			Title:           book.Title,
+			ISBN:            book.ISBN,
+			Year:            book.Year,
+			Copies:          book.Copies,
+			AvailableCopies: available[book.ID],
+		}
+		copies += book.Copies
+		free += available[book.ID]
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"work_id":          work.ID,
+		"title":            work.Title,
+		"copies":           copies,
+		"available_copies": free,
+		"waiting_holds":    waiting,
+		"editions":         editions,
+	})
+}
+
+// CreateWork creates a new work, optionally grouping existing books as its editions
+func (h *WorkHandler) CreateWork(c *gin.Context) {
+	var input struct {
+		Title       string  `json:"title" binding:"required"`
+		Description *string `json:"description"`
+		BookIDs     []uint  `json:"book_ids"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	work := models.Work{Title: input.Title, Description: input.Description}
+	if err := work.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	bookIDs := uniqueIDs(input.BookIDs)
+	if err := validateBookIDs(bookIDs); err != nil {
+		respondBookIDsError(c, err)
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Create(&work).Error; err != nil {
+			return err
+		}
+		if len(bookIDs) == 0 {
+			return nil
+		}
+		return tx.Model(&models.Book{}).Where("id IN ?", bookIDs).UpdateColumn("work_id", work.ID).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create work"})
+		return
+	}
+
+	database.DB.Scopes(withEditions, withSeries).First(&work, work.ID)
+
+	c.JSON(http.StatusCreated, work)
+}
+
+// UpdateWork updates the title or description of a work
+func (h *WorkHandler) UpdateWork(c *gin.Context) {
+	work, ok := findWork(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Title       *string `json:"title"`
+		Description *string `json:"description"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if input.Title != nil {
+		work.Title = *input.Title
+	}
+	if input.Description != nil {
+		work.Description = input.Description
+	}
+	if err := work.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := database.DB.Omit(clause.Associations).Save(work).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update work"})
+		return
+	}
+
+	c.JSON(http.StatusOK, work)
+}
+
+// DeleteWork deletes a work, leaving its editions as standalone books
+func (h *WorkHandler) DeleteWork(c *gin.Context) {
+	work, ok := findWork(c)
+	if !ok {
+		return
+	}
+
+	var waiting int64
+	database.DB.Model(&models.Hold{}).Where("work_id = ? AND status = ?", work.ID, models.HoldWaiting).Count(&waiting)
+	if waiting > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot delete work with waiting holds"})
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Book{}).Where("work_id = ?", work.ID).UpdateColumn("work_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("work_id = ?", work.ID).Delete(&models.SeriesEntry{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(work).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete work"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Work deleted successfully"})
+}
+
+// AddEdition makes a book an edition of a work, moving it from any other work
+func (h *WorkHandler) AddEdition(c *gin.Context) {
+	work, ok := findWork(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		BookID uint `json:"book_id" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var book models.Book
+	if err := database.DB.First(&book, input.BookID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Book not found"})
+		return
+	}
+
+	if err := database.DB.Model(&book).UpdateColumn("work_id", work.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add edition"})
+		return
+	}
+
+	database.DB.Scopes(withEditions, withSeries).First(work, work.ID)
+
+	c.JSON(http.StatusOK, work)
+}
+
+// RemoveEdition detaches a book from a work
+func (h *WorkHandler) RemoveEdition(c *gin.Context) {
+	work, ok := findWork(c)
+	if !ok {
+		return
+	}
+
+	bookID, err := strconv.ParseUint(c.Param("book_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid book ID"})
+		return
+	}
+
+	result := database.DB.Model(&models.Book{}).Where("id = ? AND work_id = ?", uint(bookID), work.ID).UpdateColumn("work_id", nil)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove edition"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Book is not an edition of this work"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Edition removed successfully"})
+}
+
+// findWork loads the work named by the id parameter, writing an error response on failure
+func findWork(c *gin.Context, scopes ...func(*gorm.DB) *gorm.DB) (*models.Work, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid work ID"})
+		return nil, false
+	}
+
+	var work models.Work
+	if err := database.DB.Scopes(scopes...).First(&work, uint(id)).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Work not found"})
+		return nil, false
+	}
+	return &work, true
+}
+
+// validateBookIDs checks that every book exists
+func validateBookIDs(ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	var count int64
+	if err := database.DB.Model(&models.Book{}).Where("id IN ?", ids).Count(&count).Error; err != nil {
+		return err
+	}
+	if int(count) != len(ids) {
+		return errBookNotFound
+	}
+	return nil
+}
+
+// respondBookIDsError writes the response for a failed validateBookIDs
+func respondBookIDsError(c *gin.Context, err error) {
+	if errors.Is(err, errBookNotFound) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown book"})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch books"})
+}
+
+// validateWorkID checks that the work a book is assigned to exists
+func validateWorkID(id *uint) error {
+	if id == nil {
+		return nil
+	}
+	var count int64
+	if err := database.DB.Model(&models.Work{}).Where("id = ?", *id).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return errWorkNotFound
+	}
+	return nil
+}
+
+// respondWorkError writes the response for a failed validateWorkID
+func respondWorkError(c *gin.Context, err error) {
+	if errors.Is(err, errWorkNotFound) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown work"})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch work"})
+}
+
+// withEditions preloads the editions of works by year
+func withEditions(db *gorm.DB) *gorm.DB {
+	return db.Preload("Editions", func(db *gorm.DB) *gorm.DB {
+		return db.Order("year, id")
+	})
+}
+
+// withSeries preloads the series memberships of works
+func withSeries(db *gorm.DB) *gorm.DB {
+	return db.Preload("Series.Series")
+}
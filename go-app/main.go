@@ -5,13 +5,19 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 
 	"library-go/config"
+	"library-go/covers"
 	"library-go/database"
 	"library-go/handlers"
+	"library-go/metadata"
+	"library-go/search"
+	"library-go/sip2"
+	"library-go/suggest"
 )
 
 func init() {
@@ -28,6 +34,33 @@ func main() {
 	// Initialize database connection
 	database.InitDB()
 	defer database.CloseDB()
+	defer search.Close()
+
+	// Open the cover image storage
+	if err := covers.Setup(); err != nil {
+		log.Fatal("Failed to open cover storage:", err)
+	}
+
+	// Set up the external catalogues books are looked up in by ISBN
+	if err := metadata.Setup(); err != nil {
+		log.Fatal("Failed to set up metadata lookup:", err)
+	}
+
+	// Run a maintenance command instead of the server if one is given
+	if len(os.Args) > 1 {
+		if err := runCommand(os.Args[1]); err != nil {
+			log.Println(err)
+			search.Close()
+			database.CloseDB()
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Build the in-memory typeahead index
+	if err := suggest.Build(database.DB); err != nil {
+		log.Fatal("Failed to build suggestion index:", err)
+	}
 
 	// Set up Gin router
 	r := gin.Default()
@@ -48,8 +81,26 @@ func main() {
 	// Initialize handlers
 	authHandler := handlers.NewAuthHandler()
 	bookHandler := handlers.NewBookHandler()
+	authorHandler := handlers.NewAuthorHandler()
+	categoryHandler := handlers.NewCategoryHandler()
+	tagHandler := handlers.NewTagHandler()
+	readingListHandler := handlers.NewReadingListHandler()
+	workHandler := handlers.NewWorkHandler()
+	seriesHandler := handlers.NewSeriesHandler()
+	holdHandler := handlers.NewHoldHandler()
+	coverHandler := handlers.NewCoverHandler()
+	marcHandler := handlers.NewMARCHandler()
+	importHandler := handlers.NewImportHandler()
+	exportHandler := handlers.NewExportHandler()
+	citationHandler := handlers.NewCitationHandler()
 	readerHandler := handlers.NewReaderHandler()
 	borrowHandler := handlers.NewBorrowHandler()
+	suggestHandler := handlers.NewSuggestHandler()
+	searchHandler := handlers.NewSearchHandler()
+	opdsHandler := handlers.NewOPDSHandler()
+	oaiHandler := handlers.NewOAIHandler()
+	sruHandler := handlers.NewSRUHandler()
+	linkedDataHandler := handlers.NewLinkedDataHandler()
 
 	// API routes
 	api := r.Group("/api")
@@ -61,22 +112,144 @@ func main() {
 			auth.POST("/login", authHandler.Login)
 			auth.GET("/me", authHandler.AuthMiddleware(), authHandler.GetMe)
 			auth.POST("/logout", authHandler.AuthMiddleware(), authHandler.Logout)
+			auth.GET("/sessions", authHandler.AuthMiddleware(), authHandler.GetSessions)
+			auth.DELETE("/sessions/:id", authHandler.AuthMiddleware(), authHandler.DeleteSession)
+			auth.GET("/users/:id/sessions", authHandler.AuthMiddleware(), authHandler.AdminMiddleware(), authHandler.GetUserSessions)
+			auth.DELETE("/users/:id/sessions", authHandler.AuthMiddleware(), authHandler.AdminMiddleware(), authHandler.DeleteUserSessions)
 		}
 
+		// Search index administration (admin only)
+		searchAdmin := api.Group("/search").Use(authHandler.AuthMiddleware(), authHandler.AdminMiddleware())
+		{
+			searchAdmin.GET("/status", searchHandler.GetStatus)
+			searchAdmin.POST("/reindex", searchHandler.Reindex)
+		}
+
+		// Typeahead suggestions (protected)
+		api.GET("/suggest", authHandler.AuthMiddleware(), suggestHandler.Suggest)
+
 		// Books routes (protected)
 		books := api.Group("/books").Use(authHandler.AuthMiddleware())
 		{
 			books.GET("/", bookHandler.GetBooks)
+			books.GET("/search", bookHandler.SearchBooks)
+			books.GET("/lookup", bookHandler.LookupBook)
+			books.GET("/isbn/:isbn", bookHandler.GetBookByISBN)
 			books.GET("/:id", bookHandler.GetBook)
+			books.GET("/:id/cite", citationHandler.CiteBook)
 			books.POST("/", bookHandler.CreateBook)
 			books.PUT("/:id", bookHandler.UpdateBook)
 			books.DELETE("/:id", bookHandler.DeleteBook)
+			books.POST("/:id/tags", tagHandler.AddBookTags)
+			books.DELETE("/:id/tags/:tag", tagHandler.RemoveBookTag)
+			books.POST("/:id/cover", coverHandler.UploadCover)
+			books.GET("/export.mrc", marcHandler.ExportMARC)
+			books.GET("/export.xml", marcHandler.ExportMARCXML)
+			books.GET("/export.csv", exportHandler.ExportBooks)
+			books.GET("/export.jsonl", exportHandler.ExportBooks)
+			books.GET("/export.xlsx", exportHandler.ExportBooks)
+			books.DELETE("/:id/cover", coverHandler.DeleteCover)
+		}
+
+		// Authors routes (protected)
+		authors := api.Group("/authors").Use(authHandler.AuthMiddleware())
+		{
+			authors.GET("/", authorHandler.GetAuthors)
+			authors.GET("/:id", authorHandler.GetAuthor)
+			authors.GET("/:id/books", authorHandler.GetAuthorBooks)
+			authors.POST("/", authorHandler.CreateAuthor)
+			authors.POST("/:id/merge", authorHandler.MergeAuthors)
+			authors.PUT("/:id", authorHandler.UpdateAuthor)
+			authors.DELETE("/:id", authorHandler.DeleteAuthor)
+		}
+
+		// Categories routes (protected)
+		categories := api.Group("/categories").Use(authHandler.AuthMiddleware())
+		{
+			categories.GET("/", categoryHandler.GetCategories)
+			categories.GET("/:id", categoryHandler.GetCategory)
+			categories.GET("/:id/books", categoryHandler.GetCategoryBooks)
+			categories.POST("/", categoryHandler.CreateCategory)
+			categories.PUT("/:id", categoryHandler.UpdateCategory)
+			categories.DELETE("/:id", categoryHandler.DeleteCategory)
+		}
+
+		// Tags routes (protected)
+		tags := api.Group("/tags").Use(authHandler.AuthMiddleware())
+		{
+			tags.GET("/", tagHandler.GetTags)
+			tags.GET("/:id/books", tagHandler.GetTagBooks)
+			tags.POST("/", tagHandler.CreateTag)
+			tags.PUT("/:id", tagHandler.UpdateTag)
+			tags.DELETE("/:id", tagHandler.DeleteTag)
+		}
+
+		// Reading lists routes (protected)
+		lists := api.Group("/lists").Use(authHandler.AuthMiddleware())
+		{
+			lists.GET("/", readingListHandler.GetReadingLists)
+			lists.GET("/:id", readingListHandler.GetReadingList)
+			lists.GET("/:id/cite", citationHandler.CiteReadingList)
+			lists.POST("/", readingListHandler.CreateReadingList)
+			lists.PUT("/:id", readingListHandler.UpdateReadingList)
+			lists.DELETE("/:id", readingListHandler.DeleteReadingList)
+			lists.POST("/:id/entries", readingListHandler.AddEntry)
+			lists.PUT("/:id/entries/order", readingListHandler.ReorderEntries)
+			lists.PUT("/:id/entries/:entry_id", readingListHandler.UpdateEntry)
+			lists.DELETE("/:id/entries/:entry_id", readingListHandler.RemoveEntry)
+		}
+
+		// Import routes (protected)
+		imports := api.Group("/import").Use(authHandler.AuthMiddleware())
+		{
+			imports.POST("/marc", marcHandler.ImportMARC)
+			imports.POST("/books", importHandler.ImportBooks)
+			imports.POST("/readers", importHandler.ImportReaders)
+		}
+
+		// Works routes (protected)
+		works := api.Group("/works").Use(authHandler.AuthMiddleware())
+		{
+			works.GET("/", workHandler.GetWorks)
+			works.GET("/:id", workHandler.GetWork)
+			works.GET("/:id/availability", workHandler.GetWorkAvailability)
+			works.POST("/", workHandler.CreateWork)
+			works.PUT("/:id", workHandler.UpdateWork)
+			works.DELETE("/:id", workHandler.DeleteWork)
+			works.POST("/:id/editions", workHandler.AddEdition)
+			works.DELETE("/:id/editions/:book_id", workHandler.RemoveEdition)
+		}
+
+		// Series routes (protected)
+		series := api.Group("/series").Use(authHandler.AuthMiddleware())
+		{
+			series.GET("/", seriesHandler.GetSeries)
+			series.GET("/:id", seriesHandler.GetSeriesByID)
+			series.POST("/", seriesHandler.CreateSeries)
+			series.PUT("/:id", seriesHandler.UpdateSeries)
+			series.DELETE("/:id", seriesHandler.DeleteSeries)
+			series.PUT("/:id/works", seriesHandler.SetSeriesWork)
+			series.DELETE("/:id/works/:work_id", seriesHandler.RemoveSeriesWork)
+		}
+
+		// Holds routes (protected)
+		holds := api.Group("/holds").Use(authHandler.AuthMiddleware())
+		{
+			holds.GET("/", holdHandler.GetHolds)
+			holds.GET("/:id", holdHandler.GetHold)
+			holds.POST("/", holdHandler.CreateHold)
+			holds.DELETE("/:id", holdHandler.CancelHold)
+			holds.POST("/:id/fulfill", holdHandler.FulfillHold)
 		}
 
 		// Readers routes (protected)
 		readers := api.Group("/readers").Use(authHandler.AuthMiddleware())
 		{
 			readers.GET("/", readerHandler.GetReaders)
+			readers.GET("/search", readerHandler.SearchReaders)
+			readers.GET("/export.csv", exportHandler.ExportReaders)
+			readers.GET("/export.jsonl", exportHandler.ExportReaders)
+			readers.GET("/export.xlsx", exportHandler.ExportReaders)
 			readers.GET("/:id", readerHandler.GetReader)
 			readers.POST("/", readerHandler.CreateReader)
 			readers.PUT("/:id", readerHandler.UpdateReader)
@@ -87,6 +260,9 @@ func main() {
 		borrows := api.Group("/borrows").Use(authHandler.AuthMiddleware())
 		{
 			borrows.GET("/", borrowHandler.GetBorrows)
+			borrows.GET("/export.csv", exportHandler.ExportBorrows)
+			borrows.GET("/export.jsonl", exportHandler.ExportBorrows)
+			borrows.GET("/export.xlsx", exportHandler.ExportBorrows)
 			borrows.GET("/:id", borrowHandler.GetBorrow)
 			borrows.POST("/", borrowHandler.CreateBorrow)
 			borrows.PUT("/:id", borrowHandler.UpdateBorrow)
@@ -96,11 +272,43 @@ func main() {
 
 	// Root endpoint
 	r.GET("/", func(c *gin.Context) {
-		c.JSON(200, gin.H{
+		c.JSON(http.StatusOK, gin.H{
 			"message": "Welcome to Library Management API",
 		})
 	})
 
+	// Cover images and thumbnails (public, so they can be used in img tags)
+	r.GET("/covers/*key", coverHandler.ServeCover)
+
+	// OAI-PMH repository for union catalogues harvesting the books (public)
+	r.GET("/oai", oaiHandler.HandleRequest)
+	r.POST("/oai", oaiHandler.HandleRequest)
+
+	// SRU search for library clients and federated search (public)
+	r.GET("/sru", sruHandler.HandleRequest)
+	r.POST("/sru", sruHandler.HandleRequest)
+
+	// Books as schema.org JSON-LD or Dublin Core XML for search engines and
+	// linked data clients (public, so the identifiers in the records resolve)
+	r.GET("/books/:id", linkedDataHandler.GetBook)
+
+	// OPDS catalog for e-reader apps (public, like the covers): OPDS 1.2 Atom
+	// under /opds and the same feeds as OPDS 2.0 JSON under /opds/v2
+	r.GET("/opds/opensearch.xml", opdsHandler.OpenSearch)
+	for _, prefix := range []string{"/opds", "/opds/v2"} {
+		catalog := r.Group(prefix)
+		{
+			catalog.GET("", opdsHandler.Root)
+			catalog.GET("/new", opdsHandler.NewArrivals)
+			catalog.GET("/books", opdsHandler.GetBooks)
+			catalog.GET("/search", opdsHandler.Search)
+			catalog.GET("/authors", opdsHandler.GetAuthors)
+			catalog.GET("/authors/:id", opdsHandler.GetAuthorBooks)
+			catalog.GET("/subjects", opdsHandler.GetSubjects)
+			catalog.GET("/subjects/:id", opdsHandler.GetSubjectBooks)
+		}
+	}
+
 	// Dashboard endpoint (serving static files)
 	r.Static("/static", "./templates")
 	r.GET("/dashboard", func(c *gin.Context) {
@@ -113,6 +321,42 @@ func main() {
 		port = "8000"
 	}
 
+	// Serve self-check machines over SIP2 alongside the API when an address is configured
+	if config.SIPAddress != "" {
+		sipServer := &sip2.Server{Addr: config.SIPAddress, Handler: handlers.NewSIPHandler(), IdleTimeout: 10 * time.Minute}
+		go func() {
+			log.Fatal("SIP2 server failed: ", sipServer.ListenAndServe())
+		}()
+		fmt.Printf("SIP2 server listening on %s\n", config.SIPAddress)
+	}
+
 	fmt.Printf("Server starting on port %s\n", port)
 	log.Fatal(r.Run(":" + port))
+}
+
+// runCommand runs a maintenance command against the configured database
+func runCommand(command string) error {
+	switch command {
+	case "reindex":
+		// Rebuild the external search index from the Book table
+		state, err := search.Reindex(database.DB)
+		if err != nil {
+			return fmt.Errorf("reindex failed: %w", err)
+		}
+		fmt.Printf("Reindex completed: %d indexed, %d removed\n", state.Indexed, state.Removed)
+	case "check-index":
+		// Compare the external search index with the Book table
+		report, err := search.Check(database.DB)
+		if err != nil {
+			return fmt.Errorf("consistency check failed: %w", err)
+		}
+		fmt.Printf("%s index: %d books, %d documents, %d missing, %d stale, %d orphaned\n",
+			report.Backend, report.Books, report.Documents, len(report.Missing), len(report.Stale), len(report.Orphaned))
+		if !report.Consistent {
+			return fmt.Errorf("search index is out of sync, run the reindex command")
+		}
+	default:
+		return fmt.Errorf("unknown command %q, expected reindex or check-index", command)
+	}
+	return nil
 }
\ No newline at end of file
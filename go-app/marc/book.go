@@ -0,0 +1,381 @@
+package marc
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"library-go/models"
+)
+
+// Credit is a contributor named by a record
+type Credit struct {
+	Name string
+	Role string
+}
+
+// Entry is a book mapped from a record with its credits and the problems met
+type Entry struct {
+	Book     models.Book
+	Credits  []Credit
+	Warnings []string
+}
+
+// relators maps relator terms ($e) and codes ($4) to contributor roles
+var relators = map[string]string{
+	"author": models.RoleAuthor, "aut": models.RoleAuthor,
+	"editor": models.RoleEditor, "edt": models.RoleEditor, "ed": models.RoleEditor,
+	"translator": models.RoleTranslator, "trl": models.RoleTranslator, "tr": models.RoleTranslator,
+	"illustrator": models.RoleIllustrator, "ill": models.RoleIllustrator,
+}
+
+var (
+	yearPattern  = regexp.MustCompile(`\b(1\d{3}|2\d{3})\b`)
+	pagesPattern = regexp.MustCompile(`(?i)(\d+)\s*(?:p\b|pp\b|pages?\b|leaves\b|с\.)`)
+)
+
+// ToBook maps a bibliographic record to a book: 245 title, 100/110/111 and
+// 700/710 contributors, 020 ISBN, 250 edition, 260/264 publisher and year,
+// 300 page count, 041/008 language, 520 description and the leader and 008
+// for the format
+func ToBook(r *Record) Entry {
+	var entry Entry
+	book := &entry.Book
+	book.Copies = 1
+	warn := func(format string, args ...interface{}) {
+		entry.Warnings = append(entry.Warnings, fmt.Sprintf(format, args...))
+	}
+
+	if f := r.Get("245"); f != nil {
+		parts := []string{trimISBD(f.Sub('a'))}
+		if b := trimISBD(f.Sub('b')); b != "" {
+			parts = append(parts, ": "+b)
+		}
+		for _, p := range append(f.Subs('n'), f.Subs('p')...) {
+			if p = trimISBD(p); p != "" {
+				parts = append(parts, ". "+p)
+			}
+		}
+		book.Title = strings.TrimSpace(strings.Join(parts, " "))
+		book.Title = strings.ReplaceAll(book.Title, " . ", ". ")
+	}
+
+	for _, tag := range []string{"100", "110", "111", "700", "710"} {
+		for _, f := range r.All(tag) {
+			name := trimISBD(f.Sub('a'))
+			if name == "" {
+				continue
+			}
+			if tag == "100" || tag == "700" {
+				name = uninvertName(name, f.Ind1)
+			}
+			role, ok := relatorRole(&f)
+			if !ok {
+				warn("ignored %s contributor %q with unsupported relator", tag, name)
+				continue
+			}
+			entry.Credits = append(entry.Credits, Credit{Name: name, Role: role})
+		}
+	}
+	var authors []string
+	for _, c := range entry.Credits {
+		if c.Role == models.RoleAuthor {
+			authors = append(authors, c.Name)
+		}
+	}
+	book.Author = strings.Join(authors, ", ")
+	if book.Author == "" && len(entry.Credits) > 0 {
+		book.Author = entry.Credits[0].Name
+	}
+
+	for _, f := range r.All("020") {
+		raw := strings.Fields(f.Sub('a'))
+		if len(raw) == 0 {
+			continue
+		}
+		isbn, err := models.NormalizeISBN(raw[0])
+		if err != nil {
+			warn("ignored invalid ISBN %q", raw[0])
+			continue
+		}
+		book.ISBN = &isbn
+		break
+	}
+
+	if edition := trimISBD(r.Get("250").Sub('a')); edition != "" {
+		book.Edition = &edition
+	}
+
+	publication := r.Get("260")
+	for _, f := range r.All("264") {
+		if f.Ind2 == '1' {
+			f := f
+			publication = &f
+			break
+		}
+	}
+	if publisher := trimISBD(publication.Sub('b')); publisher != "" {
+		book.Publisher = &publisher
+	}
+	year := yearPattern.FindString(publication.Sub('c'))
+	fixed := ""
+	if f := r.Get("008"); f != nil {
+		fixed = f.Value
+	}
+	if year == "" && len(fixed) >= 11 {
+		year = yearPattern.FindString(fixed[7:11])
+	}
+	if year != "" {
+		y, _ := strconv.Atoi(year)
+		book.Year = &y
+	}
+
+	if m := pagesPattern.FindStringSubmatch(r.Get("300").Sub('a')); m != nil {
+		pages, _ := strconv.Atoi(m[1])
+		if pages > 0 {
+			book.PageCount = &pages
+		}
+	}
+
+	lang := r.Get("041").Sub('a')
+	if lang == "" && len(fixed) >= 38 {
+		lang = fixed[35:38]
+	}
+	if lang = strings.TrimSpace(strings.Trim(lang, "|")); lang != "" && lang != "und" {
+		if code, err := models.NormalizeLanguage(lang); err == nil {
+			book.Language = &code
+		} else {
+			warn("ignored unknown language code %q", lang)
+		}
+	}
+
+	if f := r.Get("520"); f != nil {
+		description := strings.TrimSpace(strings.Join(append(f.Subs('a'), f.Subs('b')...), " "))
+		if description != "" {
+			book.Description = &description
+		}
+	}
+
+	book.Format = recordFormat(r, fixed)
+
+	return entry
+}
+
+// FromBook builds a bibliographic record for a book. Contributors, when
+// loaded, give the 100 and 700 fields; otherwise the credit line is split.
+// Categories and tags, when loaded, become 650, 655 and 653 fields.
+func FromBook(book *models.Book) *Record {
+	record := &Record{Leader: "00000n" + string(recordType(book.Format)) + "m a2200000 i 4500"}
+	record.Control("001", strconv.FormatUint(uint64(book.ID), 10))
+	record.Control("005", book.UpdatedAt.UTC().Format("20060102150405")+".0")
+	record.Control("008", fixedField(book))
+
+	if book.ISBN != nil {
+		record.Data("020", ' ', ' ', Subfield{'a', *book.ISBN})
+	}
+	if book.Language != nil {
+		record.Data("041", '0', ' ', Subfield{'a', models.BibliographicLanguage(*book.Language)})
+	}
+
+	credits := bookCredits(book)
+	mainEntry := -1
+	for i, c := range credits {
+		if c.Role == models.RoleAuthor {
+			mainEntry = i
+			break
+		}
+	}
+	if mainEntry >= 0 {
+		record.Data("100", '1', ' ', Subfield{'a', invertName(credits[mainEntry].Name)})
+	}
+
+	titleSubfields := []Subfield{{'a', book.Title}}
+	if book.Author != "" {
+		titleSubfields[0].Value += " /"
+		titleSubfields = append(titleSubfields, Subfield{'c', book.Author + "."})
+	}
+	ind1 := byte('0')
+	if mainEntry >= 0 {
+		ind1 = '1'
+	}
+	record.Data("245", ind1, '0', titleSubfields...)
+
+	if book.Edition != nil {
+		record.Data("250", ' ', ' ', Subfield{'a', *book.Edition})
+	}
+	var publication []Subfield
+	if book.Publisher != nil {
+		publication = append(publication, Subfield{'b', *book.Publisher})
+	}
+	if book.Year != nil {
+		publication = append(publication, Subfield{'c', strconv.Itoa(*book.Year)})
+	}
+	record.Data("264", ' ', '1', publication...)
+	if book.PageCount != nil {
+		record.Data("300", ' ', ' ', Subfield{'a', strconv.Itoa(*book.PageCount) + " pages"})
+	}
+	if book.Description != nil {
+		record.Data("520", ' ', ' ', Subfield{'a', *book.Description})
+	}
+
+	for _, c := range book.Categories {
+		tag := "650"
+		if c.Kind == models.KindGenre {
+			tag = "655"
+		}
+		record.Data(tag, ' ', '4', Subfield{'a', c.Name})
+	}
+	for _, t := range book.Tags {
+		record.Data("653", ' ', ' ', Subfield{'a', t.Name})
+	}
+
+	for i, c := range credits {
+		if i == mainEntry {
+			continue
+		}
+		record.Data("700", '1', ' ', Subfield{'a', invertName(c.Name)}, Subfield{'e', c.Role})
+	}
+	return record
+}
+
+// bookCredits returns the contributors of a book in credit order
+func bookCredits(book *models.Book) []Credit {
+	var credits []Credit
+	for _, c := range book.Contributors {
+		if c.Author != nil {
+			credits = append(credits, Credit{Name: c.Author.Name, Role: c.Role})
+		}
+	}
+	if len(credits) == 0 {
+		for _, name := range models.SplitAuthorNames(book.Author) {
+			credits = append(credits, Credit{Name: name, Role: models.RoleAuthor})
+		}
+	}
+	return credits
+}
+
+// relatorRole maps the relator of a name field to a role; fields without one credit an author
+func relatorRole(f *Field) (string, bool) {
+	terms := append(f.Subs('e'), f.Subs('4')...)
+	if len(terms) == 0 {
+		return models.RoleAuthor, true
+	}
+	for _, term := range terms {
+		term = strings.ToLower(strings.Trim(term, " .,;:"))
+		if role, ok := relators[term]; ok {
+			return role, true
+		}
+	}
+	return "", false
+}
+
+// recordFormat derives the book format from the leader type of record and 008/23, form of item
+func recordFormat(r *Record, fixed string) string {
+	switch r.leaderAt(6) {
+	case 'i':
+		return models.FormatAudiobook
+	case 'g':
+		return models.FormatDVD
+	case 'm':
+		return models.FormatEbook
+	}
+	if len(fixed) > 23 {
+		switch fixed[23] {
+		case 'd':
+			return models.FormatLargePrint
+		case 'o', 'q', 's':
+			return models.FormatEbook
+		}
+	}
+	return models.FormatPrint
+}
+
+// recordType returns the leader type of record for a format
+func recordType(format string) byte {
+	switch format {
+	case models.FormatAudiobook:
+		return 'i'
+	case models.FormatDVD:
+		return 'g'
+	}
+	return 'a'
+}
+
+// fixedField builds the 40-character 008 field of a book
+func fixedField(book *models.Book) string {
+	dateType, date := "n", "uuuu"
+	if book.Year != nil {
+		dateType, date = "s", fmt.Sprintf("%04d", *book.Year)
+	}
+	form := " "
+	switch book.Format {
+	case models.FormatLargePrint:
+		form = "d"
+	case models.FormatEbook:
+		form = "o"
+	}
+	lang := "und"
+	if book.Language != nil {
+		if code := models.BibliographicLanguage(*book.Language); len(code) == 3 {
+			lang = code
+		}
+	}
+	return book.CreatedAt.UTC().Format("060102") + dateType + date + "    " + "xx " + "     " + form +
+		strings.Repeat(" ", 11) + lang + " d"
+}
+
+// trimISBD strips the ISBD punctuation cataloguers put at the end of subfields
+func trimISBD(s string) string {
+	s = strings.TrimSpace(s)
+	for {
+		trimmed := strings.TrimRight(s, " /:;=,")
+		if strings.HasSuffix(trimmed, ".") && !strings.HasSuffix(trimmed, "..") && !abbreviated(trimmed) {
+			trimmed = strings.TrimSuffix(trimmed, ".")
+		}
+		trimmed = strings.TrimSpace(trimmed)
+		if trimmed == s {
+			return s
+		}
+		s = trimmed
+	}
+}
+
+// abbreviations keep their period when ISBD punctuation is stripped
+var abbreviations = map[string]bool{
+	"jr": true, "sr": true, "ed": true, "eds": true, "co": true, "inc": true, "ltd": true, "st": true, "vol": true, "no": true,
+}
+
+// abbreviated reports whether s ends in an initial or abbreviation such as "Jr." or "ed."
+func abbreviated(s string) bool {
+	words := strings.Fields(s)
+	if len(words) == 0 {
+		return false
+	}
+	last := strings.TrimSuffix(words[len(words)-1], ".")
+	return len([]rune(last)) == 1 || abbreviations[strings.ToLower(last)]
+}
+
+// uninvertName turns a surname-first heading such as "Tolstoy, Leo" into "Leo Tolstoy"
+func uninvertName(name string, ind1 byte) string {
+	if ind1 != '1' {
+		return name
+	}
+	parts := strings.Split(name, ",")
+	if len(parts) != 2 {
+		return name
+	}
+	return strings.TrimSpace(parts[1]) + " " + strings.TrimSpace(parts[0])
+}
+
+// invertName turns "Leo Tolstoy" into the heading form "Tolstoy, Leo"
+func invertName(name string) string {
+	if strings.Contains(name, ",") {
+		return name
+	}
+	words := strings.Fields(name)
+	if len(words) < 2 {
+		return name
+	}
+	return words[len(words)-1] + ", " + strings.Join(words[:len(words)-1], " ")
+}
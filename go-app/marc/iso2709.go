@@ -0,0 +1,232 @@
+package marc
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"unicode/utf8"
+)
+
+// ISO 2709 delimiters
+const (
+	subfieldDelimiter = 0x1F
+	fieldTerminator   = 0x1E
+	recordTerminator  = 0x1D
+)
+
+// maxRecordLength is the largest record ISO 2709 can describe in its five-digit length
+const maxRecordLength = 99999
+
+// ErrRecordTooLong is returned when a record does not fit the ISO 2709 length fields
+var ErrRecordTooLong = errors.New("record exceeds the 99999 bytes ISO 2709 allows")
+
+// RecordError is a record that was read whole but could not be decoded; the
+// reader can go on with the records after it
+type RecordError struct {
+	Err error
+}
+
+func (e *RecordError) Error() string { return e.Err.Error() }
+
+func (e *RecordError) Unwrap() error { return e.Err }
+
+// Reader decodes a stream of ISO 2709 records
+type Reader struct {
+	r *bufio.Reader
+}
+
+// NewReader returns a reader of the ISO 2709 records in r
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: bufio.NewReader(r)}
+}
+
+// Read returns the next record, or io.EOF after the last one. Records in
+// MARC-8 are converted to UTF-8.
+func (d *Reader) Read() (*Record, error) {
+	// Skip line breaks some systems put between records
+	for {
+		b, err := d.r.Peek(1)
+		if err != nil {
+			return nil, err
+		}
+		if b[0] != '\n' && b[0] != '\r' && b[0] != ' ' {
+			break
+		}
+		d.r.ReadByte()
+	}
+
+	head := make([]byte, 5)
+	if _, err := io.ReadFull(d.r, head); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, errors.New("truncated record length")
+		}
+		return nil, err
+	}
+	length, ok := number(head)
+	if !ok || length < 26 {
+		return nil, fmt.Errorf("invalid record length %q", head)
+	}
+	data := make([]byte, length)
+	copy(data, head)
+	if _, err := io.ReadFull(d.r, data[5:]); err != nil {
+		return nil, errors.New("truncated record")
+	}
+	record, err := parseRecord(data)
+	if err != nil {
+		return nil, &RecordError{Err: err}
+	}
+	return record, nil
+}
+
+// parseRecord decodes one ISO 2709 record
+func parseRecord(data []byte) (*Record, error) {
+	leader := data[:24]
+	base, ok := number(leader[12:17])
+	if !ok || base < 25 || base > len(data) {
+		return nil, fmt.Errorf("invalid base address of data %q", leader[12:17])
+	}
+	marc8 := leader[9] != 'a'
+
+	record := &Record{Leader: string(leader)}
+	if marc8 {
+		// The record is re-encoded as UTF-8
+		record.Leader = record.Leader[:9] + "a" + record.Leader[10:]
+	}
+
+	directory := data[24 : base-1]
+	if len(directory)%12 != 0 {
+		return nil, errors.New("malformed directory")
+	}
+	for i := 0; i < len(directory); i += 12 {
+		entry := directory[i : i+12]
+		tag := string(entry[:3])
+		length, ok1 := number(entry[3:7])
+		start, ok2 := number(entry[7:12])
+		if !ok1 || !ok2 || base+start+length > len(data) || length < 1 {
+			return nil, fmt.Errorf("invalid directory entry for field %s", tag)
+		}
+		raw := data[base+start : base+start+length]
+		raw = bytes.TrimSuffix(raw, []byte{fieldTerminator})
+
+		if IsControl(tag) {
+			record.Fields = append(record.Fields, Field{Tag: tag, Value: decodeText(raw, marc8)})
+			continue
+		}
+
+		field := Field{Tag: tag, Ind1: ' ', Ind2: ' '}
+		if len(raw) >= 2 {
+			field.Ind1, field.Ind2 = raw[0], raw[1]
+			raw = raw[2:]
+		}
+		for _, part := range bytes.Split(raw, []byte{subfieldDelimiter})[1:] {
+			if len(part) == 0 {
+				continue
+			}
+			field.Subfields = append(field.Subfields, Subfield{Code: part[0], Value: decodeText(part[1:], marc8)})
+		}
+		record.Fields = append(record.Fields, field)
+	}
+	return record, nil
+}
+
+// number reads a fixed-width decimal field, which unlike strconv.Atoi allows
+// no sign, so offsets read from a record are never negative
+func number(field []byte) (int, bool) {
+	if len(field) == 0 {
+		return 0, false
+	}
+	n := 0
+	for _, b := range field {
+		if b < '0' || b > '9' {
+			return 0, false
+		}
+		n = n*10 + int(b-'0')
+	}
+	return n, true
+}
+
+// decodeText converts field data to a UTF-8 string
+func decodeText(raw []byte, marc8 bool) string {
+	if marc8 {
+		return DecodeMARC8(raw)
+	}
+	if !utf8.Valid(raw) {
+		return string(bytes.ToValidUTF8(raw, []byte("\uFFFD")))
+	}
+	return string(raw)
+}
+
+// Writer encodes records as UTF-8 ISO 2709
+type Writer struct {
+	w io.Writer
+}
+
+// NewWriter returns a writer of ISO 2709 records to w
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// Write encodes one record
+func (e *Writer) Write(r *Record) error {
+	data, err := Marshal(r)
+	if err != nil {
+		return err
+	}
+	_, err = e.w.Write(data)
+	return err
+}
+
+// Marshal encodes a record as UTF-8 ISO 2709, computing its leader lengths and directory
+func Marshal(r *Record) ([]byte, error) {
+	var directory, body bytes.Buffer
+	for _, f := range r.Fields {
+		start := body.Len()
+		if IsControl(f.Tag) {
+			body.WriteString(f.Value)
+		} else {
+			body.WriteByte(indicator(f.Ind1))
+			body.WriteByte(indicator(f.Ind2))
+			for _, s := range f.Subfields {
+				body.WriteByte(subfieldDelimiter)
+				body.WriteByte(s.Code)
+				body.WriteString(s.Value)
+			}
+		}
+		body.WriteByte(fieldTerminator)
+		length := body.Len() - start
+		if len(f.Tag) != 3 || length > 9999 {
+			return nil, fmt.Errorf("field %s cannot be encoded", f.Tag)
+		}
+		fmt.Fprintf(&directory, "%s%04d%05d", f.Tag, length, start)
+	}
+	directory.WriteByte(fieldTerminator)
+	body.WriteByte(recordTerminator)
+
+	base := 24 + directory.Len()
+	total := base + body.Len()
+	if total > maxRecordLength {
+		return nil, ErrRecordTooLong
+	}
+
+	leader := []byte(fmt.Sprintf("%-24s", r.Leader))[:24]
+	copy(leader[0:5], fmt.Sprintf("%05d", total))
+	leader[9] = 'a'
+	copy(leader[10:12], "22")
+	copy(leader[12:17], fmt.Sprintf("%05d", base))
+	copy(leader[20:24], "4500")
+
+	out := make([]byte, 0, total)
+	out = append(out, leader...)
+	out = append(out, directory.Bytes()...)
+	return append(out, body.Bytes()...), nil
+}
+
+// indicator maps an unset indicator to a blank
+func indicator(b byte) byte {
+	if b == 0 {
+		return ' '
+	}
+	return b
+}
@@ -0,0 +1,108 @@
+// Package marc reads and writes MARC 21 bibliographic records in ISO 2709
+// (binary) and MARCXML form, and maps them to and from books.
+package marc
+
+import "strings"
+
+// Subfield is one coded element of a data field
+type Subfield struct {
+	Code  byte
+	Value string
+}
+
+// Field is a control field (tags 001-009), which only has a Value, or a
+// data field with two indicators and subfields
+type Field struct {
+	Tag       string
+	Value     string
+	Ind1      byte
+	Ind2      byte
+	Subfields []Subfield
+}
+
+// Record is a MARC record: a 24-character leader and its fields in order
+type Record struct {
+	Leader string
+	Fields []Field
+}
+
+// IsControl reports whether a tag names a control field
+func IsControl(tag string) bool {
+	return strings.HasPrefix(tag, "00")
+}
+
+// Get returns the first field with the given tag, or nil
+func (r *Record) Get(tag string) *Field {
+	for i := range r.Fields {
+		if r.Fields[i].Tag == tag {
+			return &r.Fields[i]
+		}
+	}
+	return nil
+}
+
+// All returns every field with the given tag
+func (r *Record) All(tag string) []Field {
+	var fields []Field
+	for _, f := range r.Fields {
+		if f.Tag == tag {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// Control appends a control field
+func (r *Record) Control(tag, value string) {
+	r.Fields = append(r.Fields, Field{Tag: tag, Value: value})
+}
+
+// Data appends a data field, skipping subfields with empty values; a field
+// left without subfields is not added
+func (r *Record) Data(tag string, ind1, ind2 byte, subfields ...Subfield) {
+	var kept []Subfield
+	for _, s := range subfields {
+		if s.Value != "" {
+			kept = append(kept, s)
+		}
+	}
+	if len(kept) == 0 {
+		return
+	}
+	r.Fields = append(r.Fields, Field{Tag: tag, Ind1: ind1, Ind2: ind2, Subfields: kept})
+}
+
+// Sub returns the first value of a subfield, or ""
+func (f *Field) Sub(code byte) string {
+	if f == nil {
+		return ""
+	}
+	for _, s := range f.Subfields {
+		if s.Code == code {
+			return s.Value
+		}
+	}
+	return ""
+}
+
+// Subs returns every value of a subfield
+func (f *Field) Subs(code byte) []string {
+	if f == nil {
+		return nil
+	}
+	var values []string
+	for _, s := range f.Subfields {
+		if s.Code == code {
+			values = append(values, s.Value)
+		}
+	}
+	return values
+}
+
+// leaderAt returns the leader character at position i, or a blank when the leader is short
+func (r *Record) leaderAt(i int) byte {
+	if i < len(r.Leader) {
+		return r.Leader[i]
+	}
+	return ' '
+}
@@ -0,0 +1,132 @@
+package marc
+
+import (
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// MARC-8 character sets that can be designated by escape sequences
+const (
+	setASCII    = 'B'
+	setANSEL    = 'E'
+	setCyrillic = 'N' // Basic Cyrillic
+	setUnknown  = 0
+)
+
+// ansel maps the spacing characters of ANSEL, the MARC-8 extended Latin set
+var ansel = map[byte]rune{
+	0xA1: 'Ł', 0xA2: 'Ø', 0xA3: 'Đ', 0xA4: 'Þ', 0xA5: 'Æ', 0xA6: 'Œ', 0xA7: 'ʹ',
+	0xA8: '·', 0xA9: '♭', 0xAA: '®', 0xAB: '±', 0xAC: 'Ơ', 0xAD: 'Ư', 0xAE: 'ʼ',
+	0xB0: 'ʻ', 0xB1: 'ł', 0xB2: 'ø', 0xB3: 'đ', 0xB4: 'þ', 0xB5: 'æ', 0xB6: 'œ',
+	0xB7: 'ʺ', 0xB8: 'ı', 0xB9: '£', 0xBA: 'ð', 0xBC: 'ơ', 0xBD: 'ư',
+	0xC0: '°', 0xC1: 'ℓ', 0xC2: '℗', 0xC3: '©', 0xC4: '♯', 0xC5: '¿', 0xC6: '¡',
+	0xC7: 'ß', 0xC8: '€',
+}
+
+// anselCombining maps the ANSEL diacritics, which precede their base letter
+var anselCombining = map[byte]rune{
+	0xE0: '\u0309', 0xE1: '\u0300', 0xE2: '\u0301', 0xE3: '\u0302', 0xE4: '\u0303',
+	0xE5: '\u0304', 0xE6: '\u0306', 0xE7: '\u0307', 0xE8: '\u0308', 0xE9: '\u030C',
+	0xEA: '\u030A', 0xEB: '\uFE20', 0xEC: '\uFE21', 0xED: '\u0315', 0xEE: '\u030B',
+	0xEF: '\u0310', 0xF0: '\u0327', 0xF1: '\u0328', 0xF2: '\u0323', 0xF3: '\u0324',
+	0xF4: '\u0325', 0xF5: '\u0333', 0xF6: '\u0332', 0xF7: '\u0326', 0xF8: '\u031C',
+	0xF9: '\u032E', 0xFA: '\uFE22', 0xFB: '\uFE23', 0xFE: '\u0313',
+}
+
+// cyrillic lists the letters of the Basic Cyrillic set from 0x40
+var cyrillic = []rune("юабцдефгхийклмнопярстужвьызшэщчъЮАБЦДЕФГХИЙКЛМНОПЯРСТУЖВЬЫЗШЭЩЧ")
+
+// DecodeMARC8 converts MARC-8 text to UTF-8. ASCII, ANSEL and Basic Cyrillic
+// are supported; characters of other sets become U+FFFD.
+func DecodeMARC8(data []byte) string {
+	var out strings.Builder
+	var pending []rune // Diacritics waiting for their base letter
+	g0, g1 := byte(setASCII), byte(setANSEL)
+
+	emit := func(r rune) {
+		out.WriteRune(r)
+		for _, d := range pending {
+			out.WriteRune(d)
+		}
+		pending = pending[:0]
+	}
+
+	for i := 0; i < len(data); i++ {
+		b := data[i]
+		switch {
+		case b == 0x1B:
+			i += designate(data[i+1:], &g0, &g1)
+		case b == 0x88 || b == 0x89:
+			// Non-sort markers have no Unicode equivalent
+		case b == 0x8D:
+			emit('\u200D')
+		case b == 0x8E:
+			emit('\u200C')
+		case b < 0x20 || b == 0x7F:
+			emit(rune(b))
+		case b < 0x80:
+			switch {
+			case g0 == setASCII || b < 0x40:
+				emit(rune(b))
+			case g0 == setCyrillic && int(b-0x40) < len(cyrillic):
+				emit(cyrillic[b-0x40])
+			default:
+				emit('\uFFFD')
+			}
+		case b >= 0xA0 && g1 == setANSEL:
+			if r, ok := anselCombining[b]; ok {
+				pending = append(pending, r)
+			} else if r, ok := ansel[b]; ok {
+				emit(r)
+			} else {
+				emit('\uFFFD')
+			}
+		default:
+			emit('\uFFFD')
+		}
+	}
+	for _, d := range pending {
+		out.WriteRune(d)
+	}
+	return norm.NFC.String(out.String())
+}
+
+// designate applies the escape sequence at the start of seq and returns its length
+func designate(seq []byte, g0, g1 *byte) int {
+	if len(seq) == 0 {
+		return 0
+	}
+	switch seq[0] {
+	case 's':
+		// Technique 1 return to ASCII
+		*g0 = setASCII
+		return 1
+	case 'g', 'b', 'p':
+		// Greek symbols, subscripts and superscripts are not supported
+		*g0 = setUnknown
+		return 1
+	case '(', ',', ')', '-':
+		if len(seq) < 2 {
+			return len(seq)
+		}
+		set := seq[1]
+		if set != setASCII && set != setANSEL && set != setCyrillic {
+			set = setUnknown
+		}
+		if seq[0] == '(' || seq[0] == ',' {
+			*g0 = set
+		} else {
+			*g1 = set
+		}
+		return 2
+	case '$':
+		// Multibyte sets (East Asian) are not supported
+		*g0 = setUnknown
+		if len(seq) > 2 && (seq[1] == ',' || seq[1] == ')' || seq[1] == '-' || seq[1] == '(') {
+			return 3
+		}
+		return 2
+	}
+	return 0
+}
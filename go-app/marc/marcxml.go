@@ -0,0 +1,182 @@
+package marc
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+)
+
+// Namespace is the MARCXML namespace
+const Namespace = "http://www.loc.gov/MARC21/slim"
+
+type xmlRecord struct {
+	XMLName       xml.Name          `xml:"record"`
+	Leader        string            `xml:"leader"`
+	ControlFields []xmlControlField `xml:"controlfield"`
+	DataFields    []xmlDataField    `xml:"datafield"`
+}
+
+type xmlControlField struct {
+	Tag   string `xml:"tag,attr"`
+	Value string `xml:",chardata"`
+}
+
+type xmlDataField struct {
+	Tag       string        `xml:"tag,attr"`
+	Ind1      string        `xml:"ind1,attr"`
+	Ind2      string        `xml:"ind2,attr"`
+	Subfields []xmlSubfield `xml:"subfield"`
+}
+
+type xmlSubfield struct {
+	Code  string `xml:"code,attr"`
+	Value string `xml:",chardata"`
+}
+
+// XMLReader decodes the records of a MARCXML collection, or a single record document
+type XMLReader struct {
+	d *xml.Decoder
+}
+
+// NewXMLReader returns a reader of the MARCXML records in r
+func NewXMLReader(r io.Reader) *XMLReader {
+	return &XMLReader{d: xml.NewDecoder(r)}
+}
+
+// Read returns the next record, or io.EOF after the last one
+func (x *XMLReader) Read() (*Record, error) {
+	for {
+		token, err := x.d.Token()
+		if err != nil {
+			return nil, err
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "record" {
+			continue
+		}
+
+		var raw xmlRecord
+		if err := x.d.DecodeElement(&raw, &start); err != nil {
+			return nil, err
+		}
+		return raw.record()
+	}
+}
+
+// record converts a decoded MARCXML record, keeping control fields before data fields
+func (raw *xmlRecord) record() (*Record, error) {
+	record := &Record{Leader: raw.Leader}
+	for _, f := range raw.ControlFields {
+		if len(f.Tag) != 3 {
+			return nil, errors.New("controlfield without a valid tag")
+		}
+		record.Control(f.Tag, f.Value)
+	}
+	for _, f := range raw.DataFields {
+		if len(f.Tag) != 3 {
+			return nil, errors.New("datafield without a valid tag")
+		}
+		field := Field{Tag: f.Tag, Ind1: xmlIndicator(f.Ind1), Ind2: xmlIndicator(f.Ind2)}
+		for _, s := range f.Subfields {
+			if len(s.Code) != 1 {
+				return nil, errors.New("subfield without a valid code in field " + f.Tag)
+			}
+			field.Subfields = append(field.Subfields, Subfield{Code: s.Code[0], Value: s.Value})
+		}
+		record.Fields = append(record.Fields, field)
+	}
+	return record, nil
+}
+
+func xmlIndicator(s string) byte {
+	if s == "" {
+		return ' '
+	}
+	return s[0]
+}
+
+// XMLWriter encodes records as a MARCXML collection. Close must be called to end the document.
+type XMLWriter struct {
+	w       io.Writer
+	e       *xml.Encoder
+	started bool
+}
+
+// NewXMLWriter returns a writer of a MARCXML collection to w
+func NewXMLWriter(w io.Writer) *XMLWriter {
+	e := xml.NewEncoder(w)
+	e.Indent("", "  ")
+	return &XMLWriter{w: w, e: e}
+}
+
+// collectionStart is the opening tag of a MARCXML collection
+var collectionStart = xml.StartElement{
+	Name: xml.Name{Local: "collection"},
+	Attr: []xml.Attr{{Name: xml.Name{Local: "xmlns"}, Value: Namespace}},
+}
+
+// Write encodes one record into the collection
+func (x *XMLWriter) Write(r *Record) error {
+	if !x.started {
+		if _, err := io.WriteString(x.w, xml.Header); err != nil {
+			return err
+		}
+		if err := x.e.EncodeToken(collectionStart); err != nil {
+			return err
+		}
+		x.started = true
+	}
+	return x.e.Encode(toXML(r))
+}
+
+// Close ends the collection
+func (x *XMLWriter) Close() error {
+	if !x.started {
+		if _, err := io.WriteString(x.w, xml.Header); err != nil {
+			return err
+		}
+		if err := x.e.EncodeToken(collectionStart); err != nil {
+			return err
+		}
+	}
+	if err := x.e.EncodeToken(collectionStart.End()); err != nil {
+		return err
+	}
+	if err := x.e.Flush(); err != nil {
+		return err
+	}
+	_, err := io.WriteString(x.w, "\n")
+	return err
+}
+
+// MarshalXMLRecord encodes one record as a standalone MARCXML record element,
+// for embedding in other documents
+func MarshalXMLRecord(r *Record) ([]byte, error) {
+	var buf bytes.Buffer
+	start := xml.StartElement{
+		Name: xml.Name{Local: "record"},
+		Attr: []xml.Attr{{Name: xml.Name{Local: "xmlns"}, Value: Namespace}},
+	}
+	if err := xml.NewEncoder(&buf).EncodeElement(toXML(r), start); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// toXML converts a record to its MARCXML form
+func toXML(r *Record) *xmlRecord {
+	raw := &xmlRecord{Leader: r.Leader}
+	for _, f := range r.Fields {
+		if IsControl(f.Tag) {
+			raw.ControlFields = append(raw.ControlFields, xmlControlField{Tag: f.Tag, Value: f.Value})
+			continue
+		}
+		field := xmlDataField{Tag: f.Tag, Ind1: string(indicator(f.Ind1)), Ind2: string(indicator(f.Ind2))}
+		for _, s := range f.Subfields {
+			field.Subfields = append(field.Subfields, xmlSubfield{Code: string(s.Code), Value: s.Value})
+		}
+		raw.DataFields = append(raw.DataFields, field)
+	}
+	return raw
+}
@@ -0,0 +1,104 @@
+package metadata
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// maxNegativeTTL caps how long an ISBN no catalogue knows is remembered, as
+// catalogues keep adding new editions
+const maxNegativeTTL = time.Hour
+
+// Cache remembers the lookups of a provider, including ISBNs it did not find,
+// evicting the least recently used once it holds size entries. Errors other
+// than ErrNotFound are not cached.
+type Cache struct {
+	provider Provider
+	ttl      time.Duration
+	size     int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List // Most recently used first
+}
+
+type cacheEntry struct {
+	isbn    string
+	record  *Record // nil when the ISBN was not found
+	expires time.Time
+}
+
+// NewCache wraps a provider with a cache
+func NewCache(provider Provider, ttl time.Duration, size int) *Cache {
+	if size < 1 {
+		size = 1
+	}
+	return &Cache{provider: provider, ttl: ttl, size: size, entries: make(map[string]*list.Element), order: list.New()}
+}
+
+// Lookup returns the cached record of the ISBN, looking it up when it is not cached or has expired
+func (c *Cache) Lookup(ctx context.Context, isbn string) (*Record, error) {
+	if entry, ok := c.get(isbn); ok {
+		if entry.record == nil {
+			return nil, ErrNotFound
+		}
+		return entry.record.copy(), nil
+	}
+
+	record, err := c.provider.Lookup(ctx, isbn)
+	switch {
+	case err == nil:
+		c.put(&cacheEntry{isbn: isbn, record: record.copy(), expires: time.Now().Add(c.ttl)})
+	case errors.Is(err, ErrNotFound):
+		ttl := c.ttl
+		if ttl > maxNegativeTTL {
+			ttl = maxNegativeTTL
+		}
+		c.put(&cacheEntry{isbn: isbn, expires: time.Now().Add(ttl)})
+	}
+	return record, err
+}
+
+func (c *Cache) get(isbn string) (*cacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.entries[isbn]
+	if !ok {
+		return nil, false
+	}
+	entry := element.Value.(*cacheEntry)
+	if time.Now().After(entry.expires) {
+		c.order.Remove(element)
+		delete(c.entries, isbn)
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+	return entry, true
+}
+
+func (c *Cache) put(entry *cacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, ok := c.entries[entry.isbn]; ok {
+		element.Value = entry
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[entry.isbn] = c.order.PushFront(entry)
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).isbn)
+	}
+}
+
+// copy returns a copy of the record, so callers cannot change a cached one
+func (r *Record) copy() *Record {
+	clone := *r
+	clone.Authors = append([]string(nil), r.Authors...)
+	clone.Sources = append([]string(nil), r.Sources...)
+	return &clone
+}
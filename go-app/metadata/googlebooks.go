@@ -0,0 +1,90 @@
+package metadata
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"library-go/models"
+)
+
+// GoogleBooks looks up ISBNs with the Google Books volumes API
+type GoogleBooks struct {
+	baseURL string
+	apiKey  string // Optional, raises the request quota
+	client  *http.Client
+}
+
+// NewGoogleBooks returns a provider for the Google Books API at baseURL
+func NewGoogleBooks(baseURL, apiKey string) *GoogleBooks {
+	return &GoogleBooks{baseURL: strings.TrimRight(baseURL, "/"), apiKey: apiKey, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Lookup searches the volume with the ISBN
+func (g *GoogleBooks) Lookup(ctx context.Context, isbn string) (*Record, error) {
+	query := url.Values{"q": {"isbn:" + isbn}}
+	if g.apiKey != "" {
+		query.Set("key", g.apiKey)
+	}
+	var result struct {
+		Items []struct {
+			VolumeInfo struct {
+				Title               string   `json:"title"`
+				Subtitle            string   `json:"subtitle"`
+				Authors             []string `json:"authors"`
+				Publisher           string   `json:"publisher"`
+				PublishedDate       string   `json:"publishedDate"`
+				Description         string   `json:"description"`
+				PageCount           int      `json:"pageCount"`
+				Language            string   `json:"language"`
+				IndustryIdentifiers []struct {
+					Type       string `json:"type"`
+					Identifier string `json:"identifier"`
+				} `json:"industryIdentifiers"`
+				ImageLinks struct {
+					Thumbnail string `json:"thumbnail"`
+				} `json:"imageLinks"`
+			} `json:"volumeInfo"`
+		} `json:"items"`
+	}
+	if err := getJSON(ctx, g.client, g.baseURL+"/volumes?"+query.Encode(), &result); err != nil {
+		return nil, fmt.Errorf("google books: %w", err)
+	}
+
+	// Searches can match other editions, so only a volume carrying the ISBN counts
+	for _, item := range result.Items {
+		volume := item.VolumeInfo
+		matches := false
+		for _, id := range volume.IndustryIdentifiers {
+			if normalized, err := models.NormalizeISBN(id.Identifier); err == nil && normalized == isbn {
+				matches = true
+			}
+		}
+		if !matches || volume.Title == "" {
+			continue
+		}
+
+		record := &Record{
+			ISBN:        isbn,
+			Title:       joinTitle(volume.Title, volume.Subtitle),
+			Authors:     volume.Authors,
+			Year:        parseYear(volume.PublishedDate),
+			Publisher:   optional(volume.Publisher),
+			Description: optional(volume.Description),
+			CoverURL:    optional(volume.ImageLinks.Thumbnail),
+			Sources:     []string{"googlebooks"},
+		}
+		if volume.PageCount > 0 {
+			record.PageCount = &volume.PageCount
+		}
+		// Languages come as BCP 47 tags such as "en" or "zh-CN"
+		if language, _, _ := strings.Cut(volume.Language, "-"); language != "" {
+			record.Language = &language
+		}
+		return record, nil
+	}
+	return nil, ErrNotFound
+}
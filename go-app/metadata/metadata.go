@@ -0,0 +1,192 @@
+// Package metadata looks up bibliographic data of books by ISBN in external
+// catalogues, so staff do not have to type it in.
+package metadata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"library-go/config"
+)
+
+var (
+	// ErrNotFound is returned when no catalogue knows the ISBN
+	ErrNotFound = errors.New("no metadata found for this ISBN")
+	// ErrDisabled is returned when no provider is configured
+	ErrDisabled = errors.New("metadata lookup is disabled")
+)
+
+// Record is what the catalogues know about an edition
+type Record struct {
+	ISBN        string   `json:"isbn"`
+	Title       string   `json:"title,omitempty"` // Includes the subtitle
+	Authors     []string `json:"authors,omitempty"`
+	Year        *int     `json:"year,omitempty"`
+	Publisher   *string  `json:"publisher,omitempty"`
+	PageCount   *int     `json:"page_count,omitempty"`
+	Language    *string  `json:"language,omitempty"` // ISO 639 code, in the form the provider uses
+	Description *string  `json:"description,omitempty"`
+	CoverURL    *string  `json:"cover_url,omitempty"`
+	Sources     []string `json:"sources"` // Providers the data came from
+}
+
+// Provider looks up an ISBN, given as its 13 digits, in one catalogue
+type Provider interface {
+	// Lookup returns the record of the ISBN, or ErrNotFound
+	Lookup(ctx context.Context, isbn string) (*Record, error)
+}
+
+var provider Provider
+
+// Setup builds the providers selected by the configuration, with a cache in front
+func Setup() error {
+	p, err := Open()
+	if err != nil {
+		return err
+	}
+	provider = p
+	return nil
+}
+
+// Open returns the providers named in config.MetadataProviders, in order,
+// behind a cache; nil when lookups are disabled
+func Open() (Provider, error) {
+	var chain Chain
+	for _, name := range strings.Split(config.MetadataProviders, ",") {
+		switch strings.TrimSpace(name) {
+		case "openlibrary":
+			chain = append(chain, NewOpenLibrary(config.OpenLibraryURL))
+		case "googlebooks":
+			chain = append(chain, NewGoogleBooks(config.GoogleBooksURL, config.GoogleBooksAPIKey))
+		case "none", "":
+		default:
+			return nil, fmt.Errorf("unknown metadata provider %q, expected openlibrary, googlebooks or none", name)
+		}
+	}
+	if len(chain) == 0 {
+		return nil, nil
+	}
+	ttl := time.Duration(config.MetadataCacheHours) * time.Hour
+	return NewCache(chain, ttl, config.MetadataCacheSize), nil
+}
+
+// Lookup looks up an ISBN with the configured providers
+func Lookup(ctx context.Context, isbn string) (*Record, error) {
+	if provider == nil {
+		return nil, ErrDisabled
+	}
+	return provider.Lookup(ctx, isbn)
+}
+
+// Chain asks each provider in turn, filling the fields earlier ones left
+// empty, until the record is complete
+type Chain []Provider
+
+// Lookup merges the records of the providers. Errors are only returned when
+// no provider found the ISBN.
+func (c Chain) Lookup(ctx context.Context, isbn string) (*Record, error) {
+	var record *Record
+	var lastErr error
+	for _, p := range c {
+		found, err := p.Lookup(ctx, isbn)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if record == nil {
+			record = found
+		} else {
+			record.merge(found)
+		}
+		if record.complete() {
+			break
+		}
+	}
+	if record != nil {
+		return record, nil
+	}
+	if lastErr != nil {
+		return nil, lastErr
+	}
+	return nil, ErrNotFound
+}
+
+// merge fills the empty fields of r from other
+func (r *Record) merge(other *Record) {
+	used := false
+	fill := func(empty bool) bool {
+		used = used || empty
+		return empty
+	}
+	if fill(r.Title == "" && other.Title != "") {
+		r.Title = other.Title
+	}
+	if fill(len(r.Authors) == 0 && len(other.Authors) > 0) {
+		r.Authors = other.Authors
+	}
+	if fill(r.Year == nil && other.Year != nil) {
+		r.Year = other.Year
+	}
+	if fill(r.Publisher == nil && other.Publisher != nil) {
+		r.Publisher = other.Publisher
+	}
+	if fill(r.PageCount == nil && other.PageCount != nil) {
+		r.PageCount = other.PageCount
+	}
+	if fill(r.Language == nil && other.Language != nil) {
+		r.Language = other.Language
+	}
+	if fill(r.Description == nil && other.Description != nil) {
+		r.Description = other.Description
+	}
+	if fill(r.CoverURL == nil && other.CoverURL != nil) {
+		r.CoverURL = other.CoverURL
+	}
+	if used {
+		r.Sources = append(r.Sources, other.Sources...)
+	}
+}
+
+// complete reports whether every field of the record is known
+func (r *Record) complete() bool {
+	return r.Title != "" && len(r.Authors) > 0 && r.Year != nil && r.Publisher != nil &&
+		r.PageCount != nil && r.Language != nil && r.Description != nil && r.CoverURL != nil
+}
+
+var yearPattern = regexp.MustCompile(`\b\d{4}\b`)
+
+// parseYear finds the year in a publication date such as "May 2003" or "2003-05-01"
+func parseYear(date string) *int {
+	match := yearPattern.FindString(date)
+	if match == "" {
+		return nil
+	}
+	year, _ := strconv.Atoi(match)
+	return &year
+}
+
+// optional returns a trimmed string, or nil when it is blank
+func optional(s string) *string {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// joinTitle appends a subtitle to a title the way catalogues display them
+func joinTitle(title, subtitle string) string {
+	title, subtitle = strings.TrimSpace(title), strings.TrimSpace(subtitle)
+	if subtitle == "" {
+		return title
+	}
+	return title + ": " + subtitle
+}
@@ -0,0 +1,92 @@
+package metadata
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// maxResponseBytes caps the size of a catalogue response
+const maxResponseBytes = 1 << 20
+
+// OpenLibrary looks up ISBNs with the Open Library books API
+type OpenLibrary struct {
+	baseURL string
+	client  *http.Client
+}
+
+// NewOpenLibrary returns a provider for the Open Library instance at baseURL
+func NewOpenLibrary(baseURL string) *OpenLibrary {
+	return &OpenLibrary{baseURL: strings.TrimRight(baseURL, "/"), client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Lookup fetches the edition data of the ISBN
+func (o *OpenLibrary) Lookup(ctx context.Context, isbn string) (*Record, error) {
+	key := "ISBN:" + isbn
+	query := url.Values{"bibkeys": {key}, "format": {"json"}, "jscmd": {"data"}}
+	var editions map[string]struct {
+		Title         string                  `json:"title"`
+		Subtitle      string                  `json:"subtitle"`
+		Authors       []struct{ Name string } `json:"authors"`
+		Publishers    []struct{ Name string } `json:"publishers"`
+		PublishDate   string                  `json:"publish_date"`
+		NumberOfPages int                     `json:"number_of_pages"`
+		Cover         struct {
+			Large string `json:"large"`
+		} `json:"cover"`
+	}
+	if err := getJSON(ctx, o.client, o.baseURL+"/api/books?"+query.Encode(), &editions); err != nil {
+		return nil, fmt.Errorf("open library: %w", err)
+	}
+
+	edition, ok := editions[key]
+	if !ok || edition.Title == "" {
+		return nil, ErrNotFound
+	}
+	record := &Record{
+		ISBN:     isbn,
+		Title:    joinTitle(edition.Title, edition.Subtitle),
+		Year:     parseYear(edition.PublishDate),
+		CoverURL: optional(edition.Cover.Large),
+		Sources:  []string{"openlibrary"},
+	}
+	for _, author := range edition.Authors {
+		if name := strings.TrimSpace(author.Name); name != "" {
+			record.Authors = append(record.Authors, name)
+		}
+	}
+	if len(edition.Publishers) > 0 {
+		record.Publisher = optional(edition.Publishers[0].Name)
+	}
+	if edition.NumberOfPages > 0 {
+		record.PageCount = &edition.NumberOfPages
+	}
+	return record, nil
+}
+
+// getJSON fetches and decodes a JSON document; a 404 is ErrNotFound
+func getJSON(ctx context.Context, client *http.Client, target string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, maxResponseBytes)).Decode(v)
+}
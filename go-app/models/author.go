@@ -0,0 +1,168 @@
+package models
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Contributor roles
+const (
+	RoleAuthor      = "author"
+	RoleEditor      = "editor"
+	RoleTranslator  = "translator"
+	RoleIllustrator = "illustrator"
+)
+
+// ContributorRoles lists the roles a contributor can have on a book
+var ContributorRoles = []string{RoleAuthor, RoleEditor, RoleTranslator, RoleIllustrator}
+
+type Author struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Name      string    `json:"name" gorm:"not null;index"`
+	Bio       *string   `json:"bio,omitempty"` // Optional biography
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// Relationship with books
+	Contributions []Contribution `json:"-" gorm:"foreignKey:AuthorID"`
+}
+
+// Contribution links an author to a book in a given role
+type Contribution struct {
+	ID       uint   `json:"-" gorm:"primaryKey"`
+	BookID   uint   `json:"-" gorm:"not null;uniqueIndex:idx_contribution"`
+	AuthorID uint   `json:"author_id" gorm:"not null;index;uniqueIndex:idx_contribution"`
+	Role     string `json:"role" gorm:"not null;default:author;uniqueIndex:idx_contribution"`
+	Position int    `json:"position"` // Order of the contributor in the book's credits
+
+	// Relationships
+	Author *Author `json:"author,omitempty" gorm:"foreignKey:AuthorID"`
+	Book   *Book   `json:"-" gorm:"foreignKey:BookID"`
+}
+
+// Validate validates the author data
+func (a *Author) Validate() error {
+	if strings.TrimSpace(a.Name) == "" {
+		return errors.New("name cannot be empty")
+	}
+	if len(a.Name) > 200 {
+		return errors.New("name must be less than 200 characters")
+	}
+	a.Name = strings.TrimSpace(a.Name)
+
+	if a.Bio != nil && len(*a.Bio) > 5000 {
+		return errors.New("bio must be less than 5000 characters")
+	}
+
+	return nil
+}
+
+// BeforeCreate is a GORM hook that runs before creating an author
+func (a *Author) BeforeCreate(tx *gorm.DB) error {
+	return a.Validate()
+}
+
+// BeforeUpdate is a GORM hook that runs before updating an author
+func (a *Author) BeforeUpdate(tx *gorm.DB) error {
+	return a.Validate()
+}
+
+// Validate validates the contribution role
+func (c *Contribution) Validate() error {
+	if c.Role == "" {
+		c.Role = RoleAuthor
+	}
+	if !IsContributorRole(c.Role) {
+		return errors.New("role must be one of: " + strings.Join(ContributorRoles, ", "))
+	}
+	return nil
+}
+
+// BeforeCreate is a GORM hook that runs before creating a contribution
+func (c *Contribution) BeforeCreate(tx *gorm.DB) error {
+	return c.Validate()
+}
+
+// IsContributorRole reports whether role is a known contributor role
+func IsContributorRole(role string) bool {
+	for _, r := range ContributorRoles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// authorSeparators split a free-text author string into names
+var authorSeparators = regexp.MustCompile(`\s*[;&]\s*|\s+(?:and|и)\s+`)
+
+// SplitAuthorNames splits a free-text author string such as "Ilf & Petrov" into
+// names. Commas only separate names when every part has several words, so an
+// inverted "Tolstoy, Leo" stays one name.
+func SplitAuthorNames(text string) []string {
+	var names []string
+	for _, part := range authorSeparators.Split(text, -1) {
+		pieces := strings.Split(part, ",")
+		splitCommas := len(pieces) > 1
+		for _, piece := range pieces {
+			if len(strings.Fields(piece)) < 2 {
+				splitCommas = false
+				break
+			}
+		}
+		if !splitCommas {
+			pieces = []string{part}
+		}
+		for _, piece := range pieces {
+			if name := strings.Join(strings.Fields(piece), " "); name != "" {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// CreditLine formats contributions ordered by position into the display form
+// stored in Book.Author: the authors' names, or every contributor's name when
+// the book has no author (an anthology credited to its editor, say). Names are
+// joined with semicolons so that SplitAuthorNames reads the line back, as it
+// keeps "Ilf, Petrov" together as an inverted name.
+func CreditLine(contributions []Contribution) string {
+	var authors, others []string
+	for _, c := range contributions {
+		if c.Author == nil {
+			continue
+		}
+		if c.Role == RoleAuthor {
+			authors = append(authors, c.Author.Name)
+		} else {
+			others = append(others, c.Author.Name)
+		}
+	}
+	if len(authors) == 0 {
+		authors = others
+	}
+	return strings.Join(authors, "; ")
+}
+
+// FindOrCreateAuthor returns the author with the given name, ignoring case, creating it if needed
+func FindOrCreateAuthor(tx *gorm.DB, name string) (*Author, error) {
+	name = strings.Join(strings.Fields(name), " ")
+	var author Author
+	err := tx.Where("LOWER(name) = LOWER(?)", name).Order("id").First(&author).Error
+	if err == nil {
+		return &author, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	author = Author{Name: name}
+	if err := tx.Create(&author).Error; err != nil {
+		return nil, err
+	}
+	return &author, nil
+}
@@ -3,13 +3,24 @@ package models
 import (
 	"errors"
 	"fmt"
-	"regexp"
 	"strings"
 	"time"
 
 	"gorm.io/gorm"
 )
 
+// Book formats
+const (
+	FormatPrint      = "print"
+	FormatLargePrint = "large_print"
+	FormatAudiobook  = "audiobook"
+	FormatEbook      = "ebook"
+	FormatDVD        = "dvd"
+)
+
+// BookFormats lists the formats a book can be held in
+var BookFormats = []string{FormatPrint, FormatLargePrint, FormatAudiobook, FormatEbook, FormatDVD}
+
 type Book struct {
 	ID          uint      `json:"id" gorm:"primaryKey"`
 	Title       string    `json:"title" gorm:"not null"`
@@ -18,11 +29,26 @@ type Book struct {
 	ISBN        *string   `json:"isbn,omitempty" gorm:"unique"` // Unique ISBN, optional
 	Copies      int       `json:"copies" gorm:"default:1"` // Number of copies available
 	Description *string   `json:"description,omitempty"` // Added for the second migration
+	Publisher   *string   `json:"publisher,omitempty" gorm:"index"`
+	Language    *string   `json:"language,omitempty" gorm:"index"` // ISO 639 code, see NormalizeLanguage
+	Edition     *string   `json:"edition,omitempty"` // Edition statement, such as "2nd ed."
+	PageCount   *int      `json:"page_count,omitempty"`
+	Format      string    `json:"format" gorm:"not null;default:print;index"` // One of BookFormats
+	WorkID      *uint     `json:"work_id,omitempty" gorm:"index"` // Work this book is an edition of
+	ISBN13      *string   `json:"isbn_13,omitempty" gorm:"-"` // Hyphenated ISBN-13, for display
+	ISBN10      *string   `json:"isbn_10,omitempty" gorm:"-"` // Hyphenated ISBN-10, when the ISBN has one
+	Cover       *string   `json:"-"` // Storage key of the cover image
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 	
 	// Relationship with borrows
 	Borrows []Borrow `json:"-" gorm:"foreignKey:BookID"`
+	// Contributors in credit order, Author holds their display form
+	Contributors []Contribution `json:"contributors,omitempty" gorm:"foreignKey:BookID"`
+	// Subjects and genres the book is classified under
+	Categories []Category `json:"categories,omitempty" gorm:"many2many:book_categories"`
+	// Free-form labels
+	Tags []Tag `json:"tags,omitempty" gorm:"many2many:book_tags"`
 }
 
 // Validate validates the book data
@@ -53,11 +79,13 @@ func (b *Book) Validate() error {
 		}
 	}
 
-	// Validate ISBN if provided
+	// Validate ISBN if provided, storing it as a bare ISBN-13
 	if b.ISBN != nil {
-		if err := validateISBN(*b.ISBN); err != nil {
+		isbn, err := NormalizeISBN(*b.ISBN)
+		if err != nil {
 			return err
 		}
+		b.ISBN = &isbn
 	}
 
 	// Validate copies
@@ -70,26 +98,72 @@ func (b *Book) Validate() error {
 		return errors.New("description must be less than 2000 characters")
 	}
 
-	return nil
-}
+	// Validate publisher and edition if provided, dropping blank ones
+	b.Publisher = trimOptional(b.Publisher)
+	if b.Publisher != nil && len(*b.Publisher) > 200 {
+		return errors.New("publisher must be less than 200 characters")
+	}
+	b.Edition = trimOptional(b.Edition)
+	if b.Edition != nil && len(*b.Edition) > 100 {
+		return errors.New("edition must be less than 100 characters")
+	}
 
-// validateISBN validates the ISBN format
-func validateISBN(isbn string) error {
-	// Remove hyphens and spaces
-	cleanISBN := regexp.MustCompile(`[-\s]`).ReplaceAllString(isbn, "")
-	
-	if len(cleanISBN) != 10 && len(cleanISBN) != 13 {
-		return errors.New("ISBN must be either 10 or 13 characters long")
+	// Validate language if provided, storing its canonical ISO 639 code
+	b.Language = trimOptional(b.Language)
+	if b.Language != nil {
+		code, err := NormalizeLanguage(*b.Language)
+		if err != nil {
+			return err
+		}
+		b.Language = &code
 	}
 
-	// Check if all characters are digits (for basic validation)
-	if !isNumeric(cleanISBN) && !(len(cleanISBN) == 10 && isNumeric(cleanISBN[:9]) && (cleanISBN[9] == 'X' || cleanISBN[9] == 'x' || isNumeric(cleanISBN[9:10]))) {
-		return errors.New("invalid ISBN format")
+	// Validate page count if provided
+	if b.PageCount != nil && (*b.PageCount < 1 || *b.PageCount > 100000) {
+		return errors.New("page count must be between 1 and 100000")
 	}
 
+	// Validate format, print by default
+	if b.Format == "" {
+		b.Format = FormatPrint
+	}
+	format, err := NormalizeFormat(b.Format)
+	if err != nil {
+		return err
+	}
+	b.Format = format
+
 	return nil
 }
 
+// NormalizeFormat maps a format name such as "Large print" or "e-book" to its BookFormats value
+func NormalizeFormat(format string) (string, error) {
+	key := strings.Map(func(r rune) rune {
+		if r == ' ' || r == '-' || r == '_' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(strings.TrimSpace(format)))
+	for _, f := range BookFormats {
+		if key == strings.ReplaceAll(f, "_", "") {
+			return f, nil
+		}
+	}
+	return "", errors.New("format must be one of: " + strings.Join(BookFormats, ", "))
+}
+
+// trimOptional trims an optional string, returning nil when it is blank
+func trimOptional(s *string) *string {
+	if s == nil {
+		return nil
+	}
+	trimmed := strings.TrimSpace(*s)
+	if trimmed == "" {
+		return nil
+	}
+	return &trimmed
+}
+
 // isNumeric checks if a string contains only numeric characters
 func isNumeric(s string) bool {
 	for _, r := range s {
@@ -108,4 +182,37 @@ func (b *Book) BeforeCreate(tx *gorm.DB) error {
 // BeforeUpdate is a GORM hook that runs before updating a book
 func (b *Book) BeforeUpdate(tx *gorm.DB) error {
 	return b.Validate()
-}
\ No newline at end of file
+}
+
+// AfterSave is a GORM hook that fills the display forms of the ISBN
+func (b *Book) AfterSave(tx *gorm.DB) error {
+	b.setISBNForms()
+	return nil
+}
+
+// AfterFind is a GORM hook that fills the display forms of the ISBN
+func (b *Book) AfterFind(tx *gorm.DB) error {
+	b.setISBNForms()
+	return nil
+}
+
+// setISBNForms derives the hyphenated ISBN-13 and ISBN-10 from the stored ISBN
+func (b *Book) setISBNForms() {
+	b.ISBN13, b.ISBN10 = nil, nil
+	if b.ISBN == nil || len(*b.ISBN) != 13 {
+		return
+	}
+	isbn13 := HyphenateISBN(*b.ISBN)
+	b.ISBN13 = &isbn13
+	if isbn10, ok := HyphenateISBN10(*b.ISBN); ok {
+		b.ISBN10 = &isbn10
+	}
+}
+
+// DeletedBook is the tombstone of a deleted book, so harvesters copying the
+// catalogue can be told the record is gone
+type DeletedBook struct {
+	BookID    uint      `json:"book_id" gorm:"primaryKey;autoIncrement:false"`
+	Sets      string    `json:"sets"` // OAI-PMH set specs the book was in, space-separated with spaces around
+	DeletedAt time.Time `json:"deleted_at" gorm:"not null;index"`
+}
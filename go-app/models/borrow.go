@@ -14,6 +14,8 @@ type Borrow struct {
 	BorrowedAt  time.Time `json:"borrowed_at" gorm:"not null;default:CURRENT_TIMESTAMP"`
 	ReturnedAt  *time.Time `json:"returned_at,omitempty"` // nil if not returned yet
 	IsReturned  bool      `json:"is_returned" gorm:"default:false"`
+	RenewedAt   *time.Time `json:"renewed_at,omitempty"` // Last renewal, which restarts the loan period
+	Renewals    int       `json:"renewals" gorm:"not null;default:0"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 	
@@ -0,0 +1,85 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"library-go/fuzzy"
+)
+
+// Category kinds
+const (
+	KindSubject = "subject"
+	KindGenre   = "genre"
+)
+
+// Category is a node of the subject or genre tree books are classified under
+type Category struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	Name        string    `json:"name" gorm:"not null"`
+	Slug        string    `json:"slug" gorm:"uniqueIndex;not null"` // URL-safe identifier, derived from the name by default
+	Kind        string    `json:"kind" gorm:"not null;default:subject;index"`
+	ParentID    *uint     `json:"parent_id,omitempty" gorm:"index"` // nil for top-level categories
+	Description *string   `json:"description,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+
+	// Relationships
+	Parent *Category `json:"-" gorm:"foreignKey:ParentID"`
+	Books  []Book    `json:"-" gorm:"many2many:book_categories"`
+}
+
+// Validate validates the category data
+func (c *Category) Validate() error {
+	if strings.TrimSpace(c.Name) == "" {
+		return errors.New("name cannot be empty")
+	}
+	if len(c.Name) > 100 {
+		return errors.New("name must be less than 100 characters")
+	}
+	c.Name = strings.TrimSpace(c.Name)
+
+	if strings.TrimSpace(c.Slug) == "" {
+		c.Slug = Slugify(c.Name)
+	} else {
+		c.Slug = Slugify(c.Slug)
+	}
+	if c.Slug == "" {
+		return errors.New("slug must contain letters or digits")
+	}
+
+	if c.Kind == "" {
+		c.Kind = KindSubject
+	}
+	if c.Kind != KindSubject && c.Kind != KindGenre {
+		return errors.New("kind must be subject or genre")
+	}
+
+	if c.ParentID != nil && *c.ParentID == c.ID && c.ID != 0 {
+		return errors.New("a category cannot be its own parent")
+	}
+
+	if c.Description != nil && len(*c.Description) > 2000 {
+		return errors.New("description must be less than 2000 characters")
+	}
+
+	return nil
+}
+
+// BeforeCreate is a GORM hook that runs before creating a category
+func (c *Category) BeforeCreate(tx *gorm.DB) error {
+	return c.Validate()
+}
+
+// BeforeUpdate is a GORM hook that runs before updating a category
+func (c *Category) BeforeUpdate(tx *gorm.DB) error {
+	return c.Validate()
+}
+
+// Slugify turns a name into a lower-case, hyphenated Latin identifier ("Научная фантастика" -> "nauchnaya-fantastika")
+func Slugify(name string) string {
+	return strings.Join(fuzzy.Words(name), "-")
+}
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Hold statuses
+const (
+	HoldWaiting   = "waiting"
+	HoldFulfilled = "fulfilled"
+	HoldCancelled = "cancelled"
+)
+
+// Hold queues a reader for a specific edition, or for any edition of a work
+type Hold struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	ReaderID    uint       `json:"reader_id" gorm:"not null;index"`
+	BookID      *uint      `json:"book_id,omitempty" gorm:"index"` // Set for a hold on one edition
+	WorkID      *uint      `json:"work_id,omitempty" gorm:"index"` // Set for a hold on any edition
+	Status      string     `json:"status" gorm:"not null;default:waiting;index"`
+	BorrowID    *uint      `json:"borrow_id,omitempty"` // Borrow that fulfilled the hold
+	FulfilledAt *time.Time `json:"fulfilled_at,omitempty"`
+	CancelledAt *time.Time `json:"cancelled_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+
+	// Relationships
+	Reader *Reader `json:"reader,omitempty" gorm:"foreignKey:ReaderID"`
+	Book   *Book   `json:"book,omitempty" gorm:"foreignKey:BookID"`
+	Work   *Work   `json:"work,omitempty" gorm:"foreignKey:WorkID"`
+}
+
+// Validate validates the hold data
+func (h *Hold) Validate() error {
+	if h.ReaderID == 0 {
+		return errors.New("reader_id is required")
+	}
+	if (h.BookID == nil) == (h.WorkID == nil) {
+		return errors.New("a hold needs either a book_id or a work_id")
+	}
+	switch h.Status {
+	case "":
+		h.Status = HoldWaiting
+	case HoldWaiting, HoldFulfilled, HoldCancelled:
+	default:
+		return errors.New("status must be waiting, fulfilled or cancelled")
+	}
+	return nil
+}
+
+// BeforeCreate is a GORM hook that runs before creating a hold
+func (h *Hold) BeforeCreate(tx *gorm.DB) error {
+	return h.Validate()
+}
+
+// BeforeUpdate is a GORM hook that runs before updating a hold
+func (h *Hold) BeforeUpdate(tx *gorm.DB) error {
+	return h.Validate()
+}
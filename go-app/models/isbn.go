@@ -0,0 +1,192 @@
+package models
+
+import (
+	"errors"
+	"strings"
+)
+
+// NormalizeISBN validates an ISBN-10 or ISBN-13 with or without hyphens and
+// returns its canonical form: the 13 digits of the ISBN-13
+func NormalizeISBN(isbn string) (string, error) {
+	clean := strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(strings.TrimSpace(isbn)))
+
+	switch len(clean) {
+	case 10:
+		if !isNumeric(clean[:9]) || !(isNumeric(clean[9:]) || clean[9] == 'X') {
+			return "", errors.New("invalid ISBN format")
+		}
+		if isbn10CheckDigit(clean[:9]) != clean[9] {
+			return "", errors.New("invalid ISBN-10 check digit")
+		}
+		return ISBN10To13(clean)
+	case 13:
+		if !isNumeric(clean) {
+			return "", errors.New("invalid ISBN format")
+		}
+		if !strings.HasPrefix(clean, "978") && !strings.HasPrefix(clean, "979") {
+			return "", errors.New("ISBN-13 must start with 978 or 979")
+		}
+		if isbn13CheckDigit(clean[:12]) != clean[12] {
+			return "", errors.New("invalid ISBN-13 check digit")
+		}
+		return clean, nil
+	}
+	return "", errors.New("ISBN must be either 10 or 13 characters long")
+}
+
+// ISBN10To13 converts an ISBN-10 without hyphens to an ISBN-13
+func ISBN10To13(isbn10 string) (string, error) {
+	if len(isbn10) != 10 || !isNumeric(isbn10[:9]) {
+		return "", errors.New("invalid ISBN-10")
+	}
+	body := "978" + isbn10[:9]
+	return body + string(isbn13CheckDigit(body)), nil
+}
+
+// ISBN13To10 converts an ISBN-13 without hyphens to an ISBN-10, which only
+// exists for the 978 prefix
+func ISBN13To10(isbn13 string) (string, error) {
+	if len(isbn13) != 13 || !isNumeric(isbn13) {
+		return "", errors.New("invalid ISBN-13")
+	}
+	if !strings.HasPrefix(isbn13, "978") {
+		return "", errors.New("only 978 ISBNs have an ISBN-10 form")
+	}
+	body := isbn13[3:12]
+	return body + string(isbn10CheckDigit(body)), nil
+}
+
+func isbn10CheckDigit(body string) byte {
+	sum := 0
+	for i := 0; i < 9; i++ {
+		sum += int(body[i]-'0') * (10 - i)
+	}
+	check := (11 - sum%11) % 11
+	if check == 10 {
+		return 'X'
+	}
+	return byte('0' + check)
+}
+
+func isbn13CheckDigit(body string) byte {
+	sum := 0
+	for i := 0; i < 12; i++ {
+		digit := int(body[i] - '0')
+		if i%2 == 1 {
+			digit *= 3
+		}
+		sum += digit
+	}
+	return byte('0' + (10-sum%10)%10)
+}
+
+// registrantRange maps a range of the 7 digits following a registration group
+// to the length of the registrant element
+type registrantRange struct {
+	from, to string
+	length   int
+}
+
+// registrationGroups lists the registrant ranges of the most common
+// registration groups, from the International ISBN Agency range message
+var registrationGroups = map[string][]registrantRange{
+	"978-0": {
+		{"0000000", "1999999", 2}, {"2000000", "6999999", 3}, {"7000000", "8499999", 4},
+		{"8500000", "8999999", 5}, {"9000000", "9499999", 6}, {"9500000", "9999999", 7},
+	},
+	"978-1": {
+		{"0000000", "0999999", 2}, {"1000000", "3999999", 3}, {"4000000", "5499999", 4},
+		{"5500000", "8697999", 5}, {"8698000", "9989999", 6}, {"9990000", "9999999", 7},
+	},
+	"978-2": {
+		{"0000000", "1999999", 2}, {"2000000", "3499999", 3}, {"3500000", "3999999", 5},
+		{"4000000", "6999999", 3}, {"7000000", "8399999", 4}, {"8400000", "8999999", 5},
+		{"9000000", "9499999", 6}, {"9500000", "9999999", 7},
+	},
+	"978-3": {
+		{"0000000", "0299999", 2}, {"0300000", "0339999", 3}, {"0340000", "0369999", 4},
+		{"0370000", "0399999", 5}, {"0400000", "1999999", 2}, {"2000000", "6999999", 3},
+		{"7000000", "8499999", 4}, {"8500000", "8999999", 5}, {"9000000", "9499999", 6},
+		{"9500000", "9539999", 7}, {"9540000", "9699999", 5}, {"9700000", "9849999", 7},
+		{"9850000", "9999999", 5},
+	},
+	"978-4": {
+		{"0000000", "1999999", 2}, {"2000000", "6999999", 3}, {"7000000", "8499999", 4},
+		{"8500000", "8999999", 5}, {"9000000", "9499999", 6}, {"9500000", "9999999", 7},
+	},
+	"978-5": {
+		{"0000000", "0049999", 5}, {"0050000", "0099999", 4}, {"0100000", "1999999", 2},
+		{"2000000", "4209999", 3}, {"4210000", "4299999", 4}, {"4300000", "4309999", 3},
+		{"4310000", "4399999", 4}, {"4400000", "4409999", 3}, {"4410000", "4499999", 4},
+		{"4500000", "6039999", 3}, {"6040000", "6049999", 7}, {"6050000", "6999999", 3},
+		{"7000000", "8499999", 4}, {"8500000", "8999999", 5}, {"9000000", "9099999", 6},
+		{"9100000", "9199999", 5}, {"9200000", "9299999", 4}, {"9300000", "9499999", 5},
+		{"9500000", "9500999", 7}, {"9501000", "9799999", 4}, {"9800000", "9899999", 5},
+		{"9900000", "9909999", 7}, {"9910000", "9999999", 4},
+	},
+	"979-10": {
+		{"0000000", "1999999", 2}, {"2000000", "6999999", 3}, {"7000000", "8999999", 4},
+		{"9000000", "9759999", 5}, {"9760000", "9999999", 6},
+	},
+}
+
+// groupLength returns the length of the registration group element that follows the prefix
+func groupLength(prefix, rest string) int {
+	if prefix == "979" {
+		if rest[0] == '8' {
+			return 1
+		}
+		return 2
+	}
+	switch {
+	case rest[0] <= '5' || rest[0] == '7':
+		return 1
+	case rest[:3] >= "600" && rest[:3] <= "649":
+		return 3
+	case rest[:2] == "65":
+		return 2
+	case rest[:2] >= "80" && rest[:2] <= "94":
+		return 2
+	case rest[:3] >= "950" && rest[:3] <= "989":
+		return 3
+	case rest[:4] >= "9900" && rest[:4] <= "9989":
+		return 4
+	}
+	return 5
+}
+
+// HyphenateISBN formats a canonical ISBN-13 as prefix-group-registrant-publication-check.
+// When the registrant ranges of a group are unknown, the registrant and
+// publication elements are left joined.
+func HyphenateISBN(isbn13 string) string {
+	if len(isbn13) != 13 || !isNumeric(isbn13) {
+		return isbn13
+	}
+
+	prefix, rest, check := isbn13[:3], isbn13[3:12], isbn13[12:]
+	group := rest[:groupLength(prefix, rest)]
+	body := rest[len(group):]
+
+	ranges, ok := registrationGroups[prefix+"-"+group]
+	if ok {
+		key := (body + "0000000")[:7]
+		for _, r := range ranges {
+			if key >= r.from && key <= r.to && r.length < len(body) {
+				return strings.Join([]string{prefix, group, body[:r.length], body[r.length:], check}, "-")
+			}
+		}
+	}
+	return strings.Join([]string{prefix, group, body, check}, "-")
+}
+
+// HyphenateISBN10 formats the ISBN-10 form of a canonical ISBN-13, if it has one
+func HyphenateISBN10(isbn13 string) (string, bool) {
+	isbn10, err := ISBN13To10(isbn13)
+	if err != nil {
+		return "", false
+	}
+	// The ISBN-10 shares the group, registrant and publication elements of the 978 form
+	parts := strings.SplitN(HyphenateISBN(isbn13), "-", 2)
+	hyphenated := parts[1]
+	return hyphenated[:len(hyphenated)-1] + isbn10[9:], true
+}
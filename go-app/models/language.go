@@ -0,0 +1,51 @@
+package models
+
+import (
+	"errors"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// bibliographicCodes maps the ISO 639-2/B codes, still used by library
+// catalogues, to their ISO 639-2/T equivalents
+var bibliographicCodes = map[string]string{
+	"alb": "sqi", "arm": "hye", "baq": "eus", "bur": "mya", "chi": "zho",
+	"cze": "ces", "dut": "nld", "fre": "fra", "geo": "kat", "ger": "deu",
+	"gre": "ell", "ice": "isl", "mac": "mkd", "mao": "mri", "may": "msa",
+	"per": "fas", "rum": "ron", "slo": "slk", "tib": "bod", "wel": "cym",
+}
+
+// NormalizeLanguage validates an ISO 639 language code, given in its two-letter
+// or either three-letter form, and returns its canonical form: the ISO 639-1
+// code when the language has one, the ISO 639-2/T code otherwise
+func NormalizeLanguage(code string) (string, error) {
+	code = strings.ToLower(strings.TrimSpace(code))
+	if terminology, ok := bibliographicCodes[code]; ok {
+		code = terminology
+	}
+	if len(code) != 2 && len(code) != 3 {
+		return "", errors.New("language must be an ISO 639 code such as en or eng")
+	}
+	base, err := language.ParseBase(code)
+	if err != nil {
+		return "", errors.New("language must be an ISO 639 code such as en or eng")
+	}
+	return base.String(), nil
+}
+
+// BibliographicLanguage returns the ISO 639-2/B code of a canonical language
+// code, the form MARC records use
+func BibliographicLanguage(code string) string {
+	base, err := language.ParseBase(code)
+	if err != nil {
+		return ""
+	}
+	iso3 := base.ISO3()
+	for bibliographic, terminology := range bibliographicCodes {
+		if terminology == iso3 {
+			return bibliographic
+		}
+	}
+	return iso3
+}
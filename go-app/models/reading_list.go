@@ -0,0 +1,82 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ReadingList is an ordered, curated selection of books with notes
+type ReadingList struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	Title       string    `json:"title" gorm:"not null"`
+	Description *string   `json:"description,omitempty"`
+	IsPublished bool      `json:"is_published" gorm:"default:false"`
+	CreatedByID uint      `json:"created_by_id" gorm:"index"` // User who created the list
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+
+	// Relationship with entries
+	Entries []ReadingListEntry `json:"entries,omitempty" gorm:"foreignKey:ReadingListID"`
+}
+
+// ReadingListEntry places a book on a reading list
+type ReadingListEntry struct {
+	ID            uint      `json:"id" gorm:"primaryKey"`
+	ReadingListID uint      `json:"reading_list_id" gorm:"not null;uniqueIndex:idx_reading_list_book"`
+	BookID        uint      `json:"book_id" gorm:"not null;uniqueIndex:idx_reading_list_book"`
+	Position      int       `json:"position"` // Zero-based order within the list
+	Note          *string   `json:"note,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+
+	// Relationship with book
+	Book *Book `json:"book,omitempty" gorm:"foreignKey:BookID"`
+}
+
+// Validate validates the reading list data
+func (l *ReadingList) Validate() error {
+	if strings.TrimSpace(l.Title) == "" {
+		return errors.New("title cannot be empty")
+	}
+	if len(l.Title) > 200 {
+		return errors.New("title must be less than 200 characters")
+	}
+	l.Title = strings.TrimSpace(l.Title)
+
+	if l.Description != nil && len(*l.Description) > 5000 {
+		return errors.New("description must be less than 5000 characters")
+	}
+
+	return nil
+}
+
+// BeforeCreate is a GORM hook that runs before creating a reading list
+func (l *ReadingList) BeforeCreate(tx *gorm.DB) error {
+	return l.Validate()
+}
+
+// BeforeUpdate is a GORM hook that runs before updating a reading list
+func (l *ReadingList) BeforeUpdate(tx *gorm.DB) error {
+	return l.Validate()
+}
+
+// Validate validates the entry note
+func (e *ReadingListEntry) Validate() error {
+	if e.Note != nil && len(*e.Note) > 2000 {
+		return errors.New("note must be less than 2000 characters")
+	}
+	return nil
+}
+
+// BeforeCreate is a GORM hook that runs before creating an entry
+func (e *ReadingListEntry) BeforeCreate(tx *gorm.DB) error {
+	return e.Validate()
+}
+
+// BeforeUpdate is a GORM hook that runs before updating an entry
+func (e *ReadingListEntry) BeforeUpdate(tx *gorm.DB) error {
+	return e.Validate()
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Tag is a free-form label librarians attach to books, such as "staff pick"
+type Tag struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Name      string    `json:"name" gorm:"uniqueIndex;not null"` // Lower-case, single-spaced
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// Relationship with books
+	Books []Book `json:"-" gorm:"many2many:book_tags"`
+}
+
+// Validate validates and normalizes the tag name
+func (t *Tag) Validate() error {
+	t.Name = NormalizeTagName(t.Name)
+	if t.Name == "" {
+		return errors.New("tag name cannot be empty")
+	}
+	if len(t.Name) > 50 {
+		return errors.New("tag name must be less than 50 characters")
+	}
+	return nil
+}
+
+// BeforeCreate is a GORM hook that runs before creating a tag
+func (t *Tag) BeforeCreate(tx *gorm.DB) error {
+	return t.Validate()
+}
+
+// BeforeUpdate is a GORM hook that runs before updating a tag
+func (t *Tag) BeforeUpdate(tx *gorm.DB) error {
+	return t.Validate()
+}
+
+// NormalizeTagName lower-cases a tag name and collapses its whitespace, so
+// "Summer  Reading" and "summer reading" are the same tag
+func NormalizeTagName(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
@@ -0,0 +1,108 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Work is the abstract creation that editions and translations (Book rows) realize
+type Work struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	Title       string    `json:"title" gorm:"not null"`
+	Description *string   `json:"description,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+
+	// Relationships
+	Editions []Book        `json:"editions,omitempty" gorm:"foreignKey:WorkID"`
+	Series   []SeriesEntry `json:"series,omitempty" gorm:"foreignKey:WorkID"`
+}
+
+// Series is an ordered sequence of works, such as a trilogy
+type Series struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	Name        string    `json:"name" gorm:"not null"`
+	Description *string   `json:"description,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+
+	// Relationship with works
+	Entries []SeriesEntry `json:"entries,omitempty" gorm:"foreignKey:SeriesID"`
+}
+
+// SeriesEntry places a work in a series. A work can belong to several series.
+type SeriesEntry struct {
+	ID       uint     `json:"-" gorm:"primaryKey"`
+	SeriesID uint     `json:"series_id" gorm:"not null;uniqueIndex:idx_series_work"`
+	WorkID   uint     `json:"work_id" gorm:"not null;index;uniqueIndex:idx_series_work"`
+	Volume   *float64 `json:"volume,omitempty"` // Volume number, fractional for novellas between volumes
+
+	// Relationships
+	Series *Series `json:"series,omitempty" gorm:"foreignKey:SeriesID"`
+	Work   *Work   `json:"work,omitempty" gorm:"foreignKey:WorkID"`
+}
+
+// Validate validates the work data
+func (w *Work) Validate() error {
+	if strings.TrimSpace(w.Title) == "" {
+		return errors.New("title cannot be empty")
+	}
+	if len(w.Title) > 500 {
+		return errors.New("title must be less than 500 characters")
+	}
+	w.Title = strings.TrimSpace(w.Title)
+
+	if w.Description != nil && len(*w.Description) > 2000 {
+		return errors.New("description must be less than 2000 characters")
+	}
+
+	return nil
+}
+
+// BeforeCreate is a GORM hook that runs before creating a work
+func (w *Work) BeforeCreate(tx *gorm.DB) error {
+	return w.Validate()
+}
+
+// BeforeUpdate is a GORM hook that runs before updating a work
+func (w *Work) BeforeUpdate(tx *gorm.DB) error {
+	return w.Validate()
+}
+
+// Validate validates the series data
+func (s *Series) Validate() error {
+	if strings.TrimSpace(s.Name) == "" {
+		return errors.New("name cannot be empty")
+	}
+	if len(s.Name) > 200 {
+		return errors.New("name must be less than 200 characters")
+	}
+	s.Name = strings.TrimSpace(s.Name)
+
+	if s.Description != nil && len(*s.Description) > 2000 {
+		return errors.New("description must be less than 2000 characters")
+	}
+
+	return nil
+}
+
+// BeforeCreate is a GORM hook that runs before creating a series
+func (s *Series) BeforeCreate(tx *gorm.DB) error {
+	return s.Validate()
+}
+
+// BeforeUpdate is a GORM hook that runs before updating a series
+func (s *Series) BeforeUpdate(tx *gorm.DB) error {
+	return s.Validate()
+}
+
+// Validate validates the volume number
+func (e *SeriesEntry) Validate() error {
+	if e.Volume != nil && *e.Volume < 0 {
+		return errors.New("volume cannot be negative")
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+// Package oai implements the protocol side of an OAI-PMH 2.0 repository:
+// datestamps, resumption tokens and the XML responses. Which records exist
+// and how they are described is left to the caller.
+package oai
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// Namespaces of OAI-PMH documents and their schemas
+const (
+	Namespace       = "http://www.openarchives.org/OAI/2.0/"
+	Schema          = "http://www.openarchives.org/OAI/2.0/OAI-PMH.xsd"
+	DCNamespace     = "http://www.openarchives.org/OAI/2.0/oai_dc/"
+	DCSchema        = "http://www.openarchives.org/OAI/2.0/oai_dc.xsd"
+	identifierNS    = "http://www.openarchives.org/OAI/2.0/oai-identifier"
+	identifierXSD   = "http://www.openarchives.org/OAI/2.0/oai-identifier.xsd"
+	xsiNS           = "http://www.w3.org/2001/XMLSchema-instance"
+	protocolVersion = "2.0"
+)
+
+// Granularity is the datestamp granularity of the repository, in OAI-PMH notation
+const Granularity = "YYYY-MM-DDThh:mm:ssZ"
+
+// Layouts of datestamps with second and day granularity
+const (
+	secondLayout = "2006-01-02T15:04:05Z"
+	dayLayout    = "2006-01-02"
+)
+
+// Error codes defined by the protocol
+const (
+	BadArgument             = "badArgument"
+	BadResumptionToken      = "badResumptionToken"
+	BadVerb                 = "badVerb"
+	CannotDisseminateFormat = "cannotDisseminateFormat"
+	IDDoesNotExist          = "idDoesNotExist"
+	NoRecordsMatch          = "noRecordsMatch"
+	NoMetadataFormats       = "noMetadataFormats"
+	NoSetHierarchy          = "noSetHierarchy"
+)
+
+// Error is an OAI-PMH error, reported in the response rather than by HTTP status
+type Error struct {
+	Code    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+// NewError returns an error with the given code
+func NewError(code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+// Identify describes the repository
+type Identify struct {
+	RepositoryName       string
+	AdminEmails          []string
+	EarliestDatestamp    time.Time
+	DeletedRecord        string // no, transient or persistent
+	RepositoryIdentifier string // Namespace of the oai-identifier scheme, a domain name
+	SampleIdentifier     string
+}
+
+// MetadataFormat is a format records can be disseminated in
+type MetadataFormat struct {
+	Prefix    string
+	Schema    string
+	Namespace string
+}
+
+// Set is a group of records harvesters can select; specs are colon-separated
+// paths, so "fiction:fantasy" is part of "fiction"
+type Set struct {
+	Spec string
+	Name string
+}
+
+// Header identifies a record; deleted records only have a header
+type Header struct {
+	Identifier string
+	Datestamp  time.Time
+	Sets       []string
+	Deleted    bool
+}
+
+// Record is a header with its metadata, an XML element, absent for deleted records
+type Record struct {
+	Header   Header
+	Metadata []byte
+}
+
+// ResumptionToken continues an incomplete list. An empty Value marks the
+// last part of a list that took several requests.
+type ResumptionToken struct {
+	Value            string
+	CompleteListSize int64
+	Cursor           int
+}
+
+// FormatDatestamp formats a time as a datestamp of the repository granularity
+func FormatDatestamp(t time.Time) string {
+	return t.UTC().Format(secondLayout)
+}
+
+// ParseDatestamp parses a from or until argument, which may have day or
+// second granularity; day reports the former
+func ParseDatestamp(s string) (t time.Time, day bool, err error) {
+	if t, err := time.Parse(secondLayout, s); err == nil {
+		return t, false, nil
+	}
+	if t, err := time.Parse(dayLayout, s); err == nil {
+		return t, true, nil
+	}
+	return time.Time{}, false, NewError(BadArgument, "Invalid datestamp "+s+", expected YYYY-MM-DD or YYYY-MM-DDThh:mm:ssZ")
+}
+
+// Token is the state of a list request carried from one response to the next
+type Token struct {
+	Prefix string     `json:"p"`
+	Set    string     `json:"s,omitempty"`
+	From   *time.Time `json:"f,omitempty"`
+	Before *time.Time `json:"b,omitempty"` // Exclusive upper bound, until plus its granularity
+	Phase  int        `json:"h,omitempty"` // Caller-defined part of the list being walked
+	After  uint       `json:"a,omitempty"` // ID of the last record returned in that part
+	Cursor int        `json:"c,omitempty"` // Number of records returned so far
+}
+
+// Encode returns the token in the opaque form given to harvesters
+func (t Token) Encode() string {
+	data, _ := json.Marshal(t)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeToken reads a token returned by Encode
+func DecodeToken(s string) (Token, error) {
+	var t Token
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err == nil {
+		err = json.Unmarshal(data, &t)
+	}
+	if err == nil && (t.Prefix == "" || t.Cursor < 0) {
+		err = errors.New("incomplete token")
+	}
+	if err != nil {
+		return Token{}, NewError(BadResumptionToken, "The resumption token is invalid")
+	}
+	return t, nil
+}
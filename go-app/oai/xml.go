@@ -0,0 +1,255 @@
+package oai
+
+import (
+	"encoding/xml"
+	"io"
+	"sort"
+	"time"
+
+	"library-go/dublincore"
+)
+
+// Verbs of the protocol
+const (
+	VerbIdentify            = "Identify"
+	VerbListMetadataFormats = "ListMetadataFormats"
+	VerbListSets            = "ListSets"
+	VerbListIdentifiers     = "ListIdentifiers"
+	VerbListRecords         = "ListRecords"
+	VerbGetRecord           = "GetRecord"
+)
+
+// Response is the answer to one request. Errors, when present, replace the
+// verb's content; otherwise the fields the verb uses are written.
+type Response struct {
+	Date    time.Time
+	BaseURL string
+	Verb    string
+	Args    map[string]string // Arguments of the request, without the verb
+	Errors  []*Error
+
+	Identify *Identify
+	Formats  []MetadataFormat // ListMetadataFormats
+	Sets     []Set            // ListSets
+	Headers  []Header         // ListIdentifiers
+	Records  []Record         // ListRecords and GetRecord
+	Token    *ResumptionToken // ListIdentifiers and ListRecords
+}
+
+type envelope struct {
+	XMLName        xml.Name       `xml:"OAI-PMH"`
+	Xmlns          string         `xml:"xmlns,attr"`
+	XmlnsXSI       string         `xml:"xmlns:xsi,attr"`
+	SchemaLocation string         `xml:"xsi:schemaLocation,attr"`
+	ResponseDate   string         `xml:"responseDate"`
+	Request        requestElement `xml:"request"`
+	Errors         []errorElement `xml:"error"`
+	Content        interface{}
+}
+
+type requestElement struct {
+	Attrs   []xml.Attr `xml:",any,attr"`
+	BaseURL string     `xml:",chardata"`
+}
+
+type errorElement struct {
+	Code    string `xml:"code,attr"`
+	Message string `xml:",chardata"`
+}
+
+type identifyElement struct {
+	XMLName           xml.Name `xml:"Identify"`
+	RepositoryName    string   `xml:"repositoryName"`
+	BaseURL           string   `xml:"baseURL"`
+	ProtocolVersion   string   `xml:"protocolVersion"`
+	AdminEmails       []string `xml:"adminEmail"`
+	EarliestDatestamp string   `xml:"earliestDatestamp"`
+	DeletedRecord     string   `xml:"deletedRecord"`
+	Granularity       string   `xml:"granularity"`
+	Description       *struct {
+		Identifier oaiIdentifier `xml:"oai-identifier"`
+	} `xml:"description"`
+}
+
+type oaiIdentifier struct {
+	Xmlns                string `xml:"xmlns,attr"`
+	SchemaLocation       string `xml:"xsi:schemaLocation,attr"`
+	Scheme               string `xml:"scheme"`
+	RepositoryIdentifier string `xml:"repositoryIdentifier"`
+	Delimiter            string `xml:"delimiter"`
+	SampleIdentifier     string `xml:"sampleIdentifier"`
+}
+
+type formatsElement struct {
+	XMLName xml.Name        `xml:"ListMetadataFormats"`
+	Formats []formatElement `xml:"metadataFormat"`
+}
+
+type formatElement struct {
+	Prefix    string `xml:"metadataPrefix"`
+	Schema    string `xml:"schema"`
+	Namespace string `xml:"metadataNamespace"`
+}
+
+type setsElement struct {
+	XMLName xml.Name     `xml:"ListSets"`
+	Sets    []setElement `xml:"set"`
+}
+
+type setElement struct {
+	Spec string `xml:"setSpec"`
+	Name string `xml:"setName"`
+}
+
+type headerElement struct {
+	Status     string   `xml:"status,attr,omitempty"`
+	Identifier string   `xml:"identifier"`
+	Datestamp  string   `xml:"datestamp"`
+	Sets       []string `xml:"setSpec"`
+}
+
+type recordElement struct {
+	Header   headerElement    `xml:"header"`
+	Metadata *metadataElement `xml:"metadata"`
+}
+
+type metadataElement struct {
+	Inner []byte `xml:",innerxml"`
+}
+
+type tokenElement struct {
+	CompleteListSize int64  `xml:"completeListSize,attr"`
+	Cursor           int    `xml:"cursor,attr"`
+	Value            string `xml:",chardata"`
+}
+
+type listElement struct {
+	XMLName xml.Name
+	Headers []headerElement `xml:"header"`
+	Records []recordElement `xml:"record"`
+	Token   *tokenElement   `xml:"resumptionToken"`
+}
+
+// Write writes the response as an OAI-PMH document
+func Write(w io.Writer, r *Response) error {
+	doc := envelope{
+		Xmlns:          Namespace,
+		XmlnsXSI:       xsiNS,
+		SchemaLocation: Namespace + " " + Schema,
+		ResponseDate:   FormatDatestamp(r.Date),
+		Request:        requestElement{BaseURL: r.BaseURL},
+	}
+
+	// The request is only echoed when it was legal, so never for badVerb or badArgument
+	echo := true
+	for _, err := range r.Errors {
+		doc.Errors = append(doc.Errors, errorElement{Code: err.Code, Message: err.Message})
+		if err.Code == BadVerb || err.Code == BadArgument {
+			echo = false
+		}
+	}
+	if echo {
+		doc.Request.Attrs = requestAttrs(r.Verb, r.Args)
+	}
+	if len(r.Errors) == 0 {
+		doc.Content = content(r)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func requestAttrs(verb string, args map[string]string) []xml.Attr {
+	attrs := []xml.Attr{{Name: xml.Name{Local: "verb"}, Value: verb}}
+	names := make([]string, 0, len(args))
+	for name := range args {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		attrs = append(attrs, xml.Attr{Name: xml.Name{Local: name}, Value: args[name]})
+	}
+	return attrs
+}
+
+// content returns the element of the verb
+func content(r *Response) interface{} {
+	switch r.Verb {
+	case VerbIdentify:
+		id := r.Identify
+		el := identifyElement{
+			RepositoryName:    id.RepositoryName,
+			BaseURL:           r.BaseURL,
+			ProtocolVersion:   protocolVersion,
+			AdminEmails:       id.AdminEmails,
+			EarliestDatestamp: FormatDatestamp(id.EarliestDatestamp),
+			DeletedRecord:     id.DeletedRecord,
+			Granularity:       Granularity,
+		}
+		if id.RepositoryIdentifier != "" {
+			el.Description = &struct {
+				Identifier oaiIdentifier `xml:"oai-identifier"`
+			}{oaiIdentifier{
+				Xmlns:                identifierNS,
+				SchemaLocation:       identifierNS + " " + identifierXSD,
+				Scheme:               "oai",
+				RepositoryIdentifier: id.RepositoryIdentifier,
+				Delimiter:            ":",
+				SampleIdentifier:     id.SampleIdentifier,
+			}}
+		}
+		return el
+	case VerbListMetadataFormats:
+		el := formatsElement{}
+		for _, f := range r.Formats {
+			el.Formats = append(el.Formats, formatElement(f))
+		}
+		return el
+	case VerbListSets:
+		el := setsElement{}
+		for _, s := range r.Sets {
+			el.Sets = append(el.Sets, setElement(s))
+		}
+		return el
+	}
+
+	el := listElement{XMLName: xml.Name{Local: r.Verb}}
+	for _, h := range r.Headers {
+		el.Headers = append(el.Headers, header(h))
+	}
+	for _, rec := range r.Records {
+		record := recordElement{Header: header(rec.Header)}
+		if !rec.Header.Deleted {
+			record.Metadata = &metadataElement{Inner: rec.Metadata}
+		}
+		el.Records = append(el.Records, record)
+	}
+	if t := r.Token; t != nil {
+		el.Token = &tokenElement{CompleteListSize: t.CompleteListSize, Cursor: t.Cursor, Value: t.Value}
+	}
+	return el
+}
+
+func header(h Header) headerElement {
+	el := headerElement{Identifier: h.Identifier, Datestamp: FormatDatestamp(h.Datestamp), Sets: h.Sets}
+	if h.Deleted {
+		el.Status = "deleted"
+	}
+	return el
+}
+
+// MarshalDC encodes a record as an oai_dc:dc element
+func MarshalDC(record dublincore.Record) ([]byte, error) {
+	return dublincore.Marshal(record, "oai_dc:dc",
+		xml.Attr{Name: xml.Name{Local: "xmlns:oai_dc"}, Value: DCNamespace},
+		xml.Attr{Name: xml.Name{Local: "xmlns:xsi"}, Value: xsiNS},
+		xml.Attr{Name: xml.Name{Local: "xsi:schemaLocation"}, Value: DCNamespace + " " + DCSchema})
+}
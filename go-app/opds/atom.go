@@ -0,0 +1,243 @@
+package opds
+
+import (
+	"encoding/xml"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Namespaces of OPDS 1.2 documents
+const (
+	atomNS       = "http://www.w3.org/2005/Atom"
+	dcNS         = "http://purl.org/dc/terms/"
+	opdsNS       = "http://opds-spec.org/2010/catalog"
+	openSearchNS = "http://a9.com/-/spec/opensearch/1.1/"
+	threadNS     = "http://purl.org/syndication/thread/1.0"
+)
+
+// The structs below use prefixed names, which encoding/xml writes as they are;
+// the prefixes are declared on the feed element
+
+type atomFeed struct {
+	XMLName      xml.Name    `xml:"feed"`
+	Xmlns        string      `xml:"xmlns,attr"`
+	XmlnsDC      string      `xml:"xmlns:dc,attr"`
+	XmlnsOPDS    string      `xml:"xmlns:opds,attr"`
+	XmlnsSearch  string      `xml:"xmlns:opensearch,attr"`
+	XmlnsThread  string      `xml:"xmlns:thr,attr"`
+	ID           string      `xml:"id"`
+	Title        string      `xml:"title"`
+	Updated      string      `xml:"updated"`
+	Links        []atomLink  `xml:"link"`
+	TotalResults *int64      `xml:"opensearch:totalResults"`
+	ItemsPerPage *int        `xml:"opensearch:itemsPerPage"`
+	StartIndex   *int        `xml:"opensearch:startIndex"`
+	Entries      []atomEntry `xml:"entry"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomLink struct {
+	Rel          string            `xml:"rel,attr,omitempty"`
+	Href         string            `xml:"href,attr"`
+	Type         string            `xml:"type,attr,omitempty"`
+	Title        string            `xml:"title,attr,omitempty"`
+	Count        *int              `xml:"thr:count,attr,omitempty"`
+	Availability *atomAvailability `xml:"opds:availability"`
+	Holds        *atomCount        `xml:"opds:holds"`
+	Copies       *atomCopies       `xml:"opds:copies"`
+}
+
+type atomAvailability struct {
+	Status string `xml:"status,attr"`
+}
+
+type atomCount struct {
+	Total int `xml:"total,attr"`
+}
+
+type atomCopies struct {
+	Total     int `xml:"total,attr"`
+	Available int `xml:"available,attr"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr"`
+	Text string `xml:",chardata"`
+}
+
+type atomCategory struct {
+	Scheme string `xml:"scheme,attr,omitempty"`
+	Term   string `xml:"term,attr"`
+	Label  string `xml:"label,attr"`
+}
+
+type atomEntry struct {
+	Title        string         `xml:"title"`
+	ID           string         `xml:"id"`
+	Updated      string         `xml:"updated"`
+	Authors      []atomPerson   `xml:"author"`
+	Contributors []atomPerson   `xml:"contributor"`
+	Identifier   string         `xml:"dc:identifier,omitempty"`
+	Language     string         `xml:"dc:language,omitempty"`
+	Publisher    string         `xml:"dc:publisher,omitempty"`
+	Issued       string         `xml:"dc:issued,omitempty"`
+	Extent       string         `xml:"dc:extent,omitempty"`
+	Categories   []atomCategory `xml:"category"`
+	Summary      *atomText      `xml:"summary"`
+	Content      *atomText      `xml:"content"`
+	Links        []atomLink     `xml:"link"`
+}
+
+// WriteAtom writes the feed as an OPDS 1.2 Atom document
+func WriteAtom(w io.Writer, feed *Feed) error {
+	doc := atomFeed{
+		Xmlns:       atomNS,
+		XmlnsDC:     dcNS,
+		XmlnsOPDS:   opdsNS,
+		XmlnsSearch: openSearchNS,
+		XmlnsThread: threadNS,
+		ID:          feed.ID,
+		Title:       feed.Title,
+		Updated:     atomTime(feed.Updated),
+		Links:       atomLinks(feed.Links),
+	}
+	if feed.Paginated() {
+		start := (feed.Page-1)*feed.ItemsPerPage + 1
+		doc.TotalResults, doc.ItemsPerPage, doc.StartIndex = &feed.TotalResults, &feed.ItemsPerPage, &start
+	}
+
+	for _, nav := range feed.Navigation {
+		entry := atomEntry{
+			Title:   nav.Title,
+			ID:      nav.ID,
+			Updated: atomTime(feed.Updated),
+			Links:   []atomLink{{Rel: "subsection", Href: nav.Href, Type: nav.Type}},
+		}
+		if nav.Count > 0 {
+			count := nav.Count
+			entry.Links[0].Count = &count
+		}
+		if nav.Summary != "" {
+			entry.Content = &atomText{Type: "text", Text: nav.Summary}
+		}
+		doc.Entries = append(doc.Entries, entry)
+	}
+	for _, pub := range feed.Publications {
+		doc.Entries = append(doc.Entries, atomPublication(pub))
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func atomPublication(pub Publication) atomEntry {
+	entry := atomEntry{
+		Title:     pub.Title,
+		ID:        pub.ID,
+		Updated:   atomTime(pub.Updated),
+		Language:  pub.Language,
+		Publisher: pub.Publisher,
+		Links:     atomLinks(pub.Links),
+	}
+	for _, contributor := range pub.Contributors {
+		if contributor.Role == "author" {
+			entry.Authors = append(entry.Authors, atomPerson{Name: contributor.Name})
+		} else {
+			entry.Contributors = append(entry.Contributors, atomPerson{Name: contributor.Name})
+		}
+	}
+	if pub.ISBN != "" {
+		entry.Identifier = "urn:isbn:" + pub.ISBN
+	}
+	if pub.Year != 0 {
+		entry.Issued = strconv.Itoa(pub.Year)
+	}
+	if pub.Pages != 0 {
+		entry.Extent = strconv.Itoa(pub.Pages) + " pages"
+	}
+	for _, subject := range pub.Subjects {
+		entry.Categories = append(entry.Categories, atomCategory{Scheme: subject.Scheme, Term: subject.Code, Label: subject.Name})
+	}
+	if pub.Description != "" {
+		entry.Summary = &atomText{Type: "text", Text: pub.Description}
+	}
+	for _, image := range pub.Images {
+		rel := RelImage
+		if image.Thumbnail {
+			rel = RelThumbnail
+		}
+		entry.Links = append(entry.Links, atomLink{Rel: rel, Href: image.Href, Type: image.Type})
+	}
+	if borrow := pub.Borrow; borrow != nil {
+		status := "available"
+		if borrow.Available == 0 {
+			status = "unavailable"
+		}
+		entry.Links = append(entry.Links, atomLink{
+			Rel:          RelBorrow,
+			Href:         borrow.Href,
+			Type:         borrow.Type,
+			Availability: &atomAvailability{Status: status},
+			Holds:        &atomCount{Total: borrow.Holds},
+			Copies:       &atomCopies{Total: borrow.Copies, Available: borrow.Available},
+		})
+	}
+	return entry
+}
+
+func atomLinks(links []Link) []atomLink {
+	out := make([]atomLink, 0, len(links))
+	for _, link := range links {
+		// Atom has no URI templates, those links only exist in OPDS 2.0
+		if link.Templated {
+			continue
+		}
+		out = append(out, atomLink{Rel: link.Rel, Href: link.Href, Type: link.Type, Title: link.Title})
+	}
+	return out
+}
+
+func atomTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// WriteOpenSearch writes an OpenSearch description whose template is the
+// search feed URL with {searchTerms} in place of the query
+func WriteOpenSearch(w io.Writer, shortName, description, template string) error {
+	doc := struct {
+		XMLName       xml.Name `xml:"OpenSearchDescription"`
+		Xmlns         string   `xml:"xmlns,attr"`
+		ShortName     string   `xml:"ShortName"`
+		Description   string   `xml:"Description"`
+		InputEncoding string   `xml:"InputEncoding"`
+		URL           struct {
+			Type     string `xml:"type,attr"`
+			Template string `xml:"template,attr"`
+		} `xml:"Url"`
+	}{Xmlns: openSearchNS, ShortName: shortName, Description: description, InputEncoding: "UTF-8"}
+	doc.URL.Type = AcquisitionType
+	doc.URL.Template = template
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
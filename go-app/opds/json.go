@@ -0,0 +1,178 @@
+package opds
+
+import (
+	"strconv"
+	"time"
+)
+
+// OPDS 2.0 documents are JSON objects; these types only carry what Feed can express
+
+type jsonFeed struct {
+	Metadata     jsonFeedMetadata  `json:"metadata"`
+	Links        []jsonLink        `json:"links"`
+	Navigation   []jsonLink        `json:"navigation,omitempty"`
+	Publications []jsonPublication `json:"publications,omitempty"`
+}
+
+type jsonFeedMetadata struct {
+	Title         string `json:"title"`
+	Modified      string `json:"modified"`
+	NumberOfItems *int64 `json:"numberOfItems,omitempty"`
+	ItemsPerPage  *int   `json:"itemsPerPage,omitempty"`
+	CurrentPage   *int   `json:"currentPage,omitempty"`
+}
+
+type jsonLink struct {
+	Rel        string          `json:"rel,omitempty"`
+	Href       string          `json:"href"`
+	Type       string          `json:"type,omitempty"`
+	Title      string          `json:"title,omitempty"`
+	Templated  bool            `json:"templated,omitempty"`
+	Width      int             `json:"width,omitempty"`
+	Properties *jsonProperties `json:"properties,omitempty"`
+}
+
+type jsonProperties struct {
+	NumberOfItems int               `json:"numberOfItems,omitempty"`
+	Availability  *jsonAvailability `json:"availability,omitempty"`
+	Copies        *jsonCopies       `json:"copies,omitempty"`
+	Holds         *jsonHolds        `json:"holds,omitempty"`
+}
+
+type jsonAvailability struct {
+	State string `json:"state"`
+}
+
+type jsonCopies struct {
+	Total     int `json:"total"`
+	Available int `json:"available"`
+}
+
+type jsonHolds struct {
+	Total int `json:"total"`
+}
+
+type jsonPublication struct {
+	Metadata jsonPublicationMetadata `json:"metadata"`
+	Links    []jsonLink              `json:"links"`
+	Images   []jsonLink              `json:"images,omitempty"`
+}
+
+type jsonContributor struct {
+	Name string `json:"name"`
+}
+
+type jsonSubject struct {
+	Name   string `json:"name"`
+	Code   string `json:"code,omitempty"`
+	Scheme string `json:"scheme,omitempty"`
+}
+
+type jsonPublicationMetadata struct {
+	Type          string            `json:"@type"`
+	Identifier    string            `json:"identifier"`
+	Title         string            `json:"title"`
+	Author        []jsonContributor `json:"author,omitempty"`
+	Editor        []jsonContributor `json:"editor,omitempty"`
+	Translator    []jsonContributor `json:"translator,omitempty"`
+	Illustrator   []jsonContributor `json:"illustrator,omitempty"`
+	Language      string            `json:"language,omitempty"`
+	Publisher     string            `json:"publisher,omitempty"`
+	Published     string            `json:"published,omitempty"`
+	Modified      string            `json:"modified"`
+	Description   string            `json:"description,omitempty"`
+	NumberOfPages int               `json:"numberOfPages,omitempty"`
+	Subject       []jsonSubject     `json:"subject,omitempty"`
+}
+
+// JSON returns the feed as an OPDS 2.0 document, ready to be encoded
+func JSON(feed *Feed) interface{} {
+	doc := jsonFeed{
+		Metadata: jsonFeedMetadata{Title: feed.Title, Modified: jsonTime(feed.Updated)},
+		Links:    jsonLinks(feed.Links),
+	}
+	if feed.Paginated() {
+		doc.Metadata.NumberOfItems, doc.Metadata.ItemsPerPage, doc.Metadata.CurrentPage = &feed.TotalResults, &feed.ItemsPerPage, &feed.Page
+	}
+
+	for _, nav := range feed.Navigation {
+		link := jsonLink{Href: nav.Href, Title: nav.Title, Type: JSONType}
+		if nav.Count > 0 {
+			link.Properties = &jsonProperties{NumberOfItems: nav.Count}
+		}
+		doc.Navigation = append(doc.Navigation, link)
+	}
+	for _, pub := range feed.Publications {
+		doc.Publications = append(doc.Publications, jsonPublicationOf(pub))
+	}
+	return doc
+}
+
+func jsonPublicationOf(pub Publication) jsonPublication {
+	meta := jsonPublicationMetadata{
+		Type:          "http://schema.org/Book",
+		Identifier:    pub.ID,
+		Title:         pub.Title,
+		Language:      pub.Language,
+		Publisher:     pub.Publisher,
+		Modified:      jsonTime(pub.Updated),
+		Description:   pub.Description,
+		NumberOfPages: pub.Pages,
+	}
+	if pub.ISBN != "" {
+		meta.Identifier = "urn:isbn:" + pub.ISBN
+	}
+	if pub.Year != 0 {
+		meta.Published = strconv.Itoa(pub.Year)
+	}
+	for _, contributor := range pub.Contributors {
+		person := jsonContributor{Name: contributor.Name}
+		switch contributor.Role {
+		case "editor":
+			meta.Editor = append(meta.Editor, person)
+		case "translator":
+			meta.Translator = append(meta.Translator, person)
+		case "illustrator":
+			meta.Illustrator = append(meta.Illustrator, person)
+		default:
+			meta.Author = append(meta.Author, person)
+		}
+	}
+	for _, subject := range pub.Subjects {
+		meta.Subject = append(meta.Subject, jsonSubject(subject))
+	}
+
+	doc := jsonPublication{Metadata: meta, Links: jsonLinks(pub.Links)}
+	if borrow := pub.Borrow; borrow != nil {
+		state := "available"
+		if borrow.Available == 0 {
+			state = "unavailable"
+		}
+		doc.Links = append(doc.Links, jsonLink{
+			Rel:  RelBorrow,
+			Href: borrow.Href,
+			Type: borrow.Type,
+			Properties: &jsonProperties{
+				Availability: &jsonAvailability{State: state},
+				Copies:       &jsonCopies{Total: borrow.Copies, Available: borrow.Available},
+				Holds:        &jsonHolds{Total: borrow.Holds},
+			},
+		})
+	}
+	for _, image := range pub.Images {
+		doc.Images = append(doc.Images, jsonLink{Href: image.Href, Type: image.Type, Width: image.Width})
+	}
+	return doc
+}
+
+func jsonLinks(links []Link) []jsonLink {
+	out := make([]jsonLink, 0, len(links))
+	for _, link := range links {
+		out = append(out, jsonLink{Rel: link.Rel, Href: link.Href, Type: link.Type, Title: link.Title, Templated: link.Templated})
+	}
+	return out
+}
+
+func jsonTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
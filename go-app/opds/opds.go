@@ -0,0 +1,109 @@
+// Package opds renders catalog feeds for e-reader apps, as OPDS 1.2 Atom
+// documents or OPDS 2.0 JSON, from one description of the feed.
+package opds
+
+import "time"
+
+// Media types of OPDS documents
+const (
+	NavigationType  = "application/atom+xml;profile=opds-catalog;kind=navigation"
+	AcquisitionType = "application/atom+xml;profile=opds-catalog;kind=acquisition"
+	JSONType        = "application/opds+json"
+	OpenSearchType  = "application/opensearchdescription+xml"
+)
+
+// Link relations specific to OPDS
+const (
+	RelImage     = "http://opds-spec.org/image"
+	RelThumbnail = "http://opds-spec.org/image/thumbnail"
+	RelBorrow    = "http://opds-spec.org/acquisition/borrow"
+)
+
+// Feed is a navigation feed, listing other feeds, or an acquisition feed,
+// listing publications
+type Feed struct {
+	ID           string
+	Title        string
+	Updated      time.Time
+	Links        []Link // self, start, up, search and pagination links
+	Navigation   []Navigation
+	Publications []Publication
+
+	// Pagination of an acquisition feed, zero when it is not paginated
+	TotalResults int64
+	ItemsPerPage int
+	Page         int
+}
+
+// Link is a typed link; Templated links carry a URI template instead of a URL
+type Link struct {
+	Rel       string
+	Href      string
+	Type      string
+	Title     string
+	Templated bool
+}
+
+// Navigation is an entry of a navigation feed, pointing at another feed
+type Navigation struct {
+	ID      string
+	Title   string
+	Href    string
+	Type    string // Media type of the feed in OPDS 1.2, such as AcquisitionType
+	Summary string
+	Count   int // Number of publications behind the entry, when known
+}
+
+// Publication is a book in an acquisition feed
+type Publication struct {
+	ID           string
+	Title        string
+	Contributors []Contributor
+	ISBN         string
+	Language     string
+	Publisher    string
+	Year         int
+	Pages        int
+	Description  string
+	Subjects     []Subject
+	Updated      time.Time
+	Images       []Image
+	Links        []Link // Alternate representations
+	Borrow       *Borrow
+}
+
+// Contributor is a person credited on a publication; Role is author,
+// editor, translator or illustrator
+type Contributor struct {
+	Name string
+	Role string
+}
+
+// Subject is a category a publication is classified under
+type Subject struct {
+	Name   string
+	Code   string
+	Scheme string
+}
+
+// Image is a cover image or thumbnail
+type Image struct {
+	Href      string
+	Type      string
+	Width     int
+	Thumbnail bool
+}
+
+// Borrow is the link patrons follow to borrow a publication, with its availability
+type Borrow struct {
+	Href      string
+	Type      string
+	Copies    int
+	Available int
+	Holds     int
+}
+
+// Paginated reports whether the feed has pagination metadata
+func (f *Feed) Paginated() bool {
+	return f.ItemsPerPage > 0
+}
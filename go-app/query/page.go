@@ -0,0 +1,101 @@
+package query
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+const (
+	// DefaultLimit is the page size used when no limit is given
+	DefaultLimit = 100
+	// MaxLimit is the largest page size a client may request
+	MaxLimit = 500
+)
+
+// Page is a validated page/limit pair for offset pagination
+type Page struct {
+	Number int
+	Limit  int
+}
+
+// ParsePage reads page and limit parameters, rejecting values outside the allowed range
+func ParsePage(values url.Values, defaultLimit int) (Page, error) {
+	page := Page{Number: 1, Limit: defaultLimit}
+
+	if raw := values.Get("page"); raw != "" {
+		number, err := strconv.Atoi(raw)
+		if err != nil || number < 1 {
+			return Page{}, errorf("page must be a positive integer")
+		}
+		page.Number = number
+	}
+
+	limit, err := ParseLimit(values, defaultLimit)
+	if err != nil {
+		return Page{}, err
+	}
+	page.Limit = limit
+
+	return page, nil
+}
+
+// ParseLimit reads the limit parameter on its own, for cursor pagination
+func ParseLimit(values url.Values, defaultLimit int) (int, error) {
+	raw := values.Get("limit")
+	if raw == "" {
+		return defaultLimit, nil
+	}
+	limit, err := strconv.Atoi(raw)
+	if err != nil || limit < 1 || limit > MaxLimit {
+		return 0, errorf("limit must be between 1 and %d", MaxLimit)
+	}
+	return limit, nil
+}
+
+// Offset returns the number of rows to skip for the page
+func (p Page) Offset() int {
+	return (p.Number - 1) * p.Limit
+}
+
+// LastPage returns the number of the last page for a total row count
+func (p Page) LastPage(total int64) int {
+	if total == 0 {
+		return 1
+	}
+	return int((total + int64(p.Limit) - 1) / int64(p.Limit))
+}
+
+// EncodeCursor encodes the ID of the last row of a page as an opaque cursor
+func EncodeCursor(id uint) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("id:%d", id)))
+}
+
+// DecodeCursor decodes a cursor produced by EncodeCursor, an empty cursor starts from the beginning
+func DecodeCursor(cursor string) (uint, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, errorf("invalid cursor")
+	}
+	var id uint
+	if _, err := fmt.Sscanf(string(data), "id:%d", &id); err != nil {
+		return 0, errorf("invalid cursor")
+	}
+	return id, nil
+}
+
+// KeysetOrder reports whether the requested sort order can be paginated by
+// cursor, which requires ordering by the primary key alone
+func (p *Params) KeysetOrder() (desc bool, ok bool) {
+	switch {
+	case len(p.orders) == 0:
+		return false, true
+	case len(p.orders) == 1 && p.orders[0].column == "id":
+		return p.orders[0].desc, true
+	}
+	return false, false
+}
@@ -0,0 +1,321 @@
+package query
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Type is the type of a filterable column, used to parse filter values
+type Type int
+
+const (
+	String Type = iota
+	Int
+	Bool
+	Time
+)
+
+// Field describes a column exposed through the query language
+type Field struct {
+	Column string // Database column, defaults to the API name
+	Type   Type
+	Filter bool // Can be used in filters
+	Sort   bool // Can be used in sort
+	// Normalize converts String filter values to their stored form, rejecting invalid ones
+	Normalize func(string) (string, error)
+}
+
+// Resource is the whitelist of fields for one list endpoint, keyed by API name
+type Resource map[string]Field
+
+// Reserved parameters that are not treated as filters
+var reserved = map[string]bool{
+	"page":   true,
+	"limit":  true,
+	"sort":   true,
+	"fields": true,
+}
+
+// Filter operators, selected by a suffix on the field name (year_gte=2000)
+var operators = map[string]string{
+	"":          "=",
+	"_ne":       "<>",
+	"_gt":       ">",
+	"_gte":      ">=",
+	"_lt":       "<",
+	"_lte":      "<=",
+	"_in":       "IN",
+	"_contains": "LIKE",
+	"_isnull":   "IS NULL",
+}
+
+// Error is returned for malformed or non-whitelisted parameters
+type Error struct {
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func errorf(format string, args ...interface{}) error {
+	return &Error{Message: fmt.Sprintf(format, args...)}
+}
+
+// likeEscaper makes wildcards in _contains values match literally, with the
+// backslash declared as the escape character of the LIKE
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+type filter struct {
+	column   string
+	operator string
+	value    interface{}
+}
+
+type order struct {
+	column string
+	desc   bool
+}
+
+// Params is a parsed set of filter, sort and field selection parameters
+type Params struct {
+	filters []filter
+	orders  []order
+	fields  []string
+}
+
+// Parse parses query parameters against the whitelist of a resource.
+// Unknown or malformed parameters produce an *Error.
+func Parse(values url.Values, resource Resource, extra ...string) (*Params, error) {
+	params := &Params{}
+	skip := make(map[string]bool, len(extra))
+	for _, name := range extra {
+		skip[name] = true
+	}
+
+	// Iterate in a stable order so error messages are deterministic
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if reserved[key] || skip[key] {
+			continue
+		}
+		for _, raw := range values[key] {
+			f, err := parseFilter(key, raw, resource)
+			if err != nil {
+				return nil, err
+			}
+			params.filters = append(params.filters, f)
+		}
+	}
+
+	if raw := values.Get("sort"); raw != "" {
+		for _, name := range strings.Split(raw, ",") {
+			name = strings.TrimSpace(name)
+			desc := strings.HasPrefix(name, "-")
+			name = strings.TrimPrefix(name, "-")
+			field, ok := resource[name]
+			if !ok || !field.Sort {
+				return nil, errorf("cannot sort by unknown field '%s'", name)
+			}
+			params.orders = append(params.orders, order{column: field.column(name), desc: desc})
+		}
+	}
+
+	if raw := values.Get("fields"); raw != "" {
+		for _, name := range strings.Split(raw, ",") {
+			name = strings.TrimSpace(name)
+			if _, ok := resource[name]; !ok {
+				return nil, errorf("cannot select unknown field '%s'", name)
+			}
+			params.fields = append(params.fields, name)
+		}
+	}
+
+	return params, nil
+}
+
+func parseFilter(key, raw string, resource Resource) (filter, error) {
+	name, suffix := key, ""
+	if _, ok := resource[key]; !ok {
+		for candidate := range operators {
+			if candidate != "" && strings.HasSuffix(key, candidate) {
+				name, suffix = strings.TrimSuffix(key, candidate), candidate
+				break
+			}
+		}
+	}
+
+	field, ok := resource[name]
+	if !ok || !field.Filter {
+		return filter{}, errorf("cannot filter by unknown field '%s'", key)
+	}
+
+	f := filter{column: field.column(name), operator: operators[suffix]}
+	switch suffix {
+	case "_in":
+		var values []interface{}
+		for _, part := range strings.Split(raw, ",") {
+			value, err := parseValue(field, strings.TrimSpace(part))
+			if err != nil {
+				return filter{}, errorf("invalid value for '%s': %s", key, err)
+			}
+			values = append(values, value)
+		}
+		f.value = values
+	case "_contains":
+		if field.Type != String {
+			return filter{}, errorf("'%s' is only supported on text fields", key)
+		}
+		f.value = "%" + likeEscaper.Replace(raw) + "%"
+	case "_isnull":
+		isNull, err := strconv.ParseBool(raw)
+		if err != nil {
+			return filter{}, errorf("invalid value for '%s': expected true or false", key)
+		}
+		if !isNull {
+			f.operator = "IS NOT NULL"
+		}
+	default:
+		value, err := parseValue(field, raw)
+		if err != nil {
+			return filter{}, errorf("invalid value for '%s': %s", key, err)
+		}
+		f.value = value
+	}
+	return f, nil
+}
+
+func parseValue(field Field, raw string) (interface{}, error) {
+	switch field.Type {
+	case Int:
+		value, err := strconv.Atoi(raw)
+		if err != nil {
+			return nil, fmt.Errorf("expected an integer")
+		}
+		return value, nil
+	case Bool:
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("expected true or false")
+		}
+		return value, nil
+	case Time:
+		for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+			if value, err := time.Parse(layout, raw); err == nil {
+				return value, nil
+			}
+		}
+		return nil, fmt.Errorf("expected an RFC 3339 timestamp or YYYY-MM-DD date")
+	}
+	if field.Normalize != nil {
+		return field.Normalize(raw)
+	}
+	return raw, nil
+}
+
+func (f Field) column(name string) string {
+	if f.Column != "" {
+		return f.Column
+	}
+	return name
+}
+
+// Scope applies the filters and sort order to a query. When no sort is
+// requested, rows are ordered by defaultOrder so pagination is stable.
+func (p *Params) Scope(defaultOrder string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		db = p.FilterScope()(db)
+		if len(p.orders) == 0 {
+			return db.Order(clause.OrderByColumn{Column: column(defaultOrder)})
+		}
+		for _, o := range p.orders {
+			db = db.Order(clause.OrderByColumn{Column: column(o.column), Desc: o.desc})
+		}
+		return db
+	}
+}
+
+// FilterScope applies only the filters, for use in count queries
+func (p *Params) FilterScope() func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		for _, f := range p.filters {
+			col := column(f.column)
+			switch f.operator {
+			case "=":
+				db = db.Where(clause.Eq{Column: col, Value: f.value})
+			case "<>":
+				db = db.Where(clause.Neq{Column: col, Value: f.value})
+			case ">":
+				db = db.Where(clause.Gt{Column: col, Value: f.value})
+			case ">=":
+				db = db.Where(clause.Gte{Column: col, Value: f.value})
+			case "<":
+				db = db.Where(clause.Lt{Column: col, Value: f.value})
+			case "<=":
+				db = db.Where(clause.Lte{Column: col, Value: f.value})
+			case "IN":
+				db = db.Where(clause.IN{Column: col, Values: f.value.([]interface{})})
+			case "LIKE":
+				db = db.Where(clause.Expr{SQL: `? LIKE ? ESCAPE '\'`, Vars: []interface{}{col, f.value}})
+			case "IS NULL":
+				db = db.Where(clause.Eq{Column: col, Value: nil})
+			case "IS NOT NULL":
+				db = db.Where(clause.Neq{Column: col, Value: nil})
+			}
+		}
+		return db
+	}
+}
+
+// column qualifies a column with the statement's table, so scopes stay
+// unambiguous when the query joins other tables
+func column(name string) clause.Column {
+	return clause.Column{Table: clause.CurrentTable, Name: name}
+}
+
+// Fields returns the selected fields in the order they were requested, or
+// nil when no field selection was requested
+func (p *Params) Fields() []string {
+	return p.fields
+}
+
+// Select reduces each item to the requested fields. Items are returned
+// unchanged when no field selection was requested.
+func (p *Params) Select(items interface{}) (interface{}, error) {
+	if len(p.fields) == 0 {
+		return items, nil
+	}
+
+	data, err := json.Marshal(items)
+	if err != nil {
+		return nil, err
+	}
+	var rows []map[string]json.RawMessage
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, err
+	}
+
+	selected := make([]map[string]json.RawMessage, len(rows))
+	for i, row := range rows {
+		selected[i] = make(map[string]json.RawMessage, len(p.fields))
+		for _, name := range p.fields {
+			if value, ok := row[name]; ok {
+				selected[i][name] = value
+			}
+		}
+	}
+	return selected, nil
+}
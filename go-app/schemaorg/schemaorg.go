@@ -0,0 +1,85 @@
+// Package schemaorg describes books with the schema.org vocabulary, encoded
+// as JSON-LD for search engines and linked-data consumers.
+package schemaorg
+
+// Context is the JSON-LD context of the vocabulary
+const Context = "https://schema.org"
+
+// ContentType is the media type of JSON-LD documents
+const ContentType = "application/ld+json"
+
+// Offer availabilities
+const (
+	InStock    = "https://schema.org/InStock"
+	OutOfStock = "https://schema.org/OutOfStock"
+)
+
+// Book formats
+const (
+	AudiobookFormat = "https://schema.org/AudiobookFormat"
+	EBook           = "https://schema.org/EBook"
+)
+
+// LeaseOut is the business function of an offer to lend
+const LeaseOut = "http://purl.org/goodrelations/v1#LeaseOut"
+
+// Book is a schema.org Book. Context is set on the top-level node only.
+type Book struct {
+	Context       string        `json:"@context,omitempty"`
+	Type          string        `json:"@type"`
+	ID            string        `json:"@id,omitempty"`
+	URL           string        `json:"url,omitempty"`
+	Name          string        `json:"name"`
+	Author        []Person      `json:"author,omitempty"`
+	Editor        []Person      `json:"editor,omitempty"`
+	Translator    []Person      `json:"translator,omitempty"`
+	Illustrator   []Person      `json:"illustrator,omitempty"`
+	ISBN          string        `json:"isbn,omitempty"`
+	BookEdition   string        `json:"bookEdition,omitempty"`
+	BookFormat    string        `json:"bookFormat,omitempty"`
+	NumberOfPages int           `json:"numberOfPages,omitempty"`
+	InLanguage    string        `json:"inLanguage,omitempty"`
+	DatePublished string        `json:"datePublished,omitempty"`
+	Publisher     *Organization `json:"publisher,omitempty"`
+	Description   string        `json:"description,omitempty"`
+	About         []Thing       `json:"about,omitempty"`
+	Genre         []string      `json:"genre,omitempty"`
+	Keywords      []string      `json:"keywords,omitempty"`
+	Image         string        `json:"image,omitempty"`
+	Offers        []Offer       `json:"offers,omitempty"`
+}
+
+// Person is a contributor
+type Person struct {
+	Type string `json:"@type"`
+	ID   string `json:"@id,omitempty"`
+	Name string `json:"name"`
+}
+
+// Organization is a publisher or library
+type Organization struct {
+	Type string `json:"@type"`
+	Name string `json:"name"`
+	URL  string `json:"url,omitempty"`
+}
+
+// Thing is a subject a book is about
+type Thing struct {
+	Type string `json:"@type"`
+	Name string `json:"name"`
+}
+
+// Offer is the lending of a book by the library, with the copies on the shelf
+type Offer struct {
+	Type             string             `json:"@type"`
+	Availability     string             `json:"availability"`
+	BusinessFunction string             `json:"businessFunction,omitempty"`
+	InventoryLevel   *QuantitativeValue `json:"inventoryLevel,omitempty"`
+	OfferedBy        *Organization      `json:"offeredBy,omitempty"`
+}
+
+// QuantitativeValue is a count, such as the copies available
+type QuantitativeValue struct {
+	Type  string `json:"@type"`
+	Value int    `json:"value"`
+}
@@ -0,0 +1,299 @@
+package search
+
+import (
+	"errors"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+
+	"library-go/models"
+)
+
+// Backend is a search index kept outside the database. When one is
+// configured it replaces the database full-text query, while filters, facets
+// and pagination still run in SQL over the candidates it returns.
+type Backend interface {
+	// Name identifies the backend in status reports
+	Name() string
+	// Match returns up to limit books matching the query, best first
+	Match(query string, limit int) ([]Candidate, error)
+	// Index adds or replaces books in the index
+	Index(books ...models.Book) error
+	// Remove drops books from the index
+	Remove(ids ...uint) error
+	// Documents returns every indexed book ID with the UpdatedAt it was indexed at
+	Documents() (map[uint]time.Time, error)
+	// Close releases the index
+	Close() error
+}
+
+// maxBackendCandidates caps the number of matches requested from a backend per search
+const maxBackendCandidates = 1000
+
+// reindexBatchSize is the number of books loaded and indexed at a time
+const reindexBatchSize = 500
+
+var (
+	backend Backend
+
+	reindexMu    sync.Mutex
+	reindexState = ReindexState{Status: "idle"}
+)
+
+// ErrNoBackend is returned by operations that need an external backend when
+// the database full-text index is in use
+var ErrNoBackend = errors.New("no external search backend is configured")
+
+// ErrReindexRunning is returned when a reindex is requested while one is in progress
+var ErrReindexRunning = errors.New("a reindex is already running")
+
+// ReindexState describes the last or current reindex run
+type ReindexState struct {
+	Status     string     `json:"status"` // idle, running, completed or failed
+	Indexed    int        `json:"indexed"`
+	Removed    int        `json:"removed"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	Error      string     `json:"error,omitempty"`
+}
+
+// Report is the result of a consistency check between the index and the Book table
+type Report struct {
+	Backend    string `json:"backend"`
+	Books      int    `json:"books"`
+	Documents  int    `json:"documents"`
+	Missing    []uint `json:"missing"`  // Books absent from the index
+	Stale      []uint `json:"stale"`    // Books indexed at an older UpdatedAt
+	Orphaned   []uint `json:"orphaned"` // Documents without a book
+	Consistent bool   `json:"consistent"`
+}
+
+// ActiveBackend returns the name of the backend answering queries
+func ActiveBackend() string {
+	if backend != nil {
+		return backend.Name()
+	}
+	return "database-" + mode
+}
+
+// useBackend installs an external backend and keeps it in sync with every
+// create, update and delete of a book made through GORM
+func useBackend(db *gorm.DB, b Backend) error {
+	backend = b
+
+	if err := db.Callback().Create().After("gorm:create").Register("search:index_create", indexCallback); err != nil {
+		return err
+	}
+	if err := db.Callback().Update().After("gorm:update").Register("search:index_update", indexCallback); err != nil {
+		return err
+	}
+	return db.Callback().Delete().After("gorm:delete").Register("search:index_delete", removeCallback)
+}
+
+// Close releases the external backend, if any
+func Close() {
+	if backend == nil {
+		return
+	}
+	if err := backend.Close(); err != nil {
+		log.Println("Error closing search index:", err)
+	}
+}
+
+func indexCallback(db *gorm.DB) {
+	if db.Error != nil || backend == nil {
+		return
+	}
+	books := statementBooks(db)
+	if len(books) == 0 {
+		return
+	}
+	// The index can be repaired by a reindex, so failures must not fail the write
+	if err := backend.Index(books...); err != nil {
+		log.Println("Failed to update search index:", err)
+	}
+}
+
+func removeCallback(db *gorm.DB) {
+	if db.Error != nil || backend == nil {
+		return
+	}
+	books := statementBooks(db)
+	ids := make([]uint, 0, len(books))
+	for _, book := range books {
+		if book.ID != 0 {
+			ids = append(ids, book.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+	if err := backend.Remove(ids...); err != nil {
+		log.Println("Failed to update search index:", err)
+	}
+}
+
+// statementBooks extracts the books written by a statement
+func statementBooks(db *gorm.DB) []models.Book {
+	switch value := db.Statement.Dest.(type) {
+	case *models.Book:
+		return []models.Book{*value}
+	case models.Book:
+		return []models.Book{value}
+	case *[]models.Book:
+		return *value
+	case []models.Book:
+		return value
+	}
+
+	// Updates through Model(&book).Updates(...) leave the model stale, reload it
+	if model, ok := db.Statement.Model.(*models.Book); ok && model.ID != 0 {
+		var book models.Book
+		if err := db.Session(&gorm.Session{NewDB: true}).First(&book, model.ID).Error; err == nil {
+			return []models.Book{book}
+		}
+		return []models.Book{{ID: model.ID}}
+	}
+	return nil
+}
+
+// Reindex rebuilds the external index from the Book table, removing documents
+// of books that no longer exist
+func Reindex(db *gorm.DB) (ReindexState, error) {
+	if backend == nil {
+		return ReindexState{}, ErrNoBackend
+	}
+
+	reindexMu.Lock()
+	if reindexState.Status == "running" {
+		reindexMu.Unlock()
+		return reindexState, ErrReindexRunning
+	}
+	started := time.Now()
+	reindexState = ReindexState{Status: "running", StartedAt: &started}
+	reindexMu.Unlock()
+
+	indexed, removed, err := reindex(db)
+
+	reindexMu.Lock()
+	defer reindexMu.Unlock()
+	finished := time.Now()
+	reindexState.Indexed = indexed
+	reindexState.Removed = removed
+	reindexState.FinishedAt = &finished
+	if err != nil {
+		reindexState.Status = "failed"
+		reindexState.Error = err.Error()
+	} else {
+		reindexState.Status = "completed"
+	}
+	return reindexState, err
+}
+
+// ReindexInBackground starts a reindex in a goroutine
+func ReindexInBackground(db *gorm.DB) error {
+	if backend == nil {
+		return ErrNoBackend
+	}
+	reindexMu.Lock()
+	running := reindexState.Status == "running"
+	reindexMu.Unlock()
+	if running {
+		return ErrReindexRunning
+	}
+
+	go func() {
+		if state, err := Reindex(db); err != nil {
+			log.Println("Search reindex failed:", err)
+		} else {
+			log.Printf("Search reindex completed: %d indexed, %d removed\n", state.Indexed, state.Removed)
+		}
+	}()
+	return nil
+}
+
+// CurrentReindex returns the state of the last or current reindex
+func CurrentReindex() ReindexState {
+	reindexMu.Lock()
+	defer reindexMu.Unlock()
+	return reindexState
+}
+
+func reindex(db *gorm.DB) (int, int, error) {
+	documents, err := backend.Documents()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	indexed := 0
+	var books []models.Book
+	result := db.Order("id").FindInBatches(&books, reindexBatchSize, func(tx *gorm.DB, batch int) error {
+		for _, book := range books {
+			delete(documents, book.ID)
+		}
+		indexed += len(books)
+		return backend.Index(books...)
+	})
+	if result.Error != nil {
+		return indexed, 0, result.Error
+	}
+
+	// Whatever is left in documents has no matching book
+	orphaned := make([]uint, 0, len(documents))
+	for id := range documents {
+		orphaned = append(orphaned, id)
+	}
+	if len(orphaned) > 0 {
+		if err := backend.Remove(orphaned...); err != nil {
+			return indexed, 0, err
+		}
+	}
+	return indexed, len(orphaned), nil
+}
+
+// Check compares the external index with the Book table
+func Check(db *gorm.DB) (*Report, error) {
+	if backend == nil {
+		return nil, ErrNoBackend
+	}
+
+	documents, err := backend.Documents()
+	if err != nil {
+		return nil, err
+	}
+
+	var books []models.Book
+	if err := db.Select("id", "updated_at").Order("id").Find(&books).Error; err != nil {
+		return nil, err
+	}
+
+	report := &Report{
+		Backend:   backend.Name(),
+		Books:     len(books),
+		Documents: len(documents),
+		Missing:   []uint{},
+		Stale:     []uint{},
+		Orphaned:  []uint{},
+	}
+	for _, book := range books {
+		indexedAt, ok := documents[book.ID]
+		switch {
+		case !ok:
+			report.Missing = append(report.Missing, book.ID)
+		// Databases keep timestamps at microsecond precision at best
+		case !indexedAt.Round(time.Microsecond).Equal(book.UpdatedAt.Round(time.Microsecond)):
+			report.Stale = append(report.Stale, book.ID)
+		}
+		delete(documents, book.ID)
+	}
+	for id := range documents {
+		report.Orphaned = append(report.Orphaned, id)
+	}
+	sort.Slice(report.Orphaned, func(i, j int) bool { return report.Orphaned[i] < report.Orphaned[j] })
+
+	report.Consistent = len(report.Missing) == 0 && len(report.Stale) == 0 && len(report.Orphaned) == 0
+	return report, nil
+}
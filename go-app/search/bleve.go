@@ -0,0 +1,193 @@
+package search
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/analysis/analyzer/keyword"
+	"github.com/blevesearch/bleve/v2/analysis/analyzer/standard"
+	"github.com/blevesearch/bleve/v2/analysis/lang/en"
+	"github.com/blevesearch/bleve/v2/analysis/lang/ru"
+	"github.com/blevesearch/bleve/v2/mapping"
+	"github.com/blevesearch/bleve/v2/search/query"
+
+	"library-go/models"
+)
+
+// bleveDocument is the indexed form of a book
+type bleveDocument struct {
+	Title       string `json:"title"`
+	Author      string `json:"author"`
+	Description string `json:"description"`
+	ISBN        string `json:"isbn"`
+	UpdatedAt   string `json:"updated_at"`
+}
+
+// bleveBackend is an embedded Bleve index stored on disk
+type bleveBackend struct {
+	index bleve.Index
+}
+
+// openBleve opens the index at path, creating it when it does not exist
+func openBleve(path string) (*bleveBackend, error) {
+	index, err := bleve.Open(path)
+	if errors.Is(err, bleve.ErrorIndexPathDoesNotExist) {
+		index, err = bleve.New(path, bleveMapping())
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &bleveBackend{index: index}, nil
+}
+
+// bleveMapping indexes title and description twice, with the English and the
+// Russian analyzer, so stemming works for either language without knowing it
+func bleveMapping() mapping.IndexMapping {
+	textField := func(name, analyzer string) *mapping.FieldMapping {
+		field := bleve.NewTextFieldMapping()
+		field.Name = name
+		field.Analyzer = analyzer
+		field.Store = false
+		field.IncludeTermVectors = false
+		return field
+	}
+
+	book := bleve.NewDocumentMapping()
+	book.AddFieldMappingsAt("title", textField("title", en.AnalyzerName), textField("title_ru", ru.AnalyzerName))
+	book.AddFieldMappingsAt("description", textField("description", en.AnalyzerName), textField("description_ru", ru.AnalyzerName))
+	book.AddFieldMappingsAt("author", textField("author", standard.Name))
+	book.AddFieldMappingsAt("isbn", textField("isbn", keyword.Name))
+
+	updatedAt := bleve.NewTextFieldMapping()
+	updatedAt.Index = false
+	updatedAt.Store = true
+	book.AddFieldMappingsAt("updated_at", updatedAt)
+
+	indexMapping := bleve.NewIndexMapping()
+	indexMapping.DefaultMapping = book
+	return indexMapping
+}
+
+func (b *bleveBackend) Name() string {
+	return "bleve"
+}
+
+// Match requires every query term to match at least one field, tolerating one typo per term
+func (b *bleveBackend) Match(text string, limit int) ([]Candidate, error) {
+	terms := Terms(text)
+	if len(terms) == 0 {
+		return []Candidate{}, nil
+	}
+
+	fields := []struct {
+		name  string
+		boost float64
+	}{
+		{"title", 10}, {"title_ru", 10}, {"author", 5}, {"description", 1}, {"description_ru", 1},
+	}
+
+	conjuncts := make([]query.Query, 0, len(terms))
+	for _, term := range terms {
+		disjuncts := make([]query.Query, 0, len(fields)+1)
+		for _, field := range fields {
+			match := bleve.NewMatchQuery(term)
+			match.SetField(field.name)
+			match.SetBoost(field.boost)
+			if len([]rune(term)) > 3 {
+				match.SetFuzziness(1)
+			}
+			disjuncts = append(disjuncts, match)
+		}
+		isbn := bleve.NewTermQuery(term)
+		isbn.SetField("isbn")
+		isbn.SetBoost(10)
+		disjuncts = append(disjuncts, isbn)
+		conjuncts = append(conjuncts, bleve.NewDisjunctionQuery(disjuncts...))
+	}
+
+	request := bleve.NewSearchRequestOptions(bleve.NewConjunctionQuery(conjuncts...), limit, 0, false)
+	result, err := b.index.Search(request)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := make([]Candidate, 0, len(result.Hits))
+	for _, hit := range result.Hits {
+		id, err := strconv.ParseUint(hit.ID, 10, 32)
+		if err != nil {
+			continue
+		}
+		candidates = append(candidates, Candidate{ID: uint(id), Score: hit.Score})
+	}
+	return candidates, nil
+}
+
+func (b *bleveBackend) Index(books ...models.Book) error {
+	batch := b.index.NewBatch()
+	for _, book := range books {
+		doc := bleveDocument{
+			Title:     book.Title,
+			Author:    book.Author,
+			UpdatedAt: book.UpdatedAt.UTC().Format(time.RFC3339Nano),
+		}
+		if book.Description != nil {
+			doc.Description = *book.Description
+		}
+		if book.ISBN != nil {
+			doc.ISBN = strings.ReplaceAll(*book.ISBN, "-", "")
+		}
+		if err := batch.Index(strconv.FormatUint(uint64(book.ID), 10), doc); err != nil {
+			return err
+		}
+	}
+	return b.index.Batch(batch)
+}
+
+func (b *bleveBackend) Remove(ids ...uint) error {
+	batch := b.index.NewBatch()
+	for _, id := range ids {
+		batch.Delete(strconv.FormatUint(uint64(id), 10))
+	}
+	return b.index.Batch(batch)
+}
+
+// Documents pages through every document, reading the stored updated_at field
+func (b *bleveBackend) Documents() (map[uint]time.Time, error) {
+	count, err := b.index.DocCount()
+	if err != nil {
+		return nil, err
+	}
+
+	documents := make(map[uint]time.Time, count)
+	const pageSize = 1000
+	for from := 0; ; from += pageSize {
+		request := bleve.NewSearchRequestOptions(bleve.NewMatchAllQuery(), pageSize, from, false)
+		request.Fields = []string{"updated_at"}
+		result, err := b.index.Search(request)
+		if err != nil {
+			return nil, err
+		}
+		for _, hit := range result.Hits {
+			id, err := strconv.ParseUint(hit.ID, 10, 32)
+			if err != nil {
+				continue
+			}
+			var updatedAt time.Time
+			if raw, ok := hit.Fields["updated_at"].(string); ok {
+				updatedAt, _ = time.Parse(time.RFC3339Nano, raw)
+			}
+			documents[uint(id)] = updatedAt
+		}
+		if len(result.Hits) < pageSize {
+			break
+		}
+	}
+	return documents, nil
+}
+
+func (b *bleveBackend) Close() error {
+	return b.index.Close()
+}
@@ -0,0 +1,133 @@
+package search
+
+import (
+	"gorm.io/gorm"
+)
+
+// maxFacetValues caps the number of values returned for open-ended facets such as author
+const maxFacetValues = 20
+
+// FacetValue is one drill-down option of a facet with the number of matching books
+type FacetValue struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// Facets maps a facet name to its values
+type Facets map[string][]FacetValue
+
+// availableExpr is true for books with at least one copy that is not on loan
+const availableExpr = `books.copies > (SELECT count(*) FROM borrows
+	WHERE borrows.book_id = books.id AND borrows.is_returned = ?)`
+
+// categoryJoin reaches the categories books are directly assigned to
+const categoryJoin = `JOIN book_categories ON book_categories.book_id = books.id
+	JOIN categories ON categories.id = book_categories.category_id`
+
+// facet computes the counts of one facet with a single grouped query
+type facet struct {
+	name    string
+	value   string // SQL expression of the facet value
+	args    []interface{}
+	joins   string // Optional join reaching the facet value
+	where   string // Optional condition excluding books without a value
+	order   string
+	limited bool
+}
+
+var facets = []facet{
+	{
+		name:    "author",
+		value:   "books.author",
+		order:   "count DESC, value",
+		limited: true,
+	},
+	{
+		name:    "subject",
+		value:   "categories.slug",
+		joins:   categoryJoin,
+		where:   "categories.kind = 'subject'",
+		order:   "count DESC, value",
+		limited: true,
+	},
+	{
+		name:    "genre",
+		value:   "categories.slug",
+		joins:   categoryJoin,
+		where:   "categories.kind = 'genre'",
+		order:   "count DESC, value",
+		limited: true,
+	},
+	{
+		name:  "language",
+		value: "books.language",
+		where: "books.language IS NOT NULL",
+		order: "count DESC, value",
+	},
+	{
+		name:  "format",
+		value: "books.format",
+		order: "value",
+	},
+	{
+		name:  "decade",
+		value: "(books.year / 10) * 10",
+		where: "books.year IS NOT NULL",
+		order: "value",
+	},
+	{
+		name:  "availability",
+		value: "CASE WHEN " + availableExpr + " THEN 'available' ELSE 'unavailable' END",
+		args:  []interface{}{false},
+		order: "value",
+	},
+}
+
+// computeFacets runs one grouped query per facet over the matches produced by base
+func computeFacets(base func() *gorm.DB) (Facets, error) {
+	result := make(Facets, len(facets))
+	for _, f := range facets {
+		tx := base().Select(f.value+" AS value, count(*) AS count", f.args...)
+		if f.joins != "" {
+			tx = tx.Joins(f.joins)
+		}
+		if f.where != "" {
+			tx = tx.Where(f.where)
+		}
+		tx = tx.Group("value").Order(f.order)
+		if f.limited {
+			tx = tx.Limit(maxFacetValues)
+		}
+
+		values := []FacetValue{}
+		if err := tx.Scan(&values).Error; err != nil {
+			return nil, err
+		}
+		result[f.name] = values
+	}
+	return result, nil
+}
+
+// DecadeScope restricts a search to books published in the decade starting at the given year
+func DecadeScope(decade int) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("books.year >= ? AND books.year < ?", decade, decade+10)
+	}
+}
+
+// AvailabilityScope restricts a search to books that do or do not have a copy on the shelf
+func AvailabilityScope(available bool) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if available {
+			return db.Where(availableExpr, false)
+		}
+		return db.Where("NOT ("+availableExpr+")", false)
+	}
+}
+
+// CategoryScope restricts a search to the books returned by a subquery of book IDs
+func CategoryScope(bookIDs *gorm.DB) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("books.id IN (?)", bookIDs)
+	}
+}
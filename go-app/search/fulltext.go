@@ -0,0 +1,358 @@
+package search
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"unicode"
+
+	"gorm.io/gorm"
+
+	"library-go/config"
+	"library-go/models"
+)
+
+// Full-text backends, chosen once in Setup depending on the connected database
+const (
+	modePostgres = "postgres" // tsvector column with a GIN index
+	modeFTS5     = "fts5"     // SQLite FTS5 virtual table maintained by triggers
+	modeLike     = "like"     // Portable LIKE fallback with a weighted score
+)
+
+var mode = modeLike
+
+// Hit is a book matched by a search query together with its relevance
+type Hit struct {
+	models.Book
+	Rank float64 `json:"rank"`
+}
+
+// Setup prepares the full-text index for the connected database. The index is
+// maintained by the database itself (a generated column on Postgres, triggers
+// on SQLite) so it stays in sync with every create, update and delete.
+// When SEARCH_BACKEND is "bleve" an embedded Bleve index answers queries instead.
+func Setup(db *gorm.DB) error {
+	if config.SearchBackend == "bleve" {
+		b, err := openBleve(config.SearchIndexPath)
+		if err != nil {
+			return fmt.Errorf("failed to open Bleve index: %w", err)
+		}
+		if err := useBackend(db, b); err != nil {
+			return err
+		}
+	}
+
+	switch db.Dialector.Name() {
+	case "postgres":
+		if err := setupPostgres(db); err != nil {
+			return err
+		}
+		mode = modePostgres
+	case "sqlite":
+		if err := setupFTS5(db); err != nil {
+			log.Println("SQLite FTS5 is unavailable (build with make or -tags sqlite_fts5), falling back to LIKE search:", err)
+			mode = modeLike
+			return nil
+		}
+		mode = modeFTS5
+	default:
+		mode = modeLike
+	}
+	return nil
+}
+
+func setupPostgres(db *gorm.DB) error {
+	statements := []string{
+		`ALTER TABLE books ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+			setweight(to_tsvector('simple', coalesce(title, '')), 'A') ||
+			setweight(to_tsvector('simple', replace(coalesce(isbn, ''), '-', '')), 'A') ||
+			setweight(to_tsvector('simple', coalesce(author, '')), 'B') ||
+			setweight(to_tsvector('simple', coalesce(description, '')), 'C')
+		) STORED`,
+		`CREATE INDEX IF NOT EXISTS idx_books_search_vector ON books USING GIN (search_vector)`,
+	}
+	for _, statement := range statements {
+		if err := db.Exec(statement).Error; err != nil {
+			return fmt.Errorf("failed to set up Postgres full-text search: %w", err)
+		}
+	}
+	return nil
+}
+
+func setupFTS5(db *gorm.DB) error {
+	var enabled int
+	if err := db.Raw("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&enabled).Error; err != nil {
+		return err
+	}
+	if enabled == 0 {
+		return errors.New("SQLite was compiled without FTS5")
+	}
+
+	var existing int64
+	db.Raw("SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name = 'books_fts'").Scan(&existing)
+
+	// A contentless table lets us index the ISBN without hyphens
+	statements := []string{
+		`CREATE VIRTUAL TABLE IF NOT EXISTS books_fts USING fts5(
+			title, author, description, isbn,
+			content='', tokenize='unicode61 remove_diacritics 2'
+		)`,
+		`CREATE TRIGGER IF NOT EXISTS books_fts_ai AFTER INSERT ON books BEGIN
+			INSERT INTO books_fts(rowid, title, author, description, isbn)
+			VALUES (new.id, new.title, new.author, coalesce(new.description, ''), replace(coalesce(new.isbn, ''), '-', ''));
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS books_fts_ad AFTER DELETE ON books BEGIN
+			INSERT INTO books_fts(books_fts, rowid, title, author, description, isbn)
+			VALUES ('delete', old.id, old.title, old.author, coalesce(old.description, ''), replace(coalesce(old.isbn, ''), '-', ''));
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS books_fts_au AFTER UPDATE ON books BEGIN
+			INSERT INTO books_fts(books_fts, rowid, title, author, description, isbn)
+			VALUES ('delete', old.id, old.title, old.author, coalesce(old.description, ''), replace(coalesce(old.isbn, ''), '-', ''));
+			INSERT INTO books_fts(rowid, title, author, description, isbn)
+			VALUES (new.id, new.title, new.author, coalesce(new.description, ''), replace(coalesce(new.isbn, ''), '-', ''));
+		END`,
+	}
+	for _, statement := range statements {
+		if err := db.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+
+	// Index books that existed before the virtual table was created
+	if existing == 0 {
+		return db.Exec(`INSERT INTO books_fts(rowid, title, author, description, isbn)
+			SELECT id, title, author, coalesce(description, ''), replace(coalesce(isbn, ''), '-', '') FROM books`).Error
+	}
+	return nil
+}
+
+// Request describes a catalog search. An empty query matches every book.
+type Request struct {
+	Query  string
+	Scopes []func(*gorm.DB) *gorm.DB // Additional filters on the books table
+	Limit  int
+	Offset int
+	Facets bool // Compute facet counts over all matches
+
+	// Candidates, when not nil, replaces the full-text query with pre-scored
+	// books such as fuzzy matches, ranked by their score
+	Candidates []Candidate
+}
+
+// Candidate is a book matched outside the database together with its score
+type Candidate struct {
+	ID    uint
+	Score float64
+}
+
+// Result is a page of search hits with the total number of matches
+type Result struct {
+	Hits   []Hit
+	Total  int64
+	Facets Facets
+}
+
+// Books runs a ranked full-text query over title, author, description and ISBN,
+// returning a page of the best matches first
+func Books(db *gorm.DB, req Request) (*Result, error) {
+	q := newRankedQuery(Terms(req.Query))
+	if req.Candidates == nil && backend != nil && req.Query != "" {
+		candidates, err := backend.Match(req.Query, maxBackendCandidates)
+		if err != nil {
+			return nil, err
+		}
+		req.Candidates = candidates
+	}
+	if req.Candidates != nil {
+		q = candidateQuery(req.Candidates)
+	}
+
+	// base returns a fresh query over the matching books, so each statement starts clean
+	base := func() *gorm.DB {
+		tx := db.Table("books")
+		if q.join != "" {
+			tx = tx.Joins(q.join)
+		}
+		if q.where != "" {
+			tx = tx.Where(q.where, q.whereArgs...)
+		}
+		return tx.Scopes(req.Scopes...)
+	}
+
+	result := &Result{Hits: []Hit{}}
+	if err := base().Count(&result.Total).Error; err != nil {
+		return nil, err
+	}
+
+	var ranked []struct {
+		ID   uint
+		Rank float64
+	}
+	err := base().Select("books.id AS id, "+q.rank+" AS rank", q.rankArgs...).
+		Order("rank DESC").Order("books.id").
+		Limit(req.Limit).Offset(req.Offset).
+		Scan(&ranked).Error
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uint, len(ranked))
+	for i, r := range ranked {
+		ids[i] = r.ID
+	}
+
+	var books []models.Book
+	if len(ids) > 0 {
+		if err := db.Where("id IN ?", ids).Find(&books).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	byID := make(map[uint]models.Book, len(books))
+	for _, book := range books {
+		byID[book.ID] = book
+	}
+
+	for _, r := range ranked {
+		if book, ok := byID[r.ID]; ok {
+			result.Hits = append(result.Hits, Hit{Book: book, Rank: r.Rank})
+		}
+	}
+
+	if req.Facets {
+		facets, err := computeFacets(base)
+		if err != nil {
+			return nil, err
+		}
+		result.Facets = facets
+	}
+
+	return result, nil
+}
+
+// rankedQuery holds the SQL fragments of a backend, ranks are higher-is-better
+type rankedQuery struct {
+	join      string
+	where     string
+	whereArgs []interface{}
+	rank      string
+	rankArgs  []interface{}
+}
+
+func newRankedQuery(terms []string) rankedQuery {
+	if len(terms) == 0 {
+		return rankedQuery{rank: "0"}
+	}
+	switch mode {
+	case modePostgres:
+		return postgresQuery(terms)
+	case modeFTS5:
+		return fts5Query(terms)
+	}
+	return likeQuery(terms)
+}
+
+func candidateQuery(candidates []Candidate) rankedQuery {
+	if len(candidates) == 0 {
+		return rankedQuery{where: "1 = 0", rank: "0"}
+	}
+
+	q := rankedQuery{where: "books.id IN ?"}
+	ids := make([]uint, len(candidates))
+	var cases strings.Builder
+	cases.WriteString("CASE books.id")
+	for i, candidate := range candidates {
+		ids[i] = candidate.ID
+		cases.WriteString(" WHEN ? THEN CAST(? AS REAL)")
+		q.rankArgs = append(q.rankArgs, candidate.ID, candidate.Score)
+	}
+	cases.WriteString(" ELSE 0 END")
+	q.whereArgs = []interface{}{ids}
+	q.rank = cases.String()
+	return q
+}
+
+func postgresQuery(terms []string) rankedQuery {
+	prefixes := make([]string, len(terms))
+	for i, term := range terms {
+		prefixes[i] = term + ":*"
+	}
+	tsquery := strings.Join(prefixes, " & ")
+
+	return rankedQuery{
+		where:     "books.search_vector @@ to_tsquery('simple', ?)",
+		whereArgs: []interface{}{tsquery},
+		rank:      "ts_rank(books.search_vector, to_tsquery('simple', ?))",
+		rankArgs:  []interface{}{tsquery},
+	}
+}
+
+func fts5Query(terms []string) rankedQuery {
+	prefixes := make([]string, len(terms))
+	for i, term := range terms {
+		prefixes[i] = `"` + term + `"*`
+	}
+
+	// bm25 is lower-is-better, negate it so every backend ranks higher-is-better
+	return rankedQuery{
+		join:      "JOIN books_fts ON books_fts.rowid = books.id",
+		where:     "books_fts MATCH ?",
+		whereArgs: []interface{}{strings.Join(prefixes, " ")},
+		rank:      "-bm25(books_fts, 10.0, 5.0, 1.0, 10.0)",
+	}
+}
+
+func likeQuery(terms []string) rankedQuery {
+	var q rankedQuery
+	var conditions, scores []string
+	for _, term := range terms {
+		pattern := "%" + term + "%"
+		conditions = append(conditions, `(lower(books.title) LIKE ? OR lower(books.author) LIKE ? OR
+			lower(coalesce(books.description, '')) LIKE ? OR replace(coalesce(books.isbn, ''), '-', '') LIKE ?)`)
+		q.whereArgs = append(q.whereArgs, pattern, pattern, pattern, pattern)
+		scores = append(scores, `(CASE WHEN lower(books.title) LIKE ? THEN 10 ELSE 0 END +
+			CASE WHEN replace(coalesce(books.isbn, ''), '-', '') LIKE ? THEN 10 ELSE 0 END +
+			CASE WHEN lower(books.author) LIKE ? THEN 5 ELSE 0 END +
+			CASE WHEN lower(coalesce(books.description, '')) LIKE ? THEN 1 ELSE 0 END)`)
+		q.rankArgs = append(q.rankArgs, pattern, pattern, pattern, pattern)
+	}
+	q.rank = strings.Join(scores, " + ")
+	q.where = strings.Join(conditions, " AND ")
+	return q
+}
+
+// Terms splits a user query into lower-cased search terms. Words made only of
+// digits, hyphens and X are treated as ISBNs and have their hyphens removed;
+// complete ISBN-10s are converted to the stored ISBN-13.
+func Terms(query string) []string {
+	var terms []string
+	for _, word := range strings.Fields(strings.ToLower(query)) {
+		if looksLikeISBN(word) {
+			if isbn, err := models.NormalizeISBN(word); err == nil {
+				terms = append(terms, isbn)
+			} else {
+				terms = append(terms, strings.ReplaceAll(word, "-", ""))
+			}
+			continue
+		}
+		terms = append(terms, strings.FieldsFunc(word, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})...)
+	}
+	return terms
+}
+
+func looksLikeISBN(word string) bool {
+	digits := 0
+	for _, r := range word {
+		switch {
+		case r >= '0' && r <= '9':
+			digits++
+		case r == '-' || r == 'x':
+		default:
+			return false
+		}
+	}
+	return digits >= 9
+}
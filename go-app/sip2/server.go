@@ -0,0 +1,133 @@
+package sip2
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"strings"
+	"time"
+)
+
+// Handler answers the requests of a connection. Every request but a login
+// is refused until the handler marks the session authenticated.
+type Handler interface {
+	ServeSIP(s *Session, req *Message) *Message
+}
+
+// Session is the state of one self-check connection
+type Session struct {
+	RemoteAddr    string
+	Authenticated bool
+	UserID        string // Login user id of the machine
+	Location      string // Location code sent with the login
+}
+
+// maxLineLength bounds a message, far above the longest a machine sends, so
+// a client that never ends its line cannot make the server buffer without limit
+const maxLineLength = 4 << 10
+
+// resend asks the machine to send its last message again, always with a checksum
+var resend = RequestSCResend + FieldChecksum + Checksum(RequestSCResend+FieldChecksum)
+
+// Server accepts SIP2 connections and passes their requests to Handler
+type Server struct {
+	Addr    string
+	Handler Handler
+
+	// IdleTimeout closes connections that send nothing for this long, zero meaning never
+	IdleTimeout time.Duration
+}
+
+// ListenAndServe listens on the TCP address and serves connections until the listener fails
+func (srv *Server) ListenAndServe() error {
+	listener, err := net.Listen("tcp", srv.Addr)
+	if err != nil {
+		return err
+	}
+	return srv.Serve(listener)
+}
+
+// Serve serves the connections accepted by listener
+func (srv *Server) Serve(listener net.Listener) error {
+	defer listener.Close()
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				time.Sleep(100 * time.Millisecond)
+				continue
+			}
+			return err
+		}
+		go srv.serveConn(conn)
+	}
+}
+
+// serveConn answers the requests of one connection in turn. A request with a
+// bad checksum, or one the handler does not support, is answered with a
+// request to resend it, and a request repeated with the same sequence number
+// gets the last response again instead of being carried out twice.
+func (srv *Server) serveConn(conn net.Conn) {
+	defer conn.Close()
+	session := &Session{RemoteAddr: conn.RemoteAddr().String()}
+	reader := bufio.NewReaderSize(conn, maxLineLength)
+	var lastLine, lastResponse string
+
+	for {
+		if srv.IdleTimeout > 0 {
+			conn.SetReadDeadline(time.Now().Add(srv.IdleTimeout))
+		}
+		slice, err := reader.ReadSlice('\r')
+		if errors.Is(err, bufio.ErrBufferFull) {
+			log.Println("SIP2 connection from", session.RemoteAddr, "sent a message longer than", maxLineLength, "bytes")
+			return
+		}
+		if err != nil {
+			if err != io.EOF && !errors.Is(err, net.ErrClosed) {
+				log.Println("SIP2 connection from", session.RemoteAddr, "closed:", err)
+			}
+			return
+		}
+		// Some machines end messages with CR LF, leaving the LF at the start of the next
+		line := strings.TrimLeft(strings.TrimSuffix(string(slice), "\r"), "\n")
+		if line == "" {
+			continue
+		}
+
+		var response string
+		req, err := Parse(line)
+		switch {
+		case errors.Is(err, ErrChecksum):
+			response = resend
+		case err != nil:
+			log.Println("SIP2 request from", session.RemoteAddr, "rejected:", err)
+			response = resend
+		case req.Code == RequestACSResend && lastResponse != "":
+			response = lastResponse
+		case req.Sequence >= 0 && line == lastLine:
+			response = lastResponse
+		case !session.Authenticated && req.Code != Login:
+			log.Println("SIP2 connection from", session.RemoteAddr, "sent message", req.Code, "before logging in")
+			return
+		default:
+			res := srv.Handler.ServeSIP(session, req)
+			if res == nil {
+				// Answered so the machine need not wait for its own timeout
+				log.Println("SIP2 message", req.Code, "from", session.RemoteAddr, "is not supported")
+				response = resend
+				break
+			}
+			res.Sequence = req.Sequence
+			response = res.Encode()
+			lastLine, lastResponse = line, response
+		}
+
+		if _, err := io.WriteString(conn, response+"\r"); err != nil {
+			log.Println("SIP2 connection from", session.RemoteAddr, "closed:", err)
+			return
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package sip2
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// countingHandler logs in user "ok", answers status and checkout requests and
+// counts the checkouts it carries out
+type countingHandler struct {
+	checkouts int
+}
+
+func (h *countingHandler) ServeSIP(s *Session, req *Message) *Message {
+	switch req.Code {
+	case Login:
+		s.Authenticated = req.Get(FieldLoginUserID) == "ok"
+		return NewMessage(LoginResponse, Digit(s.Authenticated))
+	case SCStatus:
+		return NewMessage(ACSStatus, "Y")
+	case Checkout:
+		h.checkouts++
+		return NewMessage(CheckoutResponse, "1").Add(FieldItemID, req.Get(FieldItemID))
+	}
+	return nil
+}
+
+// testClient speaks to a server over an in-memory connection
+type testClient struct {
+	t      *testing.T
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func serveTest(t *testing.T, handler Handler) *testClient {
+	t.Helper()
+	listener := NewPipeListener()
+	srv := &Server{Handler: handler}
+	go srv.Serve(listener)
+	t.Cleanup(func() { listener.Close() })
+
+	conn, err := listener.Dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return &testClient{t: t, conn: conn, reader: bufio.NewReader(conn)}
+}
+
+// send writes a request, adding the error detection suffix when sequence is not negative
+func (c *testClient) send(message string, sequence int) {
+	c.t.Helper()
+	line := message
+	if sequence >= 0 {
+		line += FieldSequence + string(rune('0'+sequence)) + FieldChecksum
+		line += Checksum(line)
+	}
+	c.sendRaw(line)
+}
+
+func (c *testClient) sendRaw(line string) {
+	c.t.Helper()
+	c.conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if _, err := io.WriteString(c.conn, line+"\r"); err != nil {
+		c.t.Fatalf("sending %q: %v", line, err)
+	}
+}
+
+// receive reads a response without its carriage return, failing the test on io.EOF
+func (c *testClient) receive() string {
+	c.t.Helper()
+	line, err := c.reader.ReadString('\r')
+	if err != nil {
+		c.t.Fatalf("reading response: %v", err)
+	}
+	return strings.TrimSuffix(line, "\r")
+}
+
+func (c *testClient) expect(want string) {
+	c.t.Helper()
+	if got := c.receive(); got != want {
+		c.t.Errorf("response %q, want %q", got, want)
+	}
+}
+
+func (c *testClient) login() {
+	c.t.Helper()
+	c.send("9300CNok|CO|", 0)
+	c.expect("941AY0AZFDFD")
+}
+
+func TestServerBadChecksum(t *testing.T) {
+	c := serveTest(t, &countingHandler{})
+	c.login()
+
+	c.sendRaw("9900302.00AY1AZ0000")
+	c.expect("96AZFEF6")
+	c.sendRaw("9")
+	c.expect("96AZFEF6")
+
+	// The machine sends the message again, now intact
+	c.send("9900302.00", 1)
+	c.expect("98YAY1AZFDD0")
+}
+
+func TestServerSequence(t *testing.T) {
+	c := serveTest(t, &countingHandler{})
+	c.login()
+
+	for _, sequence := range []int{1, 2, 9, 0} {
+		c.send("9900302.00", sequence)
+		m, err := Parse(c.receive())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m.Sequence != sequence {
+			t.Errorf("response sequence %d, want %d", m.Sequence, sequence)
+		}
+	}
+
+	// Without error detection the response has none either
+	c.send("9900302.00", -1)
+	c.expect("98Y")
+}
+
+func TestServerRequestResend(t *testing.T) {
+	c := serveTest(t, &countingHandler{})
+	c.login()
+
+	c.send("11YN20261018    120000                  AA1|AB42|AC|", 1)
+	last := c.receive()
+	c.sendRaw("97")
+	c.expect(last)
+	c.send("97", 2)
+	c.expect(last)
+}
+
+func TestServerDuplicate(t *testing.T) {
+	handler := &countingHandler{}
+	c := serveTest(t, handler)
+	c.login()
+
+	checkout := "11YN20261018    120000                  AA1|AB42|AC|"
+	c.send(checkout, 3)
+	first := c.receive()
+	c.send(checkout, 3)
+	c.expect(first)
+	if handler.checkouts != 1 {
+		t.Errorf("repeated request carried out %d times", handler.checkouts)
+	}
+
+	// A new sequence number is a new request
+	c.send(checkout, 4)
+	c.receive()
+	if handler.checkouts != 2 {
+		t.Errorf("%d checkouts after a new sequence number, want 2", handler.checkouts)
+	}
+
+	// Without a sequence number a repeat cannot be told apart from a new request
+	c.send(checkout, -1)
+	c.receive()
+	c.send(checkout, -1)
+	c.receive()
+	if handler.checkouts != 4 {
+		t.Errorf("%d checkouts without sequence numbers, want 4", handler.checkouts)
+	}
+}
+
+func TestServerLoginRequired(t *testing.T) {
+	c := serveTest(t, &countingHandler{})
+
+	c.send("9300CNwrong|CO|", 0)
+	c.expect("940AY0AZFDFE")
+
+	c.send("9900302.00", 1)
+	if line, err := c.reader.ReadString('\r'); !errors.Is(err, io.EOF) {
+		t.Errorf("request before login answered with %q, %v", line, err)
+	}
+}
+
+func TestServerUnsupported(t *testing.T) {
+	c := serveTest(t, &countingHandler{})
+	c.login()
+
+	// Item information is not answered by the handler, and the machine is
+	// told so instead of waiting for a response
+	c.send("1720261018    120000AB42|", 1)
+	c.expect("96AZFEF6")
+	c.send("9900302.00", 2)
+	c.expect("98YAY2AZFDCF")
+}
+
+func TestServerLongLine(t *testing.T) {
+	c := serveTest(t, &countingHandler{})
+
+	// A line that never ends is cut off before login rather than buffered
+	go io.WriteString(c.conn, "93"+strings.Repeat("0", 2*maxLineLength))
+	if line, err := c.reader.ReadString('\r'); !errors.Is(err, io.EOF) {
+		t.Errorf("overlong line answered with %q, %v", line, err)
+	}
+}
+
+func TestServerLineFeeds(t *testing.T) {
+	c := serveTest(t, &countingHandler{})
+	c.login()
+
+	c.sendRaw("\n9900302.00AY1AZFCA5")
+	c.expect("98YAY1AZFDD0")
+	c.sendRaw("\n")
+	c.send("9900302.00", 2)
+	c.expect("98YAY2AZFDCF")
+}
@@ -0,0 +1,291 @@
+// Package sip2 speaks the 3M Standard Interchange Protocol 2.0 that
+// self-check machines and security gates use to reach the circulation
+// system: messages, their checksums and sequence numbers, and a TCP server.
+package sip2
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Request and response message codes
+const (
+	PatronStatusRequest       = "23"
+	PatronStatusResponse      = "24"
+	Checkout                  = "11"
+	CheckoutResponse          = "12"
+	Checkin                   = "09"
+	CheckinResponse           = "10"
+	SCStatus                  = "99"
+	ACSStatus                 = "98"
+	RequestACSResend          = "97"
+	RequestSCResend           = "96"
+	Login                     = "93"
+	LoginResponse             = "94"
+	PatronInformation         = "63"
+	PatronInformationResponse = "64"
+	EndPatronSession          = "35"
+	EndSessionResponse        = "36"
+	FeePaid                   = "37"
+	FeePaidResponse           = "38"
+	ItemInformation           = "17"
+	ItemInformationResponse   = "18"
+	Renew                     = "29"
+	RenewResponse             = "30"
+)
+
+// fixedLengths is the length of the fixed fields of each request, which
+// precede the variable-length fields
+var fixedLengths = map[string]int{
+	PatronStatusRequest: 3 + 18,
+	Checkout:            1 + 1 + 18 + 18,
+	Checkin:             1 + 18 + 18,
+	SCStatus:            1 + 3 + 4,
+	RequestACSResend:    0,
+	Login:               1 + 1,
+	PatronInformation:   3 + 18 + 10,
+	EndPatronSession:    18,
+	FeePaid:             18 + 2 + 2 + 3,
+	ItemInformation:     18,
+	Renew:               1 + 1 + 18 + 18,
+}
+
+// Field identifiers of the variable-length fields
+const (
+	FieldPatronID          = "AA"
+	FieldItemID            = "AB"
+	FieldTerminalPassword  = "AC"
+	FieldPatronPassword    = "AD"
+	FieldPersonalName      = "AE"
+	FieldScreenMessage     = "AF"
+	FieldPrintLine         = "AG"
+	FieldDueDate           = "AH"
+	FieldTitle             = "AJ"
+	FieldLibraryName       = "AM"
+	FieldTerminalLocation  = "AN"
+	FieldInstitutionID     = "AO"
+	FieldCurrentLocation   = "AP"
+	FieldPermanentLocation = "AQ"
+	FieldHoldItems         = "AS"
+	FieldOverdueItems      = "AT"
+	FieldChargedItems      = "AU"
+	FieldFineItems         = "AV"
+	FieldSequence          = "AY"
+	FieldChecksum          = "AZ"
+	FieldHomeAddress       = "BD"
+	FieldEmail             = "BE"
+	FieldPhone             = "BF"
+	FieldCurrencyType      = "BH"
+	FieldFeeAmount         = "BV"
+	FieldValidPatron       = "BL"
+	FieldStartItem         = "BP"
+	FieldEndItem           = "BQ"
+	FieldSupportedMessages = "BX"
+	FieldLoginUserID       = "CN"
+	FieldLoginPassword     = "CO"
+	FieldLocationCode      = "CP"
+	FieldValidPassword     = "CQ"
+	FieldHoldQueueLength   = "CF"
+	FieldMediaType         = "CK"
+	FieldAlertType         = "CV"
+	FieldUnavailableHolds  = "CD"
+)
+
+// ErrChecksum is returned by Parse for a message whose checksum does not match
+var ErrChecksum = errors.New("sip2: checksum mismatch")
+
+// Message is a SIP2 request or response: a two-digit code, fixed fields
+// concatenated in the order of the specification and identified fields
+type Message struct {
+	Code   string
+	Fixed  string
+	Fields []Field
+
+	// Sequence is the sequence number of the error detection suffix, -1 for
+	// a message sent without one
+	Sequence int
+}
+
+// Field is a variable-length field
+type Field struct {
+	ID    string
+	Value string
+}
+
+// NewMessage returns a response with the given fixed fields
+func NewMessage(code string, fixed ...string) *Message {
+	return &Message{Code: code, Fixed: strings.Join(fixed, ""), Sequence: -1}
+}
+
+// Get returns the value of the first field with the id, empty if there is none
+func (m *Message) Get(id string) string {
+	for _, f := range m.Fields {
+		if f.ID == id {
+			return f.Value
+		}
+	}
+	return ""
+}
+
+// Has reports whether the message has a field with the id
+func (m *Message) Has(id string) bool {
+	for _, f := range m.Fields {
+		if f.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// FixedField returns the fixed field at offset with the given length, empty when the message is short
+func (m *Message) FixedField(offset, length int) string {
+	if offset+length > len(m.Fixed) {
+		return ""
+	}
+	return m.Fixed[offset : offset+length]
+}
+
+// Add appends a field, which SIP2 requires even when empty
+func (m *Message) Add(id, value string) *Message {
+	m.Fields = append(m.Fields, Field{ID: id, Value: value})
+	return m
+}
+
+// AddOptional appends a field unless its value is empty
+func (m *Message) AddOptional(id, value string) *Message {
+	if value == "" {
+		return m
+	}
+	return m.Add(id, value)
+}
+
+// Encode writes the message without its terminating carriage return, with
+// the error detection suffix when it has a sequence number
+func (m *Message) Encode() string {
+	var b strings.Builder
+	b.WriteString(m.Code)
+	b.WriteString(m.Fixed)
+	for _, f := range m.Fields {
+		b.WriteString(f.ID)
+		b.WriteString(fieldValue(f.Value))
+		b.WriteByte('|')
+	}
+	if m.Sequence < 0 {
+		return b.String()
+	}
+	b.WriteString(FieldSequence + strconv.Itoa(m.Sequence) + FieldChecksum)
+	return b.String() + Checksum(b.String())
+}
+
+// fieldValue drops the characters that would end a field or the message
+func fieldValue(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '|' || r < ' ' {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// Parse decodes a request, without its terminating carriage return. The
+// checksum of a message with an error detection suffix is verified.
+func Parse(line string) (*Message, error) {
+	m := &Message{Sequence: -1}
+	if i := strings.LastIndex(line, FieldChecksum); i >= 0 && i == len(line)-6 {
+		if Checksum(line[:i+2]) != strings.ToUpper(line[i+2:]) {
+			return nil, ErrChecksum
+		}
+		line = line[:i]
+		if j := len(line) - 3; j >= 0 && line[j:j+2] == FieldSequence {
+			sequence, err := strconv.Atoi(line[j+2:])
+			if err != nil {
+				return nil, fmt.Errorf("sip2: invalid sequence number %q", line[j+2:])
+			}
+			m.Sequence = sequence
+			line = line[:j]
+		}
+	}
+
+	if len(line) < 2 {
+		return nil, fmt.Errorf("sip2: message too short: %q", line)
+	}
+	m.Code = line[:2]
+	fixed, ok := fixedLengths[m.Code]
+	if !ok {
+		return m, nil
+	}
+	if len(line) < 2+fixed {
+		return nil, fmt.Errorf("sip2: message %s too short for its fixed fields", m.Code)
+	}
+	m.Fixed = line[2 : 2+fixed]
+
+	for _, field := range strings.Split(line[2+fixed:], "|") {
+		if len(field) >= 2 {
+			m.Fields = append(m.Fields, Field{ID: field[:2], Value: field[2:]})
+		}
+	}
+	return m, nil
+}
+
+// Checksum returns the error detection checksum of a message up to and
+// including the AZ field identifier: the two's complement of the sum of its
+// bytes, as four hexadecimal digits
+func Checksum(s string) string {
+	var sum uint16
+	for i := 0; i < len(s); i++ {
+		sum += uint16(s[i])
+	}
+	return fmt.Sprintf("%04X", -sum)
+}
+
+// DateFormat is the layout of transaction dates, YYYYMMDDZZZZHHMMSS, with
+// the time zone left blank for local time
+const DateFormat = "20060102    150405"
+
+// FormatDate writes a transaction date in local time
+func FormatDate(t time.Time) string {
+	return t.Local().Format(DateFormat)
+}
+
+// ParseDate reads a transaction date, in UTC when its zone is Z and in local time otherwise
+func ParseDate(s string) (time.Time, error) {
+	if len(s) != len(DateFormat) {
+		return time.Time{}, fmt.Errorf("sip2: invalid date %q", s)
+	}
+	location := time.Local
+	if strings.TrimSpace(s[8:12]) == "Z" {
+		location = time.UTC
+	}
+	return time.ParseInLocation("20060102150405", s[:8]+s[12:], location)
+}
+
+// Bool writes a flag as Y or N
+func Bool(b bool) string {
+	if b {
+		return "Y"
+	}
+	return "N"
+}
+
+// Digit writes a flag as 1 or 0, as the ok fields of responses are
+func Digit(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
+
+// Count writes a count padded to width digits, capped at the largest that fits
+func Count(n, width int) string {
+	limit := 1
+	for i := 0; i < width; i++ {
+		limit *= 10
+	}
+	if n >= limit {
+		n = limit - 1
+	}
+	return fmt.Sprintf("%0*d", width, n)
+}
@@ -0,0 +1,136 @@
+package sip2
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestChecksum(t *testing.T) {
+	tests := []struct {
+		message, want string
+	}{
+		{"9300CNa@b.co|COpassword1|CPmain|AY0AZ", "F359"},
+		{"941AY0AZ", "FDFD"},
+		{"96AZ", "FEF6"},
+		{"9900302.00AY1AZ", "FCA5"},
+	}
+	for _, tt := range tests {
+		if got := Checksum(tt.message); got != tt.want {
+			t.Errorf("Checksum(%q) = %s, want %s", tt.message, got, tt.want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	m, err := Parse("11YN20261018    120000                  AOlib|AA1|AB0441172717|AC|AY4AZF0A4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Code != Checkout || m.Sequence != 4 {
+		t.Errorf("code %s sequence %d, want %s and 4", m.Code, m.Sequence, Checkout)
+	}
+	if got := m.FixedField(0, 2); got != "YN" {
+		t.Errorf("fixed fields start %q, want YN", got)
+	}
+	if got := m.FixedField(2, 18); got != "20261018    120000" {
+		t.Errorf("transaction date %q", got)
+	}
+	if m.Get(FieldPatronID) != "1" || m.Get(FieldItemID) != "0441172717" || m.Get(FieldInstitutionID) != "lib" {
+		t.Errorf("fields %+v", m.Fields)
+	}
+	if !m.Has(FieldTerminalPassword) || m.Get(FieldTerminalPassword) != "" {
+		t.Error("empty terminal password field not kept")
+	}
+	if m.Has(FieldPatronPassword) {
+		t.Error("absent patron password reported present")
+	}
+}
+
+func TestParseChecksum(t *testing.T) {
+	if _, err := Parse("9900302.00AY1AZ0000"); !errors.Is(err, ErrChecksum) {
+		t.Errorf("bad checksum: error %v, want ErrChecksum", err)
+	}
+	// Some machines send the checksum in lower case
+	if _, err := Parse("9900302.00AY1AZfca5"); err != nil {
+		t.Errorf("lower-case checksum: %v", err)
+	}
+
+	m, err := Parse("9900302.00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Sequence != -1 || m.Fixed != "00302.00" {
+		t.Errorf("message without error detection: sequence %d fixed %q", m.Sequence, m.Fixed)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, line := range []string{"9", "11YN2026", "99003"} {
+		if _, err := Parse(line); err == nil {
+			t.Errorf("Parse(%q) succeeded", line)
+		}
+	}
+}
+
+func TestEncode(t *testing.T) {
+	m := NewMessage(LoginResponse, "1")
+	if got := m.Encode(); got != "941" {
+		t.Errorf("without sequence: %q", got)
+	}
+	m.Sequence = 0
+	if got := m.Encode(); got != "941AY0AZFDFD" {
+		t.Errorf("with sequence: %q", got)
+	}
+
+	m = NewMessage(EndSessionResponse, "Y", "20261018    120000")
+	m.Add(FieldInstitutionID, "lib").Add(FieldPatronID, "").AddOptional(FieldScreenMessage, "")
+	m.Add(FieldPersonalName, "Ann|Reader\r")
+	if got := m.Encode(); got != "36Y20261018    120000AOlib|AA|AEAnnReader|" {
+		t.Errorf("fields: %q", got)
+	}
+
+	m.Sequence = 7
+	parsed, err := Parse(m.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Sequence != 7 {
+		t.Errorf("round trip sequence %d", parsed.Sequence)
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	at, err := ParseDate("20261018    213005")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := time.Date(2026, 10, 18, 21, 30, 5, 0, time.Local)
+	if !at.Equal(want) {
+		t.Errorf("local date %v, want %v", at, want)
+	}
+	if got := FormatDate(at); got != "20261018    213005" {
+		t.Errorf("FormatDate = %q", got)
+	}
+
+	at, err = ParseDate("20261018   Z213005")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2026, 10, 18, 21, 30, 5, 0, time.UTC); !at.Equal(want) {
+		t.Errorf("UTC date %v, want %v", at, want)
+	}
+
+	if _, err := ParseDate("20261018"); err == nil {
+		t.Error("short date accepted")
+	}
+}
+
+func TestCount(t *testing.T) {
+	if got := Count(7, 4); got != "0007" {
+		t.Errorf("Count(7, 4) = %q", got)
+	}
+	if got := Count(12345, 4); got != "9999" {
+		t.Errorf("Count(12345, 4) = %q", got)
+	}
+}
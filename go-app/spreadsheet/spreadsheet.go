@@ -0,0 +1,89 @@
+// Package spreadsheet reads the rows of CSV files and Excel (XLSX) workbooks
+// for bulk imports, without depending on an office suite library.
+package spreadsheet
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// Row is one row of a table with its 1-based row (or CSV line) number, so
+// problems can be reported where the user will find them
+type Row struct {
+	Number int
+	Cells  []string
+}
+
+// Blank reports whether every cell of the row is empty
+func (r Row) Blank() bool {
+	for _, cell := range r.Cells {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// zipMagic starts every XLSX file, which is a zip archive
+var zipMagic = []byte("PK\x03\x04")
+
+// Read returns the rows of a CSV file or of the first sheet of an XLSX
+// workbook, telling them apart by content
+func Read(data []byte) ([]Row, error) {
+	if bytes.HasPrefix(data, zipMagic) {
+		return ReadXLSX(data)
+	}
+	return ReadCSV(data)
+}
+
+// ReadCSV returns the rows of a CSV file. The delimiter (comma, semicolon or
+// tab) is detected from the first line, and files that are not UTF-8 are
+// read as Windows-1252, which is what spreadsheet programs usually save.
+func ReadCSV(data []byte) ([]Row, error) {
+	data = bytes.TrimPrefix(data, []byte("\xEF\xBB\xBF"))
+	if !utf8.Valid(data) {
+		decoded, err := charmap.Windows1252.NewDecoder().Bytes(data)
+		if err != nil {
+			return nil, err
+		}
+		data = decoded
+	}
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.Comma = detectDelimiter(data)
+	reader.FieldsPerRecord = -1
+	var rows []Row
+	for {
+		cells, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		rows = append(rows, Row{Number: line, Cells: cells})
+	}
+	return rows, nil
+}
+
+// detectDelimiter picks the most frequent candidate delimiter of the first line
+func detectDelimiter(data []byte) rune {
+	line := data
+	if end := bytes.IndexByte(data, '\n'); end >= 0 {
+		line = data[:end]
+	}
+	delimiter, most := ',', 0
+	for _, candidate := range []rune{',', ';', '\t'} {
+		if n := bytes.Count(line, []byte(string(candidate))); n > most {
+			delimiter, most = candidate, n
+		}
+	}
+	return delimiter
+}
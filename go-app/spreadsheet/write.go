@@ -0,0 +1,155 @@
+package spreadsheet
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxRows is the number of rows a sheet can have
+const MaxRows = 1048576
+
+// ErrTooManyRows is returned when a sheet is full
+var ErrTooManyRows = errors.New("xlsx: a sheet holds at most 1048576 rows")
+
+// The parts of a workbook other than its sheet, which never change
+const (
+	contentTypesXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/></Types>`
+	packageRelsXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`
+	workbookRelsXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/></Relationships>`
+	workbookXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="%s" sheetId="1" r:id="rId1"/></sheets></workbook>`
+	sheetStartXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+	sheetEndXML = `</sheetData></worksheet>`
+)
+
+// XLSXWriter writes a workbook with a single sheet row by row, so the rows
+// never have to be held in memory. Strings are stored inline rather than in
+// a shared string table for the same reason.
+type XLSXWriter struct {
+	zip   *zip.Writer
+	sheet *bufio.Writer
+	rows  int
+}
+
+// NewXLSXWriter starts a workbook whose sheet has the given name
+func NewXLSXWriter(w io.Writer, sheetName string) (*XLSXWriter, error) {
+	var name strings.Builder
+	xml.EscapeText(&name, []byte(sheetName))
+
+	archive := zip.NewWriter(w)
+	parts := []struct{ name, content string }{
+		{"[Content_Types].xml", contentTypesXML},
+		{"_rels/.rels", packageRelsXML},
+		{"xl/workbook.xml", fmt.Sprintf(workbookXML, name.String())},
+		{"xl/_rels/workbook.xml.rels", workbookRelsXML},
+	}
+	for _, part := range parts {
+		file, err := archive.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(file, part.content); err != nil {
+			return nil, err
+		}
+	}
+
+	// The sheet is the last part, so it can stay open until Close
+	file, err := archive.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	sheet := bufio.NewWriter(file)
+	if _, err := sheet.WriteString(sheetStartXML); err != nil {
+		return nil, err
+	}
+	return &XLSXWriter{zip: archive, sheet: sheet}, nil
+}
+
+// Write appends a row. Integers and floats become number cells, booleans
+// boolean cells, times ISO 8601 text and nil an empty cell; anything else is
+// written as text.
+func (w *XLSXWriter) Write(cells []interface{}) error {
+	if w.rows == MaxRows {
+		return ErrTooManyRows
+	}
+	w.rows++
+	row := strconv.Itoa(w.rows)
+	b := w.sheet
+	b.WriteString(`<row r="` + row + `">`)
+	for i, cell := range cells {
+		ref := ColumnName(i) + row
+		switch value := cell.(type) {
+		case nil:
+			continue
+		case int:
+			b.WriteString(`<c r="` + ref + `"><v>` + strconv.Itoa(value) + `</v></c>`)
+		case int64:
+			b.WriteString(`<c r="` + ref + `"><v>` + strconv.FormatInt(value, 10) + `</v></c>`)
+		case uint:
+			b.WriteString(`<c r="` + ref + `"><v>` + strconv.FormatUint(uint64(value), 10) + `</v></c>`)
+		case float64:
+			b.WriteString(`<c r="` + ref + `"><v>` + strconv.FormatFloat(value, 'f', -1, 64) + `</v></c>`)
+		case bool:
+			v := "0"
+			if value {
+				v = "1"
+			}
+			b.WriteString(`<c r="` + ref + `" t="b"><v>` + v + `</v></c>`)
+		case time.Time:
+			w.writeText(ref, value.Format(time.RFC3339))
+		case string:
+			w.writeText(ref, value)
+		default:
+			w.writeText(ref, fmt.Sprint(value))
+		}
+	}
+	_, err := b.WriteString(`</row>`)
+	return err
+}
+
+// writeText writes an inline string cell, keeping leading and trailing spaces
+func (w *XLSXWriter) writeText(ref, text string) {
+	w.sheet.WriteString(`<c r="` + ref + `" t="inlineStr"><is><t xml:space="preserve">`)
+	// EscapeText also replaces the control characters XML cannot hold
+	xml.EscapeText(w.sheet, []byte(text))
+	w.sheet.WriteString(`</t></is></c>`)
+}
+
+// Flush sends the rows written so far on to the underlying writer
+func (w *XLSXWriter) Flush() error {
+	if err := w.sheet.Flush(); err != nil {
+		return err
+	}
+	return w.zip.Flush()
+}
+
+// Close ends the sheet and the workbook; it does not close the underlying writer
+func (w *XLSXWriter) Close() error {
+	if _, err := w.sheet.WriteString(sheetEndXML); err != nil {
+		return err
+	}
+	if err := w.sheet.Flush(); err != nil {
+		return err
+	}
+	return w.zip.Close()
+}
+
+// ColumnName returns the letters of a 0-based column, such as "AB" for 27
+func ColumnName(column int) string {
+	name := ""
+	for column++; column > 0; column = (column - 1) / 26 {
+		name = string(rune('A'+(column-1)%26)) + name
+	}
+	return name
+}
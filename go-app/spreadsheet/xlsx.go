@@ -0,0 +1,278 @@
+package spreadsheet
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// maxPartBytes caps the uncompressed size of a workbook part, so a small
+// upload cannot expand into an unbounded amount of XML
+const maxPartBytes = 256 << 20
+
+// maxColumns is the number of columns a sheet can have (A to XFD)
+const maxColumns = 16384
+
+// ReadXLSX returns the rows of the first sheet of an XLSX workbook. Cells
+// hold their text or the plain decimal form of their number; formulas give
+// their cached result. Empty rows the sheet leaves out are not returned.
+func ReadXLSX(data []byte) ([]Row, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("xlsx: %w", err)
+	}
+	files := make(map[string]*zip.File, len(archive.File))
+	for _, file := range archive.File {
+		files[file.Name] = file
+	}
+
+	sheet, err := firstSheet(files)
+	if err != nil {
+		return nil, err
+	}
+	var shared []string
+	if file, ok := files["xl/sharedStrings.xml"]; ok {
+		if shared, err = readSharedStrings(file); err != nil {
+			return nil, err
+		}
+	}
+	file, ok := files[sheet]
+	if !ok {
+		return nil, fmt.Errorf("xlsx: sheet %s is missing", sheet)
+	}
+	return readSheet(file, shared)
+}
+
+// firstSheet returns the name of the part holding the first sheet of the workbook
+func firstSheet(files map[string]*zip.File) (string, error) {
+	const fallback = "xl/worksheets/sheet1.xml"
+
+	var workbook struct {
+		Sheets []struct {
+			RelID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	file, ok := files["xl/workbook.xml"]
+	if !ok {
+		return "", errors.New("xlsx: not a workbook")
+	}
+	if err := decodePart(file, &workbook); err != nil {
+		return "", err
+	}
+	if len(workbook.Sheets) == 0 {
+		return "", errors.New("xlsx: the workbook has no sheets")
+	}
+
+	var rels struct {
+		Relationships []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	file, ok = files["xl/_rels/workbook.xml.rels"]
+	if !ok {
+		return fallback, nil
+	}
+	if err := decodePart(file, &rels); err != nil {
+		return "", err
+	}
+	for _, rel := range rels.Relationships {
+		if rel.ID != workbook.Sheets[0].RelID {
+			continue
+		}
+		// Targets are relative to xl/ unless they start at the package root
+		if strings.HasPrefix(rel.Target, "/") {
+			return strings.TrimPrefix(rel.Target, "/"), nil
+		}
+		return path.Join("xl", rel.Target), nil
+	}
+	return fallback, nil
+}
+
+// richText is the text of a shared string or inline string cell, which is
+// either plain or split in formatted runs
+type richText struct {
+	Text string `xml:"t"`
+	Runs []struct {
+		Text string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t richText) String() string {
+	if len(t.Runs) == 0 {
+		return t.Text
+	}
+	var b strings.Builder
+	for _, run := range t.Runs {
+		b.WriteString(run.Text)
+	}
+	return b.String()
+}
+
+// readSharedStrings returns the shared string table cells refer to by index
+func readSharedStrings(file *zip.File) ([]string, error) {
+	var table struct {
+		Items []richText `xml:"si"`
+	}
+	if err := decodePart(file, &table); err != nil {
+		return nil, err
+	}
+	shared := make([]string, len(table.Items))
+	for i, item := range table.Items {
+		shared[i] = item.String()
+	}
+	return shared, nil
+}
+
+// sheetRow is a row element of a worksheet
+type sheetRow struct {
+	Number int `xml:"r,attr"`
+	Cells  []struct {
+		Ref    string   `xml:"r,attr"`
+		Type   string   `xml:"t,attr"`
+		Value  string   `xml:"v"`
+		Inline richText `xml:"is"`
+	} `xml:"c"`
+}
+
+// readSheet streams the rows of a worksheet
+func readSheet(file *zip.File, shared []string) ([]Row, error) {
+	part, err := openPart(file)
+	if err != nil {
+		return nil, err
+	}
+	defer part.Close()
+
+	var rows []Row
+	decoder := xml.NewDecoder(part)
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("xlsx: %w", err)
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "row" {
+			continue
+		}
+		var row sheetRow
+		if err := decoder.DecodeElement(&row, &start); err != nil {
+			return nil, fmt.Errorf("xlsx: %w", err)
+		}
+		// Row and cell references are optional, and then follow on from the previous ones
+		if row.Number == 0 {
+			row.Number = len(rows) + 1
+			if len(rows) > 0 {
+				row.Number = rows[len(rows)-1].Number + 1
+			}
+		}
+
+		var cells []string
+		for _, cell := range row.Cells {
+			column := len(cells)
+			if cell.Ref != "" {
+				if column, err = columnIndex(cell.Ref); err != nil {
+					return nil, err
+				}
+			}
+			for len(cells) < column {
+				cells = append(cells, "")
+			}
+			value, err := cellValue(cell.Type, cell.Value, cell.Inline, shared)
+			if err != nil {
+				return nil, fmt.Errorf("xlsx: cell %s: %w", cell.Ref, err)
+			}
+			if column < len(cells) {
+				cells[column] = value
+			} else {
+				cells = append(cells, value)
+			}
+		}
+		rows = append(rows, Row{Number: row.Number, Cells: cells})
+	}
+}
+
+// cellValue returns the text of a cell by its type
+func cellValue(kind, value string, inline richText, shared []string) (string, error) {
+	switch kind {
+	case "s":
+		index, err := strconv.Atoi(value)
+		if err != nil || index < 0 || index >= len(shared) {
+			return "", fmt.Errorf("invalid shared string %q", value)
+		}
+		return shared[index], nil
+	case "inlineStr":
+		return inline.String(), nil
+	case "b":
+		if value == "1" {
+			return "TRUE", nil
+		}
+		return "FALSE", nil
+	case "", "n":
+		// Write numbers the way they were typed rather than in exponent form
+		if number, err := strconv.ParseFloat(value, 64); err == nil {
+			return strconv.FormatFloat(number, 'f', -1, 64), nil
+		}
+		return value, nil
+	}
+	// Formula strings, errors and dates are kept as stored
+	return value, nil
+}
+
+// columnIndex returns the 0-based column of a cell reference such as "AB12"
+func columnIndex(ref string) (int, error) {
+	column := 0
+	for i := 0; i < len(ref); i++ {
+		c := ref[i]
+		if c >= 'a' && c <= 'z' {
+			c -= 'a' - 'A'
+		}
+		if c < 'A' || c > 'Z' {
+			if i == 0 {
+				break
+			}
+			return column - 1, nil
+		}
+		column = column*26 + int(c-'A'+1)
+		if column > maxColumns {
+			break
+		}
+	}
+	return 0, fmt.Errorf("xlsx: invalid cell reference %q", ref)
+}
+
+// openPart opens a part of the workbook, limiting how much of it can be read
+func openPart(file *zip.File) (io.ReadCloser, error) {
+	if file.UncompressedSize64 > maxPartBytes {
+		return nil, fmt.Errorf("xlsx: %s is too large", file.Name)
+	}
+	part, err := file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("xlsx: %w", err)
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(part, maxPartBytes), part}, nil
+}
+
+// decodePart decodes a whole XML part of the workbook into v
+func decodePart(file *zip.File, v interface{}) error {
+	part, err := openPart(file)
+	if err != nil {
+		return err
+	}
+	defer part.Close()
+	if err := xml.NewDecoder(part).Decode(v); err != nil {
+		return fmt.Errorf("xlsx: %s: %w", file.Name, err)
+	}
+	return nil
+}
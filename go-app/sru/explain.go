@@ -0,0 +1,143 @@
+package sru
+
+import (
+	"encoding/xml"
+	"io"
+)
+
+// Explain describes the server to clients configuring themselves
+type Explain struct {
+	Host           string
+	Port           string
+	Database       string // Path of the endpoint, without the leading slash
+	Title          string
+	Description    string
+	Sets           []ContextSet
+	Indexes        []Index
+	Schemas        []Schema
+	DefaultRecords int
+	MaximumRecords int
+}
+
+// ContextSet is a CQL context set whose indexes the server supports
+type ContextSet struct {
+	Name       string
+	Identifier string
+}
+
+// Index is a searchable index with the qualified names it answers to
+type Index struct {
+	Title string
+	Names []IndexName
+}
+
+// IndexName is an index name in a context set, dc and title for dc.title
+type IndexName struct {
+	Set  string
+	Name string
+}
+
+// Schema is a record schema results can be returned in
+type Schema struct {
+	Name       string
+	Identifier string
+	Title      string
+}
+
+type explainResponseElement struct {
+	XMLName     xml.Name            `xml:"sruResponse:explainResponse"`
+	Xmlns       string              `xml:"xmlns:sruResponse,attr"`
+	Version     string              `xml:"sruResponse:version"`
+	Record      recordElement       `xml:"sruResponse:record"`
+	Diagnostics *diagnosticsElement `xml:"sruResponse:diagnostics"`
+}
+
+type explainElement struct {
+	XMLName    xml.Name `xml:"zr:explain"`
+	Xmlns      string   `xml:"xmlns:zr,attr"`
+	ServerInfo struct {
+		Protocol string `xml:"protocol,attr"`
+		Version  string `xml:"version,attr"`
+		Host     string `xml:"zr:host"`
+		Port     string `xml:"zr:port"`
+		Database string `xml:"zr:database"`
+	} `xml:"zr:serverInfo"`
+	DatabaseInfo struct {
+		Title       string `xml:"zr:title"`
+		Description string `xml:"zr:description,omitempty"`
+	} `xml:"zr:databaseInfo"`
+	IndexInfo struct {
+		Sets    []explainSet   `xml:"zr:set"`
+		Indexes []explainIndex `xml:"zr:index"`
+	} `xml:"zr:indexInfo"`
+	SchemaInfo struct {
+		Schemas []explainSchema `xml:"zr:schema"`
+	} `xml:"zr:schemaInfo"`
+	ConfigInfo struct {
+		Default explainSetting `xml:"zr:default"`
+		Setting explainSetting `xml:"zr:setting"`
+	} `xml:"zr:configInfo"`
+}
+
+type explainIndex struct {
+	Title string `xml:"zr:title"`
+	Map   struct {
+		Names []explainName `xml:"zr:name"`
+	} `xml:"zr:map"`
+}
+
+type explainSchema struct {
+	Name       string `xml:"name,attr"`
+	Identifier string `xml:"identifier,attr"`
+	Title      string `xml:"zr:title"`
+}
+
+type explainSet struct {
+	Name       string `xml:"name,attr"`
+	Identifier string `xml:"identifier,attr"`
+}
+
+type explainName struct {
+	Set  string `xml:"set,attr"`
+	Name string `xml:",chardata"`
+}
+
+type explainSetting struct {
+	Type  string `xml:"type,attr"`
+	Value int    `xml:",chardata"`
+}
+
+// WriteExplain writes an explain response, with diagnostics about the request if any
+func WriteExplain(w io.Writer, e *Explain, diags []*Diagnostic) error {
+	var record explainElement
+	record.Xmlns = ExplainNamespace
+	record.ServerInfo.Protocol, record.ServerInfo.Version = "SRU", Version
+	record.ServerInfo.Host, record.ServerInfo.Port, record.ServerInfo.Database = e.Host, e.Port, e.Database
+	record.DatabaseInfo.Title, record.DatabaseInfo.Description = e.Title, e.Description
+	for _, set := range e.Sets {
+		record.IndexInfo.Sets = append(record.IndexInfo.Sets, explainSet(set))
+	}
+	for _, index := range e.Indexes {
+		el := explainIndex{Title: index.Title}
+		for _, name := range index.Names {
+			el.Map.Names = append(el.Map.Names, explainName(name))
+		}
+		record.IndexInfo.Indexes = append(record.IndexInfo.Indexes, el)
+	}
+	for _, schema := range e.Schemas {
+		record.SchemaInfo.Schemas = append(record.SchemaInfo.Schemas, explainSchema(schema))
+	}
+	record.ConfigInfo.Default = explainSetting{Type: "numberOfRecords", Value: e.DefaultRecords}
+	record.ConfigInfo.Setting = explainSetting{Type: "maximumRecords", Value: e.MaximumRecords}
+
+	data, err := xml.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return encode(w, explainResponseElement{
+		Xmlns:       ResponseNamespace,
+		Version:     Version,
+		Record:      newRecordElement(Record{Schema: ExplainNamespace, Data: data}, EscapingXML),
+		Diagnostics: diagnostics(diags),
+	})
+}
@@ -0,0 +1,214 @@
+// Package sru writes SRU 2.0 responses: searchRetrieve results, the explain
+// record describing the server, and diagnostics.
+package sru
+
+import (
+	"encoding/xml"
+	"io"
+	"strconv"
+)
+
+// Version is the protocol version served
+const Version = "2.0"
+
+// Namespaces of SRU 2.0 responses and of the explain record
+const (
+	ResponseNamespace   = "http://docs.oasis-open.org/ns/search-ws/sruResponse"
+	DiagnosticNamespace = "http://docs.oasis-open.org/ns/search-ws/diagnostic"
+	ExplainNamespace    = "http://explain.z3950.org/dtd/2.0/"
+)
+
+// ContentType is the media type of SRU responses
+const ContentType = "application/sru+xml; charset=utf-8"
+
+// Values of recordXMLEscaping
+const (
+	EscapingXML    = "xml"
+	EscapingString = "string"
+)
+
+// Diagnostic codes used by the server, from the SRU diagnostics list
+const (
+	DiagGeneralError               = 1
+	DiagUnsupportedOperation       = 4
+	DiagUnsupportedVersion         = 5
+	DiagUnsupportedValue           = 6
+	DiagMissingParameter           = 7
+	DiagUnsupportedParameter       = 8
+	DiagQuerySyntax                = 10
+	DiagUnsupportedIndex           = 16
+	DiagUnsupportedRelation        = 19
+	DiagUnsupportedModifier        = 20
+	DiagEmptyTerm                  = 27
+	DiagUnsupportedAnchoring       = 32
+	DiagUnsupportedBoolean         = 37
+	DiagUnsupportedBooleanModifier = 46
+	DiagFirstRecordOutOfRange      = 61
+	DiagUnknownSchema              = 66
+	DiagUnsupportedEscaping        = 71
+	DiagSortUnsupported            = 80
+	DiagStylesheetUnsupported      = 110
+)
+
+var diagnosticMessages = map[int]string{
+	DiagGeneralError:               "General system error",
+	DiagUnsupportedOperation:       "Unsupported operation",
+	DiagUnsupportedVersion:         "Unsupported version",
+	DiagUnsupportedValue:           "Unsupported parameter value",
+	DiagMissingParameter:           "Mandatory parameter not supplied",
+	DiagUnsupportedParameter:       "Unsupported parameter",
+	DiagQuerySyntax:                "Query syntax error",
+	DiagUnsupportedIndex:           "Unsupported index",
+	DiagUnsupportedRelation:        "Unsupported relation",
+	DiagUnsupportedModifier:        "Unsupported relation modifier",
+	DiagEmptyTerm:                  "Empty term unsupported",
+	DiagUnsupportedAnchoring:       "Anchoring character not supported in this position",
+	DiagUnsupportedBoolean:         "Unsupported boolean operator",
+	DiagUnsupportedBooleanModifier: "Unsupported boolean modifier",
+	DiagFirstRecordOutOfRange:      "First record position out of range",
+	DiagUnknownSchema:              "Unknown schema for retrieval",
+	DiagUnsupportedEscaping:        "Unsupported recordXMLEscaping value",
+	DiagSortUnsupported:            "Sort not supported",
+	DiagStylesheetUnsupported:      "Stylesheets not supported",
+}
+
+// Diagnostic is an SRU error or warning, reported in the response body.
+// Details names the offending parameter, index or value.
+type Diagnostic struct {
+	Code    int
+	Details string
+}
+
+// NewDiagnostic returns a diagnostic with the given code
+func NewDiagnostic(code int, details string) *Diagnostic {
+	return &Diagnostic{Code: code, Details: details}
+}
+
+func (d *Diagnostic) Error() string {
+	if d.Details == "" {
+		return d.Message()
+	}
+	return d.Message() + ": " + d.Details
+}
+
+// URI identifies the diagnostic
+func (d *Diagnostic) URI() string {
+	return "info:srw/diagnostic/1/" + strconv.Itoa(d.Code)
+}
+
+// Message is the standard description of the diagnostic
+func (d *Diagnostic) Message() string {
+	if message, ok := diagnosticMessages[d.Code]; ok {
+		return message
+	}
+	return "Diagnostic " + strconv.Itoa(d.Code)
+}
+
+// Record is a record of a result set, Data being an XML element
+type Record struct {
+	Schema   string
+	Data     []byte
+	Position int // Position in the result set, from 1
+}
+
+// SearchRetrieveResponse is the answer to a searchRetrieve request. A
+// NextRecordPosition of zero means the result set has no more records.
+type SearchRetrieveResponse struct {
+	NumberOfRecords    int64
+	Records            []Record
+	NextRecordPosition int
+	Escaping           string
+	Diagnostics        []*Diagnostic
+}
+
+type searchRetrieveElement struct {
+	XMLName            xml.Name            `xml:"sruResponse:searchRetrieveResponse"`
+	Xmlns              string              `xml:"xmlns:sruResponse,attr"`
+	Version            string              `xml:"sruResponse:version"`
+	NumberOfRecords    int64               `xml:"sruResponse:numberOfRecords"`
+	Records            *recordsElement     `xml:"sruResponse:records"`
+	NextRecordPosition int                 `xml:"sruResponse:nextRecordPosition,omitempty"`
+	Diagnostics        *diagnosticsElement `xml:"sruResponse:diagnostics"`
+	Precision          string              `xml:"sruResponse:resultCountPrecision"`
+}
+
+type recordsElement struct {
+	Records []recordElement `xml:"sruResponse:record"`
+}
+
+type recordElement struct {
+	Schema   string            `xml:"sruResponse:recordSchema"`
+	Escaping string            `xml:"sruResponse:recordXMLEscaping"`
+	Data     recordDataElement `xml:"sruResponse:recordData"`
+	Position int               `xml:"sruResponse:recordPosition,omitempty"`
+}
+
+// recordDataElement holds the record as markup, or as text when escaped
+type recordDataElement struct {
+	Inner []byte `xml:",innerxml"`
+	Text  string `xml:",chardata"`
+}
+
+type diagnosticsElement struct {
+	Diagnostics []diagnosticElement `xml:"diag:diagnostic"`
+}
+
+type diagnosticElement struct {
+	Xmlns   string `xml:"xmlns:diag,attr"`
+	URI     string `xml:"diag:uri"`
+	Details string `xml:"diag:details,omitempty"`
+	Message string `xml:"diag:message"`
+}
+
+// WriteSearchRetrieve writes a searchRetrieve response
+func WriteSearchRetrieve(w io.Writer, r *SearchRetrieveResponse) error {
+	doc := searchRetrieveElement{
+		Xmlns:              ResponseNamespace,
+		Version:            Version,
+		NumberOfRecords:    r.NumberOfRecords,
+		NextRecordPosition: r.NextRecordPosition,
+		Diagnostics:        diagnostics(r.Diagnostics),
+		Precision:          "info:srw/vocabulary/resultCountPrecision/1/exact",
+	}
+	if len(r.Records) > 0 {
+		doc.Records = &recordsElement{}
+		for _, record := range r.Records {
+			doc.Records.Records = append(doc.Records.Records, newRecordElement(record, r.Escaping))
+		}
+	}
+	return encode(w, doc)
+}
+
+func newRecordElement(record Record, escaping string) recordElement {
+	el := recordElement{Schema: record.Schema, Escaping: escaping, Position: record.Position}
+	if escaping == EscapingString {
+		el.Data.Text = string(record.Data)
+	} else {
+		el.Data.Inner = record.Data
+	}
+	return el
+}
+
+func diagnostics(list []*Diagnostic) *diagnosticsElement {
+	if len(list) == 0 {
+		return nil
+	}
+	el := &diagnosticsElement{}
+	for _, d := range list {
+		el.Diagnostics = append(el.Diagnostics, diagnosticElement{Xmlns: DiagnosticNamespace, URI: d.URI(), Details: d.Details, Message: d.Message()})
+	}
+	return el
+}
+
+func encode(w io.Writer, doc interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
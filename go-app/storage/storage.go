@@ -0,0 +1,38 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"library-go/config"
+)
+
+// ErrNotFound is returned when an object does not exist
+var ErrNotFound = errors.New("object not found")
+
+// Store keeps binary objects, such as cover images, under slash-separated keys
+type Store interface {
+	// Put stores data under key, replacing any existing object
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	// Get opens the object stored under key along with its content type
+	Get(ctx context.Context, key string) (io.ReadCloser, string, error)
+	// Delete removes the object stored under key; deleting a missing object is not an error
+	Delete(ctx context.Context, key string) error
+}
+
+// Open returns the store selected by the configuration
+func Open() (Store, error) {
+	switch config.CoverStorage {
+	case "local":
+		return NewLocal(config.CoverDir)
+	case "s3":
+		if config.S3Bucket == "" {
+			return nil, errors.New("S3_BUCKET must be set for S3 cover storage")
+		}
+		return NewS3(config.S3Endpoint, config.S3Region, config.S3Bucket, config.S3AccessKey, config.S3SecretKey), nil
+	default:
+		return nil, fmt.Errorf("unknown cover storage %q, expected local or s3", config.CoverStorage)
+	}
+}
@@ -0,0 +1,511 @@
+package suggest
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm"
+
+	"library-go/fuzzy"
+	"library-go/models"
+)
+
+// Kind selects which index a suggestion lookup runs against
+type Kind string
+
+const (
+	Books   Kind = "book"
+	Readers Kind = "reader"
+	Authors Kind = "author"
+)
+
+// Suggestion is a single typeahead match
+type Suggestion struct {
+	ID     uint    `json:"id,omitempty"`
+	Label  string  `json:"label"`
+	Detail string  `json:"detail,omitempty"`
+	Count  int     `json:"count,omitempty"` // Number of books, for authors
+	Score  float64 `json:"score,omitempty"` // Similarity, for fuzzy matches
+}
+
+// entry is one suggestible record, shared by all of its keys
+type entry struct {
+	id     uint
+	label  string
+	detail string
+	count  int
+	words  []string // Folded words used for fuzzy matching
+}
+
+// key is a normalized string starting at a word boundary of an entry
+type key struct {
+	text  string
+	entry *entry
+}
+
+// index is a sorted list of keys supporting prefix lookups by binary search,
+// plus trigram postings of folded words for fuzzy matching
+type index struct {
+	keys    []key
+	entries map[string]*entry
+	words   map[string]map[*entry]bool // Folded word -> entries containing it
+	grams   map[string]map[string]bool // Trigram -> folded words containing it
+
+	// bulk appends keys unsorted while Build fills the index, which sorts them once
+	bulk bool
+}
+
+func newIndex() *index {
+	return &index{
+		entries: make(map[string]*entry),
+		words:   make(map[string]map[*entry]bool),
+		grams:   make(map[string]map[string]bool),
+	}
+}
+
+var (
+	mu      sync.RWMutex
+	indexes = map[Kind]*index{
+		Books:   newIndex(),
+		Readers: newIndex(),
+		Authors: newIndex(),
+	}
+
+	// bookAuthors lists the authors counted for each book, so a book can be
+	// uncounted without the caller knowing its previous contributors
+	bookAuthors = make(map[uint][]uint)
+)
+
+// Build rebuilds every index from the database
+func Build(db *gorm.DB) error {
+	var books []models.Book
+	if err := db.Select("id", "title", "author").Find(&books).Error; err != nil {
+		return err
+	}
+	var readers []models.Reader
+	if err := db.Select("id", "first_name", "last_name", "email").Find(&readers).Error; err != nil {
+		return err
+	}
+	var authors []models.Author
+	if err := db.Select("id", "name").Find(&authors).Error; err != nil {
+		return err
+	}
+	var contributions []models.Contribution
+	if err := db.Select("book_id", "author_id").Find(&contributions).Error; err != nil {
+		return err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	for kind := range indexes {
+		indexes[kind] = newIndex()
+		indexes[kind].bulk = true
+	}
+	bookAuthors = make(map[uint][]uint)
+	for _, author := range authors {
+		addAuthor(author, 0)
+	}
+	for _, book := range books {
+		addBook(book)
+	}
+	for _, contribution := range contributions {
+		creditAuthor(contribution.BookID, contribution.AuthorID)
+	}
+	for _, reader := range readers {
+		addReader(reader)
+	}
+	for _, idx := range indexes {
+		idx.sort()
+		idx.bulk = false
+	}
+	return nil
+}
+
+// AddBook indexes a newly created book and counts it for its contributors
+func AddBook(book models.Book) {
+	mu.Lock()
+	defer mu.Unlock()
+	addBook(book)
+}
+
+// UpdateBook re-indexes a book after its title or author changed
+func UpdateBook(old, book models.Book) {
+	mu.Lock()
+	defer mu.Unlock()
+	removeBook(old)
+	addBook(book)
+}
+
+// RemoveBook drops a deleted book and uncounts it for its contributors
+func RemoveBook(book models.Book) {
+	mu.Lock()
+	defer mu.Unlock()
+	removeBook(book)
+}
+
+// AddReader indexes a newly created reader
+func AddReader(reader models.Reader) {
+	mu.Lock()
+	defer mu.Unlock()
+	addReader(reader)
+}
+
+// UpdateReader re-indexes a reader after their name or email changed
+func UpdateReader(old, reader models.Reader) {
+	mu.Lock()
+	defer mu.Unlock()
+	indexes[Readers].remove(readerKey(old))
+	addReader(reader)
+}
+
+// RemoveReader drops a deleted reader
+func RemoveReader(reader models.Reader) {
+	mu.Lock()
+	defer mu.Unlock()
+	indexes[Readers].remove(readerKey(reader))
+}
+
+// AddAuthor indexes a newly created author, who has no books yet
+func AddAuthor(author models.Author) {
+	mu.Lock()
+	defer mu.Unlock()
+	addAuthor(author, 0)
+}
+
+// UpdateAuthor re-indexes an author after a rename, keeping their book count
+func UpdateAuthor(author models.Author) {
+	mu.Lock()
+	defer mu.Unlock()
+	count := 0
+	if e, ok := indexes[Authors].entries[authorKey(author.ID)]; ok {
+		count = e.count
+	}
+	indexes[Authors].remove(authorKey(author.ID))
+	addAuthor(author, count)
+}
+
+// RemoveAuthor drops a deleted author
+func RemoveAuthor(author models.Author) {
+	mu.Lock()
+	defer mu.Unlock()
+	indexes[Authors].remove(authorKey(author.ID))
+}
+
+// MergeAuthors counts the books of the duplicates for author and drops the duplicates
+func MergeAuthors(author models.Author, duplicates []models.Author) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	merged := make(map[uint]bool, len(duplicates))
+	for _, duplicate := range duplicates {
+		merged[duplicate.ID] = true
+		indexes[Authors].remove(authorKey(duplicate.ID))
+	}
+	if _, ok := indexes[Authors].entries[authorKey(author.ID)]; !ok {
+		addAuthor(author, 0)
+	}
+
+	for bookID, ids := range bookAuthors {
+		kept := ids[:0]
+		moved := false
+		for _, id := range ids {
+			if merged[id] {
+				moved = true
+			} else {
+				kept = append(kept, id)
+			}
+		}
+		bookAuthors[bookID] = kept
+		if moved {
+			creditAuthor(bookID, author.ID)
+		}
+	}
+}
+
+// Lookup returns up to limit entries with a word starting with prefix.
+// Entries whose label itself starts with the prefix are returned first.
+func Lookup(kind Kind, prefix string, limit int) []Suggestion {
+	normalized := normalize(prefix)
+	suggestions := []Suggestion{}
+	if normalized == "" || limit <= 0 {
+		return suggestions
+	}
+
+	mu.RLock()
+	defer mu.RUnlock()
+
+	idx, ok := indexes[kind]
+	if !ok {
+		return suggestions
+	}
+
+	// Scan a bounded window of matching keys to keep lookups fast on short prefixes
+	maxScan := limit * 20
+	seen := make(map[*entry]bool, limit)
+	var leading, other []*entry
+	start := sort.Search(len(idx.keys), func(i int) bool { return idx.keys[i].text >= normalized })
+	for i := start; i < len(idx.keys) && i-start < maxScan; i++ {
+		k := idx.keys[i]
+		if !strings.HasPrefix(k.text, normalized) {
+			break
+		}
+		if seen[k.entry] {
+			continue
+		}
+		seen[k.entry] = true
+		if strings.HasPrefix(normalize(k.entry.label), normalized) {
+			leading = append(leading, k.entry)
+		} else {
+			other = append(other, k.entry)
+		}
+	}
+
+	for _, e := range append(leading, other...) {
+		if len(suggestions) == limit {
+			break
+		}
+		suggestions = append(suggestions, Suggestion{ID: e.id, Label: e.label, Detail: e.detail, Count: e.count})
+	}
+	return suggestions
+}
+
+// minSimilarity is the lowest word similarity accepted as a fuzzy match
+const minSimilarity = 0.6
+
+// Match returns up to limit entries matching every word of the query, tolerating
+// typos and differences between Cyrillic and Latin spellings. Results are
+// ordered by their average word similarity.
+func Match(kind Kind, query string, limit int) []Suggestion {
+	queryWords := fuzzy.Fold(query)
+	suggestions := []Suggestion{}
+	if len(queryWords) == 0 || limit <= 0 {
+		return suggestions
+	}
+
+	mu.RLock()
+	defer mu.RUnlock()
+
+	idx, ok := indexes[kind]
+	if !ok {
+		return suggestions
+	}
+
+	// scores accumulates, per entry, the best similarity for each query word
+	scores := make(map[*entry][]float64)
+	for i, queryWord := range queryWords {
+		// Candidate words share at least one trigram with the query word
+		candidates := make(map[string]bool)
+		for _, gram := range fuzzy.Trigrams(queryWord) {
+			for word := range idx.grams[gram] {
+				candidates[word] = true
+			}
+		}
+
+		for word := range candidates {
+			similarity := fuzzy.Similarity(queryWord, word)
+			if similarity < minSimilarity {
+				continue
+			}
+			for e := range idx.words[word] {
+				if scores[e] == nil {
+					scores[e] = make([]float64, len(queryWords))
+				}
+				if similarity > scores[e][i] {
+					scores[e][i] = similarity
+				}
+			}
+		}
+	}
+
+	type scored struct {
+		entry *entry
+		score float64
+	}
+	var matches []scored
+	for e, wordScores := range scores {
+		total := 0.0
+		matchedAll := true
+		for _, score := range wordScores {
+			if score == 0 {
+				matchedAll = false
+				break
+			}
+			total += score
+		}
+		if matchedAll {
+			matches = append(matches, scored{entry: e, score: total / float64(len(wordScores))})
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score > matches[j].score
+		}
+		return matches[i].entry.label < matches[j].entry.label
+	})
+
+	for _, m := range matches {
+		if len(suggestions) == limit {
+			break
+		}
+		e := m.entry
+		suggestions = append(suggestions, Suggestion{ID: e.id, Label: e.label, Detail: e.detail, Count: e.count, Score: m.score})
+	}
+	return suggestions
+}
+
+func addBook(book models.Book) {
+	e := &entry{id: book.ID, label: book.Title, detail: book.Author, words: fuzzy.Fold(book.Title + " " + book.Author)}
+	indexes[Books].add(bookKey(book), e, book.Title)
+
+	// Authors created along with the book are indexed from its loaded contributors
+	for _, contribution := range book.Contributors {
+		if _, ok := indexes[Authors].entries[authorKey(contribution.AuthorID)]; !ok && contribution.Author != nil {
+			addAuthor(*contribution.Author, 0)
+		}
+		creditAuthor(book.ID, contribution.AuthorID)
+	}
+}
+
+func removeBook(book models.Book) {
+	indexes[Books].remove(bookKey(book))
+
+	for _, id := range bookAuthors[book.ID] {
+		if e, ok := indexes[Authors].entries[authorKey(id)]; ok {
+			e.count--
+		}
+	}
+	delete(bookAuthors, book.ID)
+}
+
+func addAuthor(author models.Author, count int) {
+	e := &entry{id: author.ID, label: author.Name, count: count, words: fuzzy.Fold(author.Name)}
+	indexes[Authors].add(authorKey(author.ID), e, author.Name)
+}
+
+// creditAuthor counts a book once for an indexed author, whatever their roles on it
+func creditAuthor(bookID, authorID uint) {
+	e, ok := indexes[Authors].entries[authorKey(authorID)]
+	if !ok {
+		return
+	}
+	for _, id := range bookAuthors[bookID] {
+		if id == authorID {
+			return
+		}
+	}
+	bookAuthors[bookID] = append(bookAuthors[bookID], authorID)
+	e.count++
+}
+
+func addReader(reader models.Reader) {
+	e := &entry{id: reader.ID, label: reader.FirstName + " " + reader.LastName}
+	if reader.Email != nil {
+		e.detail = *reader.Email
+	}
+	e.words = fuzzy.Fold(e.label + " " + e.detail)
+	indexes[Readers].add(readerKey(reader), e, e.label, e.detail)
+}
+
+func bookKey(book models.Book) string {
+	return "book:" + strconv.FormatUint(uint64(book.ID), 10)
+}
+
+func readerKey(reader models.Reader) string {
+	return "reader:" + strconv.FormatUint(uint64(reader.ID), 10)
+}
+
+func authorKey(id uint) string {
+	return "author:" + strconv.FormatUint(uint64(id), 10)
+}
+
+// add registers an entry under every word of the given texts. Each key is
+// inserted at its sorted position, so adding a book during an import costs a
+// binary search and a copy rather than sorting every key again.
+func (idx *index) add(id string, e *entry, texts ...string) {
+	idx.entries[id] = e
+	for _, text := range texts {
+		for _, suffix := range wordSuffixes(normalize(text)) {
+			k := key{text: suffix, entry: e}
+			if idx.bulk {
+				idx.keys = append(idx.keys, k)
+				continue
+			}
+			i := sort.Search(len(idx.keys), func(i int) bool { return idx.keys[i].text > suffix })
+			idx.keys = append(idx.keys, key{})
+			copy(idx.keys[i+1:], idx.keys[i:])
+			idx.keys[i] = k
+		}
+	}
+
+	for _, word := range e.words {
+		if idx.words[word] == nil {
+			idx.words[word] = make(map[*entry]bool)
+			for _, gram := range fuzzy.Trigrams(word) {
+				if idx.grams[gram] == nil {
+					idx.grams[gram] = make(map[string]bool)
+				}
+				idx.grams[gram][word] = true
+			}
+		}
+		idx.words[word][e] = true
+	}
+}
+
+// remove drops an entry and all of its keys, keeping the keys sorted
+func (idx *index) remove(id string) {
+	e, ok := idx.entries[id]
+	if !ok {
+		return
+	}
+	delete(idx.entries, id)
+
+	kept := idx.keys[:0]
+	for _, k := range idx.keys {
+		if k.entry != e {
+			kept = append(kept, k)
+		}
+	}
+	idx.keys = kept
+
+	for _, word := range e.words {
+		delete(idx.words[word], e)
+		if len(idx.words[word]) > 0 {
+			continue
+		}
+		delete(idx.words, word)
+		for _, gram := range fuzzy.Trigrams(word) {
+			delete(idx.grams[gram], word)
+			if len(idx.grams[gram]) == 0 {
+				delete(idx.grams, gram)
+			}
+		}
+	}
+}
+
+func (idx *index) sort() {
+	sort.SliceStable(idx.keys, func(i, j int) bool { return idx.keys[i].text < idx.keys[j].text })
+}
+
+// wordSuffixes returns the text starting at each of its words ("leo tolstoy" -> "leo tolstoy", "tolstoy")
+func wordSuffixes(text string) []string {
+	if text == "" {
+		return nil
+	}
+	suffixes := []string{text}
+	for i := 0; i < len(text); i++ {
+		if text[i] == ' ' && i+1 < len(text) {
+			suffixes = append(suffixes, text[i+1:])
+		}
+	}
+	return suffixes
+}
+
+// normalize transliterates text to lower-case Latin and collapses punctuation
+// and whitespace into single spaces, so Cyrillic prefixes match Latin spellings
+func normalize(text string) string {
+	return strings.Join(fuzzy.Words(text), " ")
+}